@@ -15,6 +15,10 @@ CREATE TABLE IF NOT EXISTS benchmarks (
     dataset_name VARCHAR(255) NOT NULL,
     dataset_size VARCHAR(50) CHECK (dataset_size IN ('small', 'medium', 'large')),
     engines TEXT[], -- Array of engine names
+    warmup_iterations INTEGER DEFAULT 0 CHECK (warmup_iterations >= 0),
+    measured_iterations INTEGER DEFAULT 1 CHECK (measured_iterations >= 1),
+    baseline_run_id INTEGER, -- Pinned run to detect regressions against
+    workload JSONB, -- Sequential or concurrent workload settings
     status VARCHAR(50) DEFAULT 'created' CHECK (status IN ('created', 'running', 'completed', 'failed')),
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -33,22 +37,50 @@ CREATE TABLE IF NOT EXISTS queries (
     deleted_at TIMESTAMP NULL
 );
 
+CREATE TABLE IF NOT EXISTS benchmark_runs (
+    id SERIAL PRIMARY KEY,
+    benchmark_id INTEGER NOT NULL REFERENCES benchmarks(id) ON DELETE CASCADE,
+    status VARCHAR(50) DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    triggered_by VARCHAR(255),
+    config JSONB, -- Snapshot of engine and catalog configuration
+    start_time TIMESTAMP,
+    end_time TIMESTAMP,
+    error_message TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE TABLE IF NOT EXISTS query_executions (
     id SERIAL PRIMARY KEY,
     query_id INTEGER NOT NULL REFERENCES queries(id) ON DELETE CASCADE,
+    run_id INTEGER REFERENCES benchmark_runs(id) ON DELETE CASCADE,
     engine VARCHAR(100) NOT NULL,
-    status VARCHAR(50) DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    iteration INTEGER DEFAULT 1,
+    concurrency INTEGER DEFAULT 1, -- Virtual users running at the time
+    virtual_user INTEGER DEFAULT 1,
+    arrival_rate DOUBLE PRECISION DEFAULT 0, -- Open-loop target queries per second
+    scheduled_time TIMESTAMP, -- When an open-loop query was due to start
+    service_time_ms BIGINT, -- Latency as measured by the query-service
+    status VARCHAR(50) DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed', 'cancelled', 'timed_out')),
     start_time TIMESTAMP,
     end_time TIMESTAMP,
     execution_time_ms BIGINT,
+    rows_returned BIGINT,
     rows_processed BIGINT,
     bytes_processed BIGINT,
-    cpu_usage DECIMAL(5,2),
-    memory_usage BIGINT,
+    cpu_usage DECIMAL(5,2), -- CPU time per second of execution time
+    cpu_time_ms BIGINT,
+    memory_usage BIGINT, -- Peak user memory
     io_read_bytes BIGINT,
+    io_read_rows BIGINT,
     io_write_bytes BIGINT,
+    queued_time_ms BIGINT,
+    planning_time_ms BIGINT,
     error_message TEXT,
     query_plan TEXT,
+    plan_type VARCHAR(50) CHECK (plan_type IN ('explain', 'analyze')),
+    plan_analysis JSONB,
+    result_checksum VARCHAR(64), -- Order-insensitive "<rows>:<hash>" of the result set
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -56,26 +88,56 @@ CREATE TABLE IF NOT EXISTS query_executions (
 CREATE TABLE IF NOT EXISTS results (
     id SERIAL PRIMARY KEY,
     benchmark_id INTEGER NOT NULL REFERENCES benchmarks(id) ON DELETE CASCADE,
+    run_id INTEGER REFERENCES benchmark_runs(id) ON DELETE CASCADE,
     engine VARCHAR(100) NOT NULL,
     table_format VARCHAR(50) NOT NULL,
+    concurrency INTEGER DEFAULT 1,
+    arrival_rate DOUBLE PRECISION DEFAULT 0,
     total_queries INTEGER,
     successful_queries INTEGER,
     failed_queries INTEGER,
     avg_execution_time_ms DECIMAL(15,2),
     min_execution_time_ms DECIMAL(15,2),
     max_execution_time_ms DECIMAL(15,2),
+    median_execution_time_ms DECIMAL(15,2),
+    p90_execution_time_ms DECIMAL(15,2),
+    p95_execution_time_ms DECIMAL(15,2),
+    p99_execution_time_ms DECIMAL(15,2),
+    stddev_execution_time_ms DECIMAL(15,2),
+    coefficient_of_variation DECIMAL(10,4),
+    ci_lower_ms DECIMAL(15,2), -- 95% confidence interval of the mean
+    ci_upper_ms DECIMAL(15,2),
     total_rows_processed BIGINT,
     total_bytes_processed BIGINT,
     avg_cpu_usage DECIMAL(5,2),
     avg_memory_usage DECIMAL(15,2),
     total_io_read_bytes BIGINT,
     total_io_write_bytes BIGINT,
+    error_rate DECIMAL(5,4), -- failed / total queries
     throughput DECIMAL(10,4), -- queries per second
+    saturated BOOLEAN DEFAULT FALSE, -- Throughput fell short of the arrival rate
     efficiency_score DECIMAL(5,2),
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE TABLE IF NOT EXISTS regressions (
+    id SERIAL PRIMARY KEY,
+    benchmark_id INTEGER NOT NULL REFERENCES benchmarks(id) ON DELETE CASCADE,
+    run_id INTEGER NOT NULL REFERENCES benchmark_runs(id) ON DELETE CASCADE,
+    baseline_run_id INTEGER NOT NULL REFERENCES benchmark_runs(id) ON DELETE CASCADE,
+    query_id INTEGER NOT NULL REFERENCES queries(id) ON DELETE CASCADE,
+    engine VARCHAR(100) NOT NULL,
+    concurrency INTEGER DEFAULT 1,
+    arrival_rate DOUBLE PRECISION DEFAULT 0,
+    test VARCHAR(50) CHECK (test IN ('welch', 'mann_whitney')),
+    baseline_median_ms DECIMAL(15,2),
+    median_ms DECIMAL(15,2),
+    magnitude DECIMAL(10,4), -- relative slowdown of the median
+    p_value DOUBLE PRECISION,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE TABLE IF NOT EXISTS table_info (
     id SERIAL PRIMARY KEY,
     table_name VARCHAR(255) UNIQUE NOT NULL,
@@ -124,15 +186,23 @@ CREATE INDEX idx_benchmarks_created_at ON benchmarks(created_at);
 CREATE INDEX idx_queries_benchmark_id ON queries(benchmark_id);
 CREATE INDEX idx_queries_query_type ON queries(query_type);
 
+CREATE INDEX idx_benchmark_runs_benchmark_id ON benchmark_runs(benchmark_id);
+CREATE INDEX idx_benchmark_runs_status ON benchmark_runs(status);
+
 CREATE INDEX idx_query_executions_query_id ON query_executions(query_id);
+CREATE INDEX idx_query_executions_run_id ON query_executions(run_id);
 CREATE INDEX idx_query_executions_engine ON query_executions(engine);
 CREATE INDEX idx_query_executions_status ON query_executions(status);
 CREATE INDEX idx_query_executions_start_time ON query_executions(start_time);
 
 CREATE INDEX idx_results_benchmark_id ON results(benchmark_id);
+CREATE INDEX idx_results_run_id ON results(run_id);
 CREATE INDEX idx_results_engine ON results(engine);
 CREATE INDEX idx_results_table_format ON results(table_format);
 
+CREATE INDEX idx_regressions_benchmark_id ON regressions(benchmark_id);
+CREATE INDEX idx_regressions_run_id ON regressions(run_id);
+
 CREATE INDEX idx_table_info_table_format ON table_info(table_format);
 CREATE INDEX idx_engines_type ON engines(type);
 CREATE INDEX idx_engines_is_active ON engines(is_active);
@@ -152,6 +222,9 @@ CREATE TRIGGER update_benchmarks_updated_at BEFORE UPDATE ON benchmarks
 CREATE TRIGGER update_queries_updated_at BEFORE UPDATE ON queries
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+CREATE TRIGGER update_benchmark_runs_updated_at BEFORE UPDATE ON benchmark_runs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
 CREATE TRIGGER update_query_executions_updated_at BEFORE UPDATE ON query_executions
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
@@ -3,14 +3,21 @@ package config
 import (
 	"os"
 	"strconv"
+	"time"
 )
 
 type Config struct {
-	Server     ServerConfig
-	Database   DatabaseConfig
-	MinIO      MinIOConfig
-	Engines    EnginesConfig
-	Prometheus PrometheusConfig
+	Server        ServerConfig
+	Database      DatabaseConfig
+	MinIO         MinIOConfig
+	Engines       EnginesConfig
+	QueryService  QueryServiceConfig
+	EngineMonitor EngineMonitorConfig
+	Prometheus    PrometheusConfig
+	Scoring       ScoringConfig
+	Regression    RegressionConfig
+	Checksum      ChecksumConfig
+	Plan          PlanConfig
 }
 
 type ServerConfig struct {
@@ -47,10 +54,63 @@ type EngineConfig struct {
 	Password string
 }
 
+type QueryServiceConfig struct {
+	URL     string
+	Timeout time.Duration
+}
+
+// EngineMonitorConfig controls how often engine health is copied from the
+// query-service into the engines table
+type EngineMonitorConfig struct {
+	Interval time.Duration
+}
+
 type PrometheusConfig struct {
 	URL string
 }
 
+// ScoringConfig weighs the components of a result's efficiency score. See
+// services.EfficiencyScore for the formula.
+type ScoringConfig struct {
+	LatencyWeight      float64
+	ReliabilityWeight  float64
+	StabilityWeight    float64
+	ReferenceLatencyMs float64
+}
+
+// RegressionConfig controls how runs are checked for regressions. A query
+// regresses when its median latency grew by more than Threshold (0.1 = 10%)
+// and Test finds the difference significant at Alpha. Without a pinned
+// baseline, the samples of the last BaselineRuns completed runs are pooled.
+type RegressionConfig struct {
+	Test         string // "welch" or "mann_whitney"
+	Alpha        float64
+	Threshold    float64
+	BaselineRuns int
+}
+
+// ChecksumConfig controls the result checksums used to verify that engines
+// and table formats return the same rows. Results are checksummed in
+// separate, untimed executions after a run's measured iterations.
+type ChecksumConfig struct {
+	Enabled bool
+	// Verifications is how many times each query is checksummed per engine.
+	// More than one detects nondeterministic results.
+	Verifications int
+	// FloatPrecision is the number of decimal places floating point and
+	// decimal values are rounded to; negative compares them exactly
+	FloatPrecision    int
+	EmptyStringAsNull bool
+	NaNAsNull         bool
+}
+
+// PlanConfig controls the query plans captured for measured executions.
+// Capture is "none", "explain" to plan each query before running it, or
+// "analyze" to run EXPLAIN ANALYZE after it, which runs the query again.
+type PlanConfig struct {
+	Capture string
+}
+
 func Load() (*Config, error) {
 	return &Config{
 		Server: ServerConfig{
@@ -88,9 +148,38 @@ func Load() (*Config, error) {
 				Password: getEnv("STARROCKS_PASSWORD", ""),
 			},
 		},
+		QueryService: QueryServiceConfig{
+			URL:     getEnv("QUERY_SERVICE_URL", "http://localhost:8083"),
+			Timeout: getEnvDuration("QUERY_SERVICE_TIMEOUT", 30*time.Minute),
+		},
+		EngineMonitor: EngineMonitorConfig{
+			Interval: getEnvDuration("ENGINE_MONITOR_INTERVAL", 30*time.Second),
+		},
 		Prometheus: PrometheusConfig{
 			URL: getEnv("PROMETHEUS_URL", "http://localhost:9090"),
 		},
+		Scoring: ScoringConfig{
+			LatencyWeight:      getEnvFloat("EFFICIENCY_LATENCY_WEIGHT", 0.5),
+			ReliabilityWeight:  getEnvFloat("EFFICIENCY_RELIABILITY_WEIGHT", 0.3),
+			StabilityWeight:    getEnvFloat("EFFICIENCY_STABILITY_WEIGHT", 0.2),
+			ReferenceLatencyMs: getEnvFloat("EFFICIENCY_REFERENCE_LATENCY_MS", 1000),
+		},
+		Regression: RegressionConfig{
+			Test:         getEnv("REGRESSION_TEST", "welch"),
+			Alpha:        getEnvFloat("REGRESSION_ALPHA", 0.05),
+			Threshold:    getEnvFloat("REGRESSION_THRESHOLD", 0.1),
+			BaselineRuns: getEnvInt("REGRESSION_BASELINE_RUNS", 5),
+		},
+		Checksum: ChecksumConfig{
+			Enabled:           getEnvBool("CHECKSUM_ENABLED", true),
+			Verifications:     getEnvInt("CHECKSUM_VERIFICATIONS", 1),
+			FloatPrecision:    getEnvInt("CHECKSUM_FLOAT_PRECISION", 6),
+			EmptyStringAsNull: getEnvBool("CHECKSUM_EMPTY_STRING_AS_NULL", false),
+			NaNAsNull:         getEnvBool("CHECKSUM_NAN_AS_NULL", false),
+		},
+		Plan: PlanConfig{
+			Capture: getEnv("PLAN_CAPTURE", "none"),
+		},
 	}, nil
 }
 
@@ -109,3 +198,30 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
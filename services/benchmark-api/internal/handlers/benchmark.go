@@ -1,11 +1,17 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 
 	"benchmark-api/internal/models"
 	"benchmark-api/internal/services"
@@ -43,6 +49,10 @@ func (h *BenchmarkHandler) CreateBenchmark(c *gin.Context) {
 	}
 
 	if err := h.service.CreateBenchmark(&benchmark); err != nil {
+		if errors.Is(err, services.ErrInvalidWorkload) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		h.logger.WithError(err).Error("Failed to create benchmark")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create benchmark"})
 		return
@@ -153,6 +163,10 @@ func (h *BenchmarkHandler) UpdateBenchmark(c *gin.Context) {
 
 	benchmark.ID = uint(id)
 	if err := h.service.UpdateBenchmark(&benchmark); err != nil {
+		if errors.Is(err, services.ErrInvalidWorkload) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		h.logger.WithError(err).Error("Failed to update benchmark")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update benchmark"})
 		return
@@ -186,13 +200,23 @@ func (h *BenchmarkHandler) DeleteBenchmark(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// RunBenchmarkRequest is the optional body of POST /benchmarks/{id}/run
+type RunBenchmarkRequest struct {
+	TriggeredBy string `json:"triggered_by"`
+}
+
 // RunBenchmark godoc
 // @Summary Run a benchmark
 // @Description Start executing all queries in a benchmark across specified engines
 // @Tags benchmarks
+// @Accept json
+// @Produce json
 // @Param id path int true "Benchmark ID"
-// @Success 202 {object} map[string]string
+// @Param request body RunBenchmarkRequest false "Run options"
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/benchmarks/{id}/run [post]
 func (h *BenchmarkHandler) RunBenchmark(c *gin.Context) {
@@ -202,22 +226,220 @@ func (h *BenchmarkHandler) RunBenchmark(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.RunBenchmark(uint(id)); err != nil {
-		h.logger.WithError(err).Error("Failed to run benchmark")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run benchmark"})
+	var req RunBenchmarkRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.TriggeredBy == "" {
+		req.TriggeredBy = c.GetHeader("X-User")
+	}
+	if req.TriggeredBy == "" {
+		req.TriggeredBy = "anonymous"
+	}
+
+	run, err := h.service.RunBenchmark(uint(id), req.TriggeredBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Benchmark not found"})
+		case errors.Is(err, services.ErrBenchmarkRunning):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrBenchmarkEmpty):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.logger.WithError(err).Error("Failed to run benchmark")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run benchmark"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Benchmark execution started", "run_id": run.ID, "run": run})
+}
+
+// ListBenchmarkRuns godoc
+// @Summary List benchmark runs
+// @Description Get the runs of a benchmark, most recent first
+// @Tags benchmarks
+// @Produce json
+// @Param id path int true "Benchmark ID"
+// @Param limit query int false "Limit number of results" default(20)
+// @Param offset query int false "Offset for pagination" default(0)
+// @Success 200 {array} models.BenchmarkRun
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/benchmarks/{id}/runs [get]
+func (h *BenchmarkHandler) ListBenchmarkRuns(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid benchmark ID"})
+		return
+	}
+
+	limit := 20
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil {
+			offset = parsed
+		}
+	}
+
+	runs, err := h.service.ListBenchmarkRuns(uint(id), limit, offset)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Benchmark not found"})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to list benchmark runs")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list benchmark runs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// GetBenchmarkRun godoc
+// @Summary Get a benchmark run
+// @Description Get a single run of a benchmark with its executions and results
+// @Tags benchmarks
+// @Produce json
+// @Param id path int true "Benchmark ID"
+// @Param run_id path int true "Run ID"
+// @Success 200 {object} models.BenchmarkRun
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/benchmarks/{id}/runs/{run_id} [get]
+func (h *BenchmarkHandler) GetBenchmarkRun(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid benchmark ID"})
+		return
+	}
+
+	runID, err := strconv.ParseUint(c.Param("run_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run ID"})
 		return
 	}
 
-	c.JSON(http.StatusAccepted, gin.H{"message": "Benchmark execution started"})
+	run, err := h.service.GetBenchmarkRun(uint(id), uint(runID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Benchmark run not found"})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get benchmark run")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get benchmark run"})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// GetBenchmarkRunStatistics godoc
+// @Summary Get benchmark run statistics
+// @Description Get latency statistics of every query of a run per engine, computed from the measured iterations
+// @Tags benchmarks
+// @Produce json
+// @Param id path int true "Benchmark ID"
+// @Param run_id path int true "Run ID"
+// @Success 200 {array} services.QueryStatistics
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/benchmarks/{id}/runs/{run_id}/statistics [get]
+func (h *BenchmarkHandler) GetBenchmarkRunStatistics(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid benchmark ID"})
+		return
+	}
+
+	runID, err := strconv.ParseUint(c.Param("run_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run ID"})
+		return
+	}
+
+	statistics, err := h.service.GetRunStatistics(uint(id), uint(runID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Benchmark run not found"})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get benchmark run statistics")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get benchmark run statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, statistics)
+}
+
+// GetBenchmarkRunCorrectness godoc
+// @Summary Check benchmark run correctness
+// @Description Compare the result checksums of every query of a run across its engines and against another run, by default the latest completed run on a different table format of the same dataset. Queries of different benchmarks are matched by name.
+// @Tags benchmarks
+// @Produce json
+// @Param id path int true "Benchmark ID"
+// @Param run_id path int true "Run ID"
+// @Param against_run_id query int false "Run to compare against"
+// @Success 200 {object} services.CorrectnessReport
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/benchmarks/{id}/runs/{run_id}/correctness [get]
+func (h *BenchmarkHandler) GetBenchmarkRunCorrectness(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid benchmark ID"})
+		return
+	}
+
+	runID, err := strconv.ParseUint(c.Param("run_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run ID"})
+		return
+	}
+
+	var againstRunID *uint
+	if a := c.Query("against_run_id"); a != "" {
+		parsed, err := strconv.ParseUint(a, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid against_run_id"})
+			return
+		}
+		against := uint(parsed)
+		againstRunID = &against
+	}
+
+	report, err := h.service.GetRunCorrectness(uint(id), uint(runID), againstRunID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Benchmark run not found"})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to check benchmark run correctness")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check benchmark run correctness"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
 }
 
 // GetBenchmarkStatus godoc
 // @Summary Get benchmark status
-// @Description Get the current status of a benchmark execution
+// @Description Get the progress of the benchmark's most recent run
 // @Tags benchmarks
 // @Produce json
 // @Param id path int true "Benchmark ID"
-// @Success 200 {object} map[string]interface{}
+// @Success 200 {object} services.BenchmarkStatus
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/benchmarks/{id}/status [get]
@@ -230,6 +452,10 @@ func (h *BenchmarkHandler) GetBenchmarkStatus(c *gin.Context) {
 
 	status, err := h.service.GetBenchmarkStatus(uint(id))
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Benchmark not found"})
+			return
+		}
 		h.logger.WithError(err).Error("Failed to get benchmark status")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get benchmark status"})
 		return
@@ -238,9 +464,84 @@ func (h *BenchmarkHandler) GetBenchmarkStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, status)
 }
 
+// StreamBenchmarkEvents godoc
+// @Summary Stream benchmark run events
+// @Description Server-Sent Events stream of query-started, query-finished, engine-failed and run-completed events. Send Last-Event-ID (or last_event_id) to resume after a reconnect.
+// @Tags benchmarks
+// @Produce text/event-stream
+// @Param id path int true "Benchmark ID"
+// @Param Last-Event-ID header int false "ID of the last event received"
+// @Param last_event_id query int false "ID of the last event received"
+// @Success 200 {object} services.RunEvent
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/benchmarks/{id}/events [get]
+func (h *BenchmarkHandler) StreamBenchmarkEvents(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid benchmark ID"})
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var since uint64
+	if lastEventID != "" {
+		if since, err = strconv.ParseUint(lastEventID, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+	}
+
+	events, unsubscribe, err := h.service.SubscribeEvents(uint(id), since)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Benchmark not found"})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to subscribe to benchmark events")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe to benchmark events"})
+		return
+	}
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case event, ok := <-events:
+			if !ok {
+				// Dropped for falling behind or the server shutting down; the
+				// client resumes with Last-Event-ID
+				return false
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				h.logger.WithError(err).Error("Failed to encode benchmark event")
+				return true
+			}
+			_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			return err == nil
+		}
+	})
+}
+
 // GetBenchmarkResults godoc
 // @Summary Get benchmark results
-// @Description Get aggregated results for a benchmark
+// @Description Get the aggregated results of every run of a benchmark, one per engine and table format, newest run first
 // @Tags benchmarks
 // @Produce json
 // @Param id path int true "Benchmark ID"
@@ -257,6 +558,10 @@ func (h *BenchmarkHandler) GetBenchmarkResults(c *gin.Context) {
 
 	results, err := h.service.GetBenchmarkResults(uint(id))
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Benchmark not found"})
+			return
+		}
 		h.logger.WithError(err).Error("Failed to get benchmark results")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get benchmark results"})
 		return
@@ -264,3 +569,104 @@ func (h *BenchmarkHandler) GetBenchmarkResults(c *gin.Context) {
 
 	c.JSON(http.StatusOK, results)
 }
+
+// AggregateBenchmarkRun godoc
+// @Summary Recompute benchmark run results
+// @Description Recompute the aggregated results and regressions of a run from its query executions, replacing the stored ones
+// @Tags benchmarks
+// @Produce json
+// @Param id path int true "Benchmark ID"
+// @Param run_id path int true "Run ID"
+// @Success 200 {array} models.Result
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/benchmarks/{id}/runs/{run_id}/aggregate [post]
+func (h *BenchmarkHandler) AggregateBenchmarkRun(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid benchmark ID"})
+		return
+	}
+
+	runID, err := strconv.ParseUint(c.Param("run_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run ID"})
+		return
+	}
+
+	results, err := h.service.AggregateBenchmarkRun(uint(id), uint(runID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Benchmark run not found"})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to aggregate benchmark run results")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate benchmark run results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// ListBenchmarkRegressions godoc
+// @Summary List benchmark regressions
+// @Description Get the queries that got slower compared to the baseline run, newest run and largest slowdown first
+// @Tags benchmarks
+// @Produce json
+// @Param id path int true "Benchmark ID"
+// @Param run_id query int false "Filter by run ID"
+// @Param engine query string false "Filter by engine"
+// @Param limit query int false "Limit number of results" default(50)
+// @Param offset query int false "Offset for pagination" default(0)
+// @Success 200 {array} models.Regression
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/benchmarks/{id}/regressions [get]
+func (h *BenchmarkHandler) ListBenchmarkRegressions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid benchmark ID"})
+		return
+	}
+
+	filters := make(map[string]interface{})
+	if r := c.Query("run_id"); r != "" {
+		runID, err := strconv.ParseUint(r, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run ID"})
+			return
+		}
+		filters["run_id"] = uint(runID)
+	}
+	if engine := c.Query("engine"); engine != "" {
+		filters["engine"] = engine
+	}
+
+	limit := 50
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil {
+			offset = parsed
+		}
+	}
+
+	regressions, err := h.service.ListBenchmarkRegressions(uint(id), filters, limit, offset)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Benchmark not found"})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to list benchmark regressions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list benchmark regressions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, regressions)
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"benchmark-api/internal/services"
+)
+
+type ExecutionHandler struct {
+	service *services.ExecutionService
+	logger  *logrus.Logger
+}
+
+func NewExecutionHandler(service *services.ExecutionService, logger *logrus.Logger) *ExecutionHandler {
+	return &ExecutionHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetExecution godoc
+// @Summary Get a query execution by ID
+// @Description Get a single measured execution of a query
+// @Tags executions
+// @Produce json
+// @Param id path int true "Execution ID"
+// @Success 200 {object} models.QueryExecution
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/executions/{id} [get]
+func (h *ExecutionHandler) GetExecution(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid execution ID"})
+		return
+	}
+
+	execution, err := h.service.GetExecution(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Execution not found"})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get execution")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get execution"})
+		return
+	}
+
+	c.JSON(http.StatusOK, execution)
+}
+
+// GetExecutionPlan godoc
+// @Summary Get the plan of a query execution
+// @Description Get the EXPLAIN or EXPLAIN ANALYZE plan captured when the query ran, as the engine's JSON plan or as text
+// @Tags executions
+// @Produce json
+// @Param id path int true "Execution ID"
+// @Success 200 {object} services.ExecutionPlan
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/executions/{id}/plan [get]
+func (h *ExecutionHandler) GetExecutionPlan(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid execution ID"})
+		return
+	}
+
+	plan, err := h.service.GetPlan(uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Execution not found"})
+		case errors.Is(err, services.ErrNoPlan):
+			c.JSON(http.StatusNotFound, gin.H{"error": "No plan was captured for this execution"})
+		default:
+			h.logger.WithError(err).Error("Failed to get execution plan")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get execution plan"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// DiffExecutionPlans godoc
+// @Summary Diff the plans of two executions
+// @Description Show what changed in a query's plan between two executions, or between the latest completed runs of the query's benchmark on two table formats: operators, scanned tables with their pruning and predicate pushdown, join distribution and estimated vs actual rows
+// @Tags executions
+// @Produce json
+// @Param base query int false "Base execution ID, used with candidate"
+// @Param candidate query int false "Candidate execution ID, used with base"
+// @Param query_id query int false "Query ID, to compare its plans on hive and iceberg"
+// @Param engine query string false "Engine, used with query_id"
+// @Success 200 {object} services.PlanDiff
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/executions/plan-diff [get]
+func (h *ExecutionHandler) DiffExecutionPlans(c *gin.Context) {
+	req := services.PlanDiffRequest{Engine: c.Query("engine")}
+	for name, target := range map[string]*uint{
+		"base":      &req.BaseID,
+		"candidate": &req.CandidateID,
+		"query_id":  &req.QueryID,
+	} {
+		v := c.Query(name)
+		if v == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+			return
+		}
+		*target = uint(id)
+	}
+
+	diff, err := h.service.DiffPlans(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidPlanDiff):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrNoPlan):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Execution or query not found"})
+		default:
+			h.logger.WithError(err).Error("Failed to diff execution plans")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to diff execution plans"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
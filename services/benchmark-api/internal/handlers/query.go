@@ -1,10 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"benchmark-api/internal/models"
 	"benchmark-api/internal/services"
+	"benchmark-api/pkg/queryclient"
 )
 
 type QueryHandler struct {
@@ -19,49 +26,329 @@ func NewQueryHandler(service *services.QueryService, logger *logrus.Logger) *Que
 	}
 }
 
+// CreateQuery godoc
+// @Summary Create a query
+// @Description Add a query to a benchmark. The SQL must be a single SELECT, WITH, VALUES or TABLE statement.
+// @Tags queries
+// @Accept json
+// @Produce json
+// @Param query body models.Query true "Query"
+// @Success 201 {object} models.Query
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/queries [post]
 func (h *QueryHandler) CreateQuery(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "Not implemented yet"})
+	var query models.Query
+	if err := c.ShouldBindJSON(&query); err != nil {
+		h.logger.WithError(err).Error("Failed to bind query JSON")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query.ID = 0
+	if err := h.service.CreateQuery(&query); err != nil {
+		if errors.Is(err, services.ErrInvalidQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to create query")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create query"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, query)
 }
 
+// ListQueries godoc
+// @Summary List queries
+// @Description Get a list of queries with optional filtering
+// @Tags queries
+// @Produce json
+// @Param benchmark_id query int false "Filter by benchmark ID"
+// @Param query_type query string false "Filter by query type: select, aggregation, join or window"
+// @Param limit query int false "Limit number of results" default(20)
+// @Param offset query int false "Offset for pagination" default(0)
+// @Success 200 {array} models.Query
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/queries [get]
 func (h *QueryHandler) ListQueries(c *gin.Context) {
-	c.JSON(http.StatusOK, []interface{}{})
+	filters := make(map[string]interface{})
+
+	if v := c.Query("benchmark_id"); v != "" {
+		benchmarkID, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid benchmark_id"})
+			return
+		}
+		filters["benchmark_id"] = uint(benchmarkID)
+	}
+	if queryType := c.Query("query_type"); queryType != "" {
+		filters["query_type"] = queryType
+	}
+
+	limit := 20
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil {
+			offset = parsed
+		}
+	}
+
+	queries, err := h.service.ListQueries(filters, limit, offset)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list queries")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list queries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, queries)
 }
 
+// GetQuery godoc
+// @Summary Get a query by ID
+// @Description Get a query along with its executions
+// @Tags queries
+// @Produce json
+// @Param id path int true "Query ID"
+// @Success 200 {object} models.Query
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/queries/{id} [get]
 func (h *QueryHandler) GetQuery(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "Not implemented yet"})
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query ID"})
+		return
+	}
+
+	query, err := h.service.GetQueryByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Query not found"})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get query")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get query"})
+		return
+	}
+
+	c.JSON(http.StatusOK, query)
 }
 
+// UpdateQuery godoc
+// @Summary Update a query
+// @Description Replace an existing query, validated the same way as on creation
+// @Tags queries
+// @Accept json
+// @Produce json
+// @Param id path int true "Query ID"
+// @Param query body models.Query true "Updated query"
+// @Success 200 {object} models.Query
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/queries/{id} [put]
 func (h *QueryHandler) UpdateQuery(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "Not implemented yet"})
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query ID"})
+		return
+	}
+
+	var query models.Query
+	if err := c.ShouldBindJSON(&query); err != nil {
+		h.logger.WithError(err).Error("Failed to bind query JSON")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query.ID = uint(id)
+	if err := h.service.UpdateQuery(&query); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Query not found"})
+		case errors.Is(err, services.ErrInvalidQuery):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.logger.WithError(err).Error("Failed to update query")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update query"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, query)
 }
 
+// DeleteQuery godoc
+// @Summary Delete a query
+// @Description Delete a query by ID. Its past executions are kept.
+// @Tags queries
+// @Param id path int true "Query ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/queries/{id} [delete]
 func (h *QueryHandler) DeleteQuery(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "Not implemented yet"})
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query ID"})
+		return
+	}
+
+	if err := h.service.DeleteQuery(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Query not found"})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to delete query")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete query"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
 
+// ExecuteQuery godoc
+// @Summary Execute a query
+// @Description Run a query once on an engine through the query-service and record it as an execution outside any benchmark run. Executions that fail on the engine are recorded and returned with their status and error.
+// @Tags queries
+// @Accept json
+// @Produce json
+// @Param id path int true "Query ID"
+// @Param request body services.ExecuteQueryRequest true "Engine and options"
+// @Success 201 {object} services.QueryExecutionResult
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/queries/{id}/execute [post]
 func (h *QueryHandler) ExecuteQuery(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "Not implemented yet"})
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query ID"})
+		return
+	}
+
+	var req services.ExecuteQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.ExecuteQuery(c.Request.Context(), uint(id), req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Query not found"})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to execute query")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute query"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
 }
 
+// GetQueryResults godoc
+// @Summary Get a query's execution history
+// @Description Get the executions of a query, newest first, from benchmark runs and ad-hoc executions alike
+// @Tags queries
+// @Produce json
+// @Param id path int true "Query ID"
+// @Param engine query string false "Filter by engine"
+// @Param status query string false "Filter by status"
+// @Param limit query int false "Limit number of results" default(20)
+// @Param offset query int false "Offset for pagination" default(0)
+// @Success 200 {array} models.QueryExecution
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/queries/{id}/results [get]
 func (h *QueryHandler) GetQueryResults(c *gin.Context) {
-	c.JSON(http.StatusOK, []interface{}{})
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query ID"})
+		return
+	}
+
+	filters := make(map[string]interface{})
+	if engine := c.Query("engine"); engine != "" {
+		filters["engine"] = engine
+	}
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+
+	limit := 20
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil {
+			offset = parsed
+		}
+	}
+
+	executions, err := h.service.ListQueryExecutions(uint(id), filters, limit, offset)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Query not found"})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to list query executions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list query executions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, executions)
 }
 
 func (h *QueryHandler) ListEngines(c *gin.Context) {
-	engines := []map[string]interface{}{
-		{"name": "trino", "type": "trino", "status": "active"},
-		{"name": "presto", "type": "presto", "status": "active"},
-		{"name": "starrocks", "type": "starrocks", "status": "active"},
+	engines, err := h.service.ListEngines(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list engines")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to list engines"})
+		return
 	}
 	c.JSON(http.StatusOK, engines)
 }
 
+// GetEngineStatus godoc
+// @Summary Get an engine's health
+// @Description Get the latest health check of an engine by the query-service: its status, version, uptime, workers and connection latency
+// @Tags engines
+// @Produce json
+// @Param engine path string true "Engine name"
+// @Param refresh query bool false "Check the engine now instead of returning the latest check"
+// @Success 200 {object} queryclient.EngineHealth
+// @Failure 404 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Router /api/v1/engines/{engine}/status [get]
 func (h *QueryHandler) GetEngineStatus(c *gin.Context) {
 	engine := c.Param("engine")
-	status := map[string]interface{}{
-		"engine": engine,
-		"status": "healthy",
-		"uptime": "1h 30m",
+	status, err := h.service.GetEngineStatus(c.Request.Context(), engine, c.Query("refresh") == "true")
+	if err != nil {
+		if errors.Is(err, queryclient.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Engine not found"})
+			return
+		}
+		h.logger.WithError(err).WithField("engine", engine).Error("Failed to get engine status")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to get engine status"})
+		return
 	}
 	c.JSON(http.StatusOK, status)
 }
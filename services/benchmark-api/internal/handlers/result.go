@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 
 	"benchmark-api/internal/services"
 )
@@ -21,22 +26,261 @@ func NewResultHandler(service *services.ResultService, logger *logrus.Logger) *R
 	}
 }
 
-// ListResults lists all results
+// ListResults godoc
+// @Summary List results
+// @Description Get aggregated benchmark results with optional filtering
+// @Tags results
+// @Produce json
+// @Param benchmark_id query int false "Filter by benchmark ID"
+// @Param run_id query int false "Filter by run ID"
+// @Param engine query string false "Filter by engine"
+// @Param table_format query string false "Filter by table format"
+// @Param concurrency query int false "Filter by concurrency level"
+// @Param limit query int false "Limit number of results" default(20)
+// @Param offset query int false "Offset for pagination" default(0)
+// @Success 200 {array} models.Result
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/results [get]
 func (h *ResultHandler) ListResults(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "Not implemented yet"})
+	filters := make(map[string]interface{})
+
+	for _, key := range []string{"benchmark_id", "run_id"} {
+		if v := c.Query(key); v != "" {
+			id, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + key})
+				return
+			}
+			filters[key] = uint(id)
+		}
+	}
+	if engine := c.Query("engine"); engine != "" {
+		filters["engine"] = engine
+	}
+	if tableFormat := c.Query("table_format"); tableFormat != "" {
+		filters["table_format"] = tableFormat
+	}
+	if v := c.Query("concurrency"); v != "" {
+		concurrency, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid concurrency"})
+			return
+		}
+		filters["concurrency"] = concurrency
+	}
+
+	limit := 20
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil {
+			offset = parsed
+		}
+	}
+
+	results, err := h.service.ListResults(filters, limit, offset)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list results")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
 }
 
-// GetResult gets a result by ID
+// GetResult godoc
+// @Summary Get a result by ID
+// @Description Get a specific aggregated result by its ID
+// @Tags results
+// @Produce json
+// @Param id path int true "Result ID"
+// @Success 200 {object} models.Result
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/results/{id} [get]
 func (h *ResultHandler) GetResult(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "Not implemented yet"})
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid result ID"})
+		return
+	}
+
+	result, err := h.service.GetResult(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Result not found"})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get result")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get result"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
 }
 
-// CompareResults compares results
+// CompareResults godoc
+// @Summary Compare results
+// @Description Compare two or more results or runs, or a benchmark's latest completed runs pivoted on engine or table format. Every side is compared against the first one, query by query.
+// @Tags results
+// @Produce json
+// @Param result_ids query string false "Comma-separated result IDs"
+// @Param run_ids query string false "Comma-separated run IDs"
+// @Param benchmark_id query int false "Benchmark ID, used with pivot"
+// @Param pivot query string false "Dimension to compare a benchmark on: engine or table_format"
+// @Success 200 {object} services.Comparison
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/results/compare [get]
 func (h *ResultHandler) CompareResults(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "Not implemented yet"})
+	var req services.CompareRequest
+	var err error
+
+	if req.ResultIDs, err = parseIDs(c.QueryArray("result_ids")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid result_ids"})
+		return
+	}
+	if req.RunIDs, err = parseIDs(c.QueryArray("run_ids")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run_ids"})
+		return
+	}
+	if v := c.Query("benchmark_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid benchmark_id"})
+			return
+		}
+		req.BenchmarkID = uint(id)
+	}
+	req.Pivot = c.Query("pivot")
+
+	comparison, err := h.service.Compare(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidComparison):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Result or run not found"})
+		default:
+			h.logger.WithError(err).Error("Failed to compare results")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare results"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
 }
 
-// GetAnalytics gets analytics data
+// GetAnalytics godoc
+// @Summary Get analytics
+// @Description Get time series of latency, throughput and failure rate computed from historical query executions, one series per group
+// @Tags results
+// @Produce json
+// @Param bucket query string false "Bucket size: hour, day, week or month" default(day)
+// @Param group_by query string false "Comma-separated dimensions: engine, table_format, dataset_size, query_type, complexity, concurrency, arrival_rate"
+// @Param from query string false "Start of the range, RFC 3339 or YYYY-MM-DD (default 30 days before to)"
+// @Param to query string false "End of the range, RFC 3339 or YYYY-MM-DD (default now)"
+// @Param benchmark_id query int false "Filter by benchmark ID"
+// @Param engine query string false "Filter by engine"
+// @Param table_format query string false "Filter by table format"
+// @Param dataset_size query string false "Filter by dataset size"
+// @Param query_type query string false "Filter by query type"
+// @Param complexity query string false "Filter by query complexity"
+// @Param concurrency query int false "Filter by concurrency level"
+// @Param arrival_rate query number false "Filter by open-loop arrival rate"
+// @Success 200 {object} services.Analytics
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/results/analytics [get]
 func (h *ResultHandler) GetAnalytics(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "Not implemented yet"})
+	req := services.AnalyticsRequest{
+		Bucket:  c.Query("bucket"),
+		Filters: make(map[string]interface{}),
+	}
+
+	for _, value := range c.QueryArray("group_by") {
+		for _, dimension := range strings.Split(value, ",") {
+			if dimension = strings.TrimSpace(dimension); dimension != "" {
+				req.GroupBy = append(req.GroupBy, dimension)
+			}
+		}
+	}
+
+	var err error
+	if req.From, err = parseTime(c.Query("from")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from"})
+		return
+	}
+	if req.To, err = parseTime(c.Query("to")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to"})
+		return
+	}
+
+	if v := c.Query("benchmark_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid benchmark_id"})
+			return
+		}
+		req.Filters["benchmark_id"] = uint(id)
+	}
+	for _, key := range []string{"engine", "table_format", "dataset_size", "query_type", "complexity", "concurrency", "arrival_rate"} {
+		if v := c.Query(key); v != "" {
+			req.Filters[key] = v
+		}
+	}
+
+	analytics, err := h.service.GetAnalytics(req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAnalytics) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get analytics")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get analytics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, analytics)
+}
+
+// parseIDs parses IDs given either as repeated query parameters or as a
+// comma-separated list
+func parseIDs(values []string) ([]uint, error) {
+	var ids []uint
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			id, err := strconv.ParseUint(part, 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids, nil
+}
+
+// parseTime parses an RFC 3339 timestamp or a YYYY-MM-DD date; empty is nil
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if t, err = time.Parse("2006-01-02", value); err != nil {
+			return nil, err
+		}
+	}
+	return &t, nil
 }
@@ -1,10 +1,26 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 	"gorm.io/gorm"
 )
 
+// Status values used by benchmarks and query executions
+const (
+	StatusCreated   = "created"
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+
+	// Query executions can also end in these states
+	StatusCancelled = "cancelled"
+	StatusTimedOut  = "timed_out"
+)
+
 // Benchmark represents a benchmark configuration
 type Benchmark struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
@@ -14,14 +30,223 @@ type Benchmark struct {
 	DatasetName string    `json:"dataset_name" gorm:"not null"`
 	DatasetSize string    `json:"dataset_size"`                 // "small", "medium", "large"
 	Engines     []string  `json:"engines" gorm:"type:text[]"`   // JSON array of engine names
+	// Warm-up iterations run before measuring and are not recorded
+	WarmupIterations   int `json:"warmup_iterations" gorm:"default:0" binding:"min=0"`
+	MeasuredIterations int `json:"measured_iterations" gorm:"default:1" binding:"omitempty,min=1"`
+	// Runs are checked for regressions against this run, or the last completed one if unset
+	BaselineRunID *uint `json:"baseline_run_id"`
+	Workload      WorkloadConfig `json:"workload" gorm:"type:jsonb"`
 	Status      string    `json:"status" gorm:"default:'created'"` // "created", "running", "completed", "failed"
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 	
 	// Relationships
-	Queries []Query  `json:"queries,omitempty" gorm:"foreignKey:BenchmarkID"`
-	Results []Result `json:"results,omitempty" gorm:"foreignKey:BenchmarkID"`
+	Queries []Query        `json:"queries,omitempty" gorm:"foreignKey:BenchmarkID"`
+	Results []Result       `json:"results,omitempty" gorm:"foreignKey:BenchmarkID"`
+	Runs    []BenchmarkRun `json:"runs,omitempty" gorm:"foreignKey:BenchmarkID"`
+}
+
+// BenchmarkRun represents one execution of a benchmark
+type BenchmarkRun struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	BenchmarkID  uint       `json:"benchmark_id" gorm:"not null"`
+	Status       string     `json:"status" gorm:"default:'pending'"` // "pending", "running", "completed", "failed"
+	TriggeredBy  string     `json:"triggered_by"`
+	Config       RunConfig  `json:"config" gorm:"type:jsonb"`
+	StartTime    *time.Time `json:"start_time"`
+	EndTime      *time.Time `json:"end_time"`
+	ErrorMessage *string    `json:"error_message"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	// Relationships
+	Benchmark  Benchmark        `json:"benchmark,omitempty" gorm:"foreignKey:BenchmarkID"`
+	Executions []QueryExecution `json:"executions,omitempty" gorm:"foreignKey:RunID"`
+	Results    []Result         `json:"results,omitempty" gorm:"foreignKey:RunID"`
+}
+
+// RunConfig is a snapshot of the engine and catalog configuration a run used
+type RunConfig struct {
+	TableFormat        string            `json:"table_format"`
+	Catalog            string            `json:"catalog"`
+	DatasetName        string            `json:"dataset_name"`
+	DatasetSize        string            `json:"dataset_size"`
+	WarmupIterations   int               `json:"warmup_iterations"`
+	MeasuredIterations int               `json:"measured_iterations"`
+	Workload           WorkloadConfig    `json:"workload"`
+	PlanCapture        string            `json:"plan_capture,omitempty"` // "explain" or "analyze"; empty captures no plans
+	Engines            []RunEngineConfig `json:"engines"`
+}
+
+// RunEngineConfig describes an engine as it was configured when a run started
+type RunEngineConfig struct {
+	Name     string `json:"name"`
+	Host     string `json:"host"`
+	Username string `json:"username"`
+}
+
+// Value implements driver.Valuer so RunConfig is stored as JSON
+func (c RunConfig) Value() (driver.Value, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner so RunConfig is loaded from JSON
+func (c *RunConfig) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = RunConfig{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	default:
+		return fmt.Errorf("cannot scan %T into RunConfig", value)
+	}
+}
+
+// Workload modes
+const (
+	WorkloadSequential = "sequential"
+	WorkloadConcurrent = "concurrent"
+	WorkloadOpenLoop   = "open_loop"
+)
+
+// Plan types, which say how a QueryExecution's plan was captured
+const (
+	PlanExplain = "explain"
+	PlanAnalyze = "analyze"
+)
+
+// Arrival schedules of the open-loop mode
+const (
+	ArrivalPoisson  = "poisson"
+	ArrivalConstant = "constant"
+)
+
+// WorkloadConfig describes how a benchmark drives its engines. The default
+// sequential mode runs the queries one at a time for the measured
+// iterations. The concurrent mode runs each concurrency level in turn, with
+// that many virtual users each running the queries in their own shuffled
+// order, for DurationSeconds or else Iterations passes over the queries.
+// The open-loop mode issues queries at each of the ArrivalRates in turn for
+// DurationSeconds, on schedule whether or not earlier queries have finished.
+type WorkloadConfig struct {
+	Mode              string    `json:"mode"`                         // "sequential", "concurrent" or "open_loop"
+	ConcurrencyLevels []int     `json:"concurrency_levels,omitempty"` // virtual users per level, e.g. [1, 2, 4, 8, 16]
+	ArrivalRates      []float64 `json:"arrival_rates,omitempty"`      // open loop: target queries per second per step
+	Arrival           string    `json:"arrival,omitempty"`            // open loop: "poisson" or "constant"
+	MaxInFlight       int       `json:"max_in_flight,omitempty"`      // open loop: queries waiting past it still count their wait; 0 is unlimited
+	DurationSeconds   int       `json:"duration_seconds,omitempty"`   // per level; stops starting queries after it
+	Iterations        int       `json:"iterations,omitempty"`         // passes over the queries per user when there's no duration
+	ThinkTimeMs       int       `json:"think_time_ms,omitempty"`      // pause between a user's queries
+	Seed              int64     `json:"seed,omitempty"`               // shuffle seed; picked at random when zero
+}
+
+// Value implements driver.Valuer so WorkloadConfig is stored as JSON
+func (w WorkloadConfig) Value() (driver.Value, error) {
+	data, err := json.Marshal(w)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner so WorkloadConfig is loaded from JSON
+func (w *WorkloadConfig) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*w = WorkloadConfig{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, w)
+	case string:
+		return json.Unmarshal([]byte(v), w)
+	default:
+		return fmt.Errorf("cannot scan %T into WorkloadConfig", value)
+	}
+}
+
+// PlanAnalysis is the structured form of a captured query plan. Actual rows
+// and what was read are only known for plans captured with EXPLAIN ANALYZE,
+// and only as far as the engine reports them.
+type PlanAnalysis struct {
+	Root          *PlanNode      `json:"root"` // operator tree
+	Tables        []ScannedTable `json:"tables"`
+	Joins         []PlanJoin     `json:"joins"`
+	EstimatedRows *float64       `json:"estimated_rows"` // rows the planner expected the query to return
+	ActualRows    *int64         `json:"actual_rows"`
+}
+
+// PlanNode is an operator of a query plan
+type PlanNode struct {
+	ID            string            `json:"id,omitempty"`
+	Name          string            `json:"name"` // e.g. "ScanFilterProject", "InnerJoin"
+	Descriptor    map[string]string `json:"descriptor,omitempty"`
+	EstimatedRows *float64          `json:"estimated_rows,omitempty"`
+	ActualRows    *int64            `json:"actual_rows,omitempty"`
+	Children      []*PlanNode       `json:"children,omitempty"`
+}
+
+// Predicate pushdown of a scanned table
+const (
+	PushdownFull    = "full"    // every predicate was handed to the connector
+	PushdownPartial = "partial" // some predicates are still filtered after the scan
+	PushdownNone    = "none"    // all predicates are filtered after the scan
+)
+
+// ScannedTable is a table a plan reads
+type ScannedTable struct {
+	Table              string   `json:"table"` // schema.table
+	Catalog            string   `json:"catalog"`
+	Constraint         string   `json:"constraint,omitempty"`         // predicate handed to the connector
+	FilterPredicate    string   `json:"filter_predicate,omitempty"`   // predicate evaluated after the scan
+	PredicatePushdown  string   `json:"predicate_pushdown,omitempty"` // "full", "partial" or "none"; empty without predicates
+	PartitionsRead     *int64   `json:"partitions_read,omitempty"`
+	PartitionsTotal    *int64   `json:"partitions_total,omitempty"`
+	FilesRead          *int64   `json:"files_read,omitempty"`
+	Splits             *int64   `json:"splits,omitempty"`
+	EstimatedRows      *float64 `json:"estimated_rows,omitempty"`
+	ActualRows         *int64   `json:"actual_rows,omitempty"` // rows read from the table
+	PhysicalInputBytes *int64   `json:"physical_input_bytes,omitempty"`
+}
+
+// PlanJoin is a join in a query plan
+type PlanJoin struct {
+	Type          string   `json:"type"`         // e.g. "InnerJoin", "LeftJoin", "SemiJoin"
+	Distribution  string   `json:"distribution"` // e.g. "PARTITIONED", "REPLICATED", "BROADCAST"; empty if not shown
+	Criteria      string   `json:"criteria,omitempty"`
+	EstimatedRows *float64 `json:"estimated_rows,omitempty"`
+	ActualRows    *int64   `json:"actual_rows,omitempty"`
+}
+
+// Value implements driver.Valuer so PlanAnalysis is stored as JSON
+func (p PlanAnalysis) Value() (driver.Value, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner so PlanAnalysis is loaded from JSON
+func (p *PlanAnalysis) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*p = PlanAnalysis{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return fmt.Errorf("cannot scan %T into PlanAnalysis", value)
+	}
 }
 
 // Query represents a SQL query to be benchmarked
@@ -45,51 +270,106 @@ type Query struct {
 type QueryExecution struct {
 	ID               uint      `json:"id" gorm:"primaryKey"`
 	QueryID          uint      `json:"query_id" gorm:"not null"`
+	RunID            *uint     `json:"run_id" gorm:"index"`
 	Engine           string    `json:"engine" gorm:"not null"`
-	Status           string    `json:"status" gorm:"default:'pending'"` // "pending", "running", "completed", "failed"
+	Iteration        int       `json:"iteration" gorm:"default:1"` // 1-based measured iteration within the run
+	Concurrency      int       `json:"concurrency" gorm:"default:1"`  // virtual users running when it executed
+	VirtualUser      int       `json:"virtual_user" gorm:"default:1"` // 1-based virtual user that ran it
+	ArrivalRate      float64   `json:"arrival_rate"` // open loop: target queries per second, 0 otherwise
+	// ScheduledTime is when an open-loop query was due to start. Its
+	// ExecutionTimeMs counts from then rather than from StartTime so time
+	// spent waiting behind earlier queries isn't omitted.
+	ScheduledTime    *time.Time `json:"scheduled_time"`
+	ServiceTimeMs    *int64     `json:"service_time_ms"` // as measured by the query-service
+	Status           string    `json:"status" gorm:"default:'pending'"` // "pending", "running", "completed", "failed", "cancelled", "timed_out"
 	StartTime        *time.Time `json:"start_time"`
 	EndTime          *time.Time `json:"end_time"`
 	ExecutionTimeMs  *int64     `json:"execution_time_ms"`
-	RowsProcessed    *int64     `json:"rows_processed"`
-	BytesProcessed   *int64     `json:"bytes_processed"`
-	CPUUsage         *float64   `json:"cpu_usage"`
-	MemoryUsage      *int64     `json:"memory_usage"`
-	IOReadBytes      *int64     `json:"io_read_bytes"`
+	RowsReturned     *int64     `json:"rows_returned"`
+	// The engine's own statistics, where it reports them
+	RowsProcessed    *int64     `json:"rows_processed"`      // input rows processed
+	BytesProcessed   *int64     `json:"bytes_processed"`     // input bytes processed
+	CPUUsage         *float64   `json:"cpu_usage"`           // CPU time per second of execution time, i.e. cores busy
+	CPUTimeMs        *int64     `json:"cpu_time_ms"`
+	MemoryUsage      *int64     `json:"memory_usage"`        // peak user memory in bytes
+	IOReadBytes      *int64     `json:"io_read_bytes"`       // physical input bytes
+	IOReadRows       *int64     `json:"io_read_rows"`        // physical input rows
 	IOWriteBytes     *int64     `json:"io_write_bytes"`
+	QueuedTimeMs     *int64     `json:"queued_time_ms"`
+	PlanningTimeMs   *int64     `json:"planning_time_ms"`
 	ErrorMessage     *string    `json:"error_message"`
 	QueryPlan        *string    `json:"query_plan" gorm:"type:text"`
+	PlanType         *string    `json:"plan_type"` // "explain" or "analyze"
+	PlanAnalysis     *PlanAnalysis `json:"plan_analysis" gorm:"type:jsonb"`
+	ResultChecksum   *string    `json:"result_checksum"` // "<rows>:<hash>", order-insensitive
 	CreatedAt        time.Time  `json:"created_at"`
 	UpdatedAt        time.Time  `json:"updated_at"`
 	
 	// Relationships
 	Query  Query  `json:"query,omitempty" gorm:"foreignKey:QueryID"`
+	Run    *BenchmarkRun `json:"run,omitempty" gorm:"foreignKey:RunID"`
 }
 
 // Result represents aggregated benchmark results
 type Result struct {
 	ID                    uint      `json:"id" gorm:"primaryKey"`
 	BenchmarkID           uint      `json:"benchmark_id" gorm:"not null"`
+	RunID                 *uint     `json:"run_id" gorm:"index"`
 	Engine                string    `json:"engine" gorm:"not null"`
 	TableFormat           string    `json:"table_format" gorm:"not null"`
+	Concurrency           int       `json:"concurrency" gorm:"default:1"` // virtual users the executions ran under
+	ArrivalRate           float64   `json:"arrival_rate"` // open loop: target queries per second, 0 otherwise
 	TotalQueries          int       `json:"total_queries"`
 	SuccessfulQueries     int       `json:"successful_queries"`
 	FailedQueries         int       `json:"failed_queries"`
 	AvgExecutionTimeMs    float64   `json:"avg_execution_time_ms"`
 	MinExecutionTimeMs    float64   `json:"min_execution_time_ms"`
 	MaxExecutionTimeMs    float64   `json:"max_execution_time_ms"`
+	MedianExecutionTimeMs float64   `json:"median_execution_time_ms"`
+	P90ExecutionTimeMs    float64   `json:"p90_execution_time_ms" gorm:"column:p90_execution_time_ms"`
+	P95ExecutionTimeMs    float64   `json:"p95_execution_time_ms" gorm:"column:p95_execution_time_ms"`
+	P99ExecutionTimeMs    float64   `json:"p99_execution_time_ms" gorm:"column:p99_execution_time_ms"`
+	StdDevExecutionTimeMs float64   `json:"stddev_execution_time_ms" gorm:"column:stddev_execution_time_ms"`
+	CoefficientOfVariation float64  `json:"coefficient_of_variation"`
+	CILowerMs             float64   `json:"ci_lower_ms" gorm:"column:ci_lower_ms"` // 95% confidence interval of the mean
+	CIUpperMs             float64   `json:"ci_upper_ms" gorm:"column:ci_upper_ms"`
 	TotalRowsProcessed    int64     `json:"total_rows_processed"`
 	TotalBytesProcessed   int64     `json:"total_bytes_processed"`
 	AvgCPUUsage           float64   `json:"avg_cpu_usage"`
 	AvgMemoryUsage        float64   `json:"avg_memory_usage"`
 	TotalIOReadBytes      int64     `json:"total_io_read_bytes"`
 	TotalIOWriteBytes     int64     `json:"total_io_write_bytes"`
+	ErrorRate             float64   `json:"error_rate"` // failed / total queries
 	Throughput            float64   `json:"throughput"` // queries per second
+	Saturated             bool      `json:"saturated"`  // open loop: throughput fell short of the arrival rate
 	EfficiencyScore       float64   `json:"efficiency_score"` // custom metric
 	CreatedAt             time.Time `json:"created_at"`
 	UpdatedAt             time.Time `json:"updated_at"`
 	
 	// Relationships
-	Benchmark Benchmark `json:"benchmark,omitempty" gorm:"foreignKey:BenchmarkID"`
+	Benchmark Benchmark     `json:"benchmark,omitempty" gorm:"foreignKey:BenchmarkID"`
+	Run       *BenchmarkRun `json:"run,omitempty" gorm:"foreignKey:RunID"`
+}
+
+// Regression records a query that got slower on an engine compared to a baseline run
+type Regression struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	BenchmarkID      uint      `json:"benchmark_id" gorm:"not null"`
+	RunID            uint      `json:"run_id" gorm:"not null"`
+	BaselineRunID    uint      `json:"baseline_run_id" gorm:"not null"`
+	QueryID          uint      `json:"query_id" gorm:"not null"`
+	Engine           string    `json:"engine" gorm:"not null"`
+	Concurrency      int       `json:"concurrency" gorm:"default:1"`
+	ArrivalRate      float64   `json:"arrival_rate"`
+	Test             string    `json:"test"` // "welch" or "mann_whitney"
+	BaselineMedianMs float64   `json:"baseline_median_ms"`
+	MedianMs         float64   `json:"median_ms"`
+	Magnitude        float64   `json:"magnitude"` // relative slowdown of the median, 0.25 = 25% slower
+	PValue           float64   `json:"p_value"`
+	CreatedAt        time.Time `json:"created_at"`
+
+	// Relationships
+	Query Query `json:"query,omitempty" gorm:"foreignKey:QueryID"`
 }
 
 // TableInfo represents metadata about a table
@@ -39,6 +39,10 @@ func (r *BenchmarkRepository) Update(benchmark *models.Benchmark) error {
 	return r.db.Save(benchmark).Error
 }
 
+func (r *BenchmarkRepository) UpdateStatus(id uint, status string) error {
+	return r.db.Model(&models.Benchmark{}).Where("id = ?", id).Update("status", status).Error
+}
+
 func (r *BenchmarkRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Benchmark{}, id).Error
 }
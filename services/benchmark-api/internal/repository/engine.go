@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"benchmark-api/internal/models"
+)
+
+type EngineRepository struct {
+	db *gorm.DB
+}
+
+func NewEngineRepository(db *gorm.DB) *EngineRepository {
+	return &EngineRepository{db: db}
+}
+
+func (r *EngineRepository) List() ([]models.Engine, error) {
+	var engines []models.Engine
+	err := r.db.Order("name").Find(&engines).Error
+	return engines, err
+}
+
+// SaveHealth records the outcome of an engine's health check, adding the
+// engine if it isn't known yet. An empty version keeps the stored one, as
+// unhealthy engines don't report theirs.
+func (r *EngineRepository) SaveHealth(engine *models.Engine) error {
+	return r.db.Exec(`
+		INSERT INTO engines (name, type, version, is_active, last_checked)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET
+			is_active = EXCLUDED.is_active,
+			last_checked = EXCLUDED.last_checked,
+			version = COALESCE(NULLIF(EXCLUDED.version, ''), engines.version)`,
+		engine.Name, engine.Type, engine.Version, engine.IsActive, engine.LastChecked,
+	).Error
+}
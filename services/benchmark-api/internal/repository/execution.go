@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"benchmark-api/internal/models"
+)
+
+type ExecutionRepository struct {
+	db *gorm.DB
+}
+
+func NewExecutionRepository(db *gorm.DB) *ExecutionRepository {
+	return &ExecutionRepository{db: db}
+}
+
+func (r *ExecutionRepository) Create(execution *models.QueryExecution) error {
+	return r.db.Omit(clause.Associations).Create(execution).Error
+}
+
+func (r *ExecutionRepository) GetByID(id uint) (*models.QueryExecution, error) {
+	var execution models.QueryExecution
+	err := r.db.First(&execution, id).Error
+	return &execution, err
+}
+
+func (r *ExecutionRepository) GetByQueryID(queryID uint) ([]models.QueryExecution, error) {
+	var executions []models.QueryExecution
+	err := r.db.Where("query_id = ?", queryID).Order("id").Find(&executions).Error
+	return executions, err
+}
+
+// ListByQueryID returns a page of a query's executions, newest first
+func (r *ExecutionRepository) ListByQueryID(queryID uint, filters map[string]interface{}, limit, offset int) ([]models.QueryExecution, error) {
+	var executions []models.QueryExecution
+	query := r.db.Where("query_id = ?", queryID)
+	for key, value := range filters {
+		query = query.Where(key+" = ?", value)
+	}
+	err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&executions).Error
+	return executions, err
+}
+
+func (r *ExecutionRepository) GetByRunID(runID uint) ([]models.QueryExecution, error) {
+	var executions []models.QueryExecution
+	err := r.db.Where("run_id = ?", runID).Order("id").Find(&executions).Error
+	return executions, err
+}
+
+// GetPlanned returns the executions of a query in a run that captured a
+// plan, optionally on one engine only
+func (r *ExecutionRepository) GetPlanned(runID, queryID uint, engine string) ([]models.QueryExecution, error) {
+	var executions []models.QueryExecution
+	query := r.db.Where("run_id = ? AND query_id = ? AND query_plan IS NOT NULL", runID, queryID)
+	if engine != "" {
+		query = query.Where("engine = ?", engine)
+	}
+	err := query.Order("id").Find(&executions).Error
+	return executions, err
+}
+
+func (r *ExecutionRepository) Update(execution *models.QueryExecution) error {
+	return r.db.Omit(clause.Associations).Save(execution).Error
+}
+
+// SetChecksum stores a result checksum on the completed executions of a
+// query on an engine in a run with the given iteration, or with that
+// iteration and later ones if andLater is set
+func (r *ExecutionRepository) SetChecksum(runID uint, engine string, queryID uint, iteration int, andLater bool, checksum string) error {
+	op := "="
+	if andLater {
+		op = ">="
+	}
+	return r.db.Model(&models.QueryExecution{}).
+		Where("run_id = ? AND engine = ? AND query_id = ? AND status = ? AND iteration "+op+" ?",
+			runID, engine, queryID, models.StatusCompleted, iteration).
+		Update("result_checksum", checksum).Error
+}
+
+// AnalyticsQuery selects the executions that go into an analytics time series
+type AnalyticsQuery struct {
+	Bucket  string   // date_trunc unit: "hour", "day", "week" or "month"
+	GroupBy []string // keys of AnalyticsDimensions
+	From    time.Time
+	To      time.Time
+	Filters map[string]interface{} // keys of AnalyticsDimensions, or "benchmark_id"
+}
+
+// AnalyticsRow aggregates the executions of one group in one time bucket.
+// Only the dimensions in AnalyticsQuery.GroupBy are set.
+type AnalyticsRow struct {
+	Bucket          time.Time
+	Engine          string
+	TableFormat     string
+	DatasetSize     string
+	QueryType       string
+	Complexity      string
+	Concurrency     string
+	ArrivalRate     string
+	Completed       int64
+	Failed          int64
+	AvgLatencyMs    *float64
+	MedianLatencyMs *float64
+	P95LatencyMs    *float64 `gorm:"column:p95_latency_ms"`
+	WindowSeconds   float64
+}
+
+// AnalyticsDimensions maps the dimensions analytics can be grouped and
+// filtered by to their SQL expressions. The table format and dataset size
+// come from the run's config snapshot, as the benchmark may have changed since.
+// Every expression yields text so grouped values scan into strings.
+var AnalyticsDimensions = map[string]string{
+	"engine":       "e.engine",
+	"table_format": "r.config->>'table_format'",
+	"dataset_size": "r.config->>'dataset_size'",
+	"query_type":   "q.query_type",
+	"complexity":   "q.complexity",
+	"concurrency":  "e.concurrency::text",
+	"arrival_rate": "e.arrival_rate::text",
+}
+
+// analyticsBuckets are the date_trunc units analytics can bucket by
+var analyticsBuckets = map[string]bool{"hour": true, "day": true, "week": true, "month": true}
+
+// Analytics aggregates finished executions per time bucket and group. The
+// window throughput is measured over is the sum of the time each run spent
+// executing in the bucket and group, so idle time between runs isn't counted.
+func (r *ExecutionRepository) Analytics(q AnalyticsQuery) ([]AnalyticsRow, error) {
+	if !analyticsBuckets[q.Bucket] {
+		return nil, fmt.Errorf("unknown analytics bucket %q", q.Bucket)
+	}
+	bucket := fmt.Sprintf("date_trunc('%s', e.start_time)", q.Bucket)
+
+	// Executions with their bucket and dimensions, the first of each run in
+	// its bucket and group carrying the run's window
+	partition := []string{bucket}
+	columns := []string{"e.status", "e.execution_time_ms", bucket + " AS bucket"}
+	selects := []string{
+		"bucket",
+		"COUNT(*) FILTER (WHERE status = 'completed') AS completed",
+		"COUNT(*) FILTER (WHERE status IN ('failed', 'cancelled', 'timed_out')) AS failed",
+		"AVG(execution_time_ms) FILTER (WHERE status = 'completed') AS avg_latency_ms",
+		"percentile_cont(0.5) WITHIN GROUP (ORDER BY execution_time_ms) FILTER (WHERE status = 'completed') AS median_latency_ms",
+		"percentile_cont(0.95) WITHIN GROUP (ORDER BY execution_time_ms) FILTER (WHERE status = 'completed') AS p95_latency_ms",
+		"COALESCE(SUM(run_window_seconds), 0) AS window_seconds",
+	}
+	groups := []string{"bucket"}
+	for _, dimension := range q.GroupBy {
+		expr, ok := AnalyticsDimensions[dimension]
+		if !ok {
+			return nil, fmt.Errorf("unknown analytics dimension %q", dimension)
+		}
+		partition = append(partition, expr)
+		columns = append(columns, "COALESCE("+expr+", '') AS "+dimension)
+		selects = append(selects, dimension)
+		groups = append(groups, dimension)
+	}
+	window := "OVER (PARTITION BY " + strings.Join(append(partition, "e.run_id"), ", ") + ")"
+	columns = append(columns, "CASE WHEN ROW_NUMBER() "+window+" = 1 THEN EXTRACT(EPOCH FROM MAX(e.end_time) "+window+" - MIN(e.start_time) "+window+") END AS run_window_seconds")
+
+	executions := r.db.Table("query_executions e").
+		Select(strings.Join(columns, ", ")).
+		Joins("JOIN benchmark_runs r ON r.id = e.run_id").
+		Joins("JOIN queries q ON q.id = e.query_id").
+		Where("e.status IN ?", []string{models.StatusCompleted, models.StatusFailed, models.StatusCancelled, models.StatusTimedOut}).
+		Where("e.start_time >= ? AND e.start_time < ?", q.From, q.To)
+
+	for key, value := range q.Filters {
+		if key == "benchmark_id" {
+			executions = executions.Where("r.benchmark_id = ?", value)
+			continue
+		}
+		expr, ok := AnalyticsDimensions[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown analytics dimension %q", key)
+		}
+		executions = executions.Where(expr+" = ?", value)
+	}
+
+	var rows []AnalyticsRow
+	err := r.db.Table("(?) AS executions", executions).
+		Select(strings.Join(selects, ", ")).
+		Group(strings.Join(groups, ", ")).
+		Order("bucket").
+		Scan(&rows).Error
+	return rows, err
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"benchmark-api/internal/models"
 )
 
@@ -14,7 +15,7 @@ func NewQueryRepository(db *gorm.DB) *QueryRepository {
 }
 
 func (r *QueryRepository) Create(query *models.Query) error {
-	return r.db.Create(query).Error
+	return r.db.Omit(clause.Associations).Create(query).Error
 }
 
 func (r *QueryRepository) GetByID(id uint) (*models.Query, error) {
@@ -37,12 +38,12 @@ func (r *QueryRepository) List(filters map[string]interface{}, limit, offset int
 		query = query.Where(key+" = ?", value)
 	}
 	
-	err := query.Limit(limit).Offset(offset).Find(&queries).Error
+	err := query.Order("id").Limit(limit).Offset(offset).Find(&queries).Error
 	return queries, err
 }
 
 func (r *QueryRepository) Update(query *models.Query) error {
-	return r.db.Save(query).Error
+	return r.db.Omit(clause.Associations).Save(query).Error
 }
 
 func (r *QueryRepository) Delete(id uint) error {
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"benchmark-api/internal/models"
+)
+
+type RegressionRepository struct {
+	db *gorm.DB
+}
+
+func NewRegressionRepository(db *gorm.DB) *RegressionRepository {
+	return &RegressionRepository{db: db}
+}
+
+func (r *RegressionRepository) GetByBenchmarkID(benchmarkID uint, filters map[string]interface{}, limit, offset int) ([]models.Regression, error) {
+	var regressions []models.Regression
+	query := r.db.Preload("Query").Where("benchmark_id = ?", benchmarkID)
+
+	for key, value := range filters {
+		query = query.Where(key+" = ?", value)
+	}
+
+	err := query.Order("run_id DESC, magnitude DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&regressions).Error
+	return regressions, err
+}
+
+// ReplaceForRun swaps the regressions detected in a run for a fresh set
+func (r *RegressionRepository) ReplaceForRun(runID uint, regressions []models.Regression) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("run_id = ?", runID).Delete(&models.Regression{}).Error; err != nil {
+			return err
+		}
+		if len(regressions) == 0 {
+			return nil
+		}
+		return tx.Omit(clause.Associations).Create(&regressions).Error
+	})
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"benchmark-api/internal/models"
 )
 
@@ -25,10 +26,29 @@ func (r *ResultRepository) GetByID(id uint) (*models.Result, error) {
 
 func (r *ResultRepository) GetByBenchmarkID(benchmarkID uint) ([]models.Result, error) {
 	var results []models.Result
-	err := r.db.Where("benchmark_id = ?", benchmarkID).Find(&results).Error
+	err := r.db.Where("benchmark_id = ?", benchmarkID).Order("run_id DESC, engine, table_format").Find(&results).Error
 	return results, err
 }
 
+func (r *ResultRepository) GetByRunID(runID uint) ([]models.Result, error) {
+	var results []models.Result
+	err := r.db.Where("run_id = ?", runID).Order("engine, table_format").Find(&results).Error
+	return results, err
+}
+
+// ReplaceForRun swaps the results of a run for a freshly computed set
+func (r *ResultRepository) ReplaceForRun(runID uint, results []models.Result) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("run_id = ?", runID).Delete(&models.Result{}).Error; err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			return nil
+		}
+		return tx.Omit(clause.Associations).Create(&results).Error
+	})
+}
+
 func (r *ResultRepository) List(filters map[string]interface{}, limit, offset int) ([]models.Result, error) {
 	var results []models.Result
 	query := r.db
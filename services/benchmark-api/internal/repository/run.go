@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"benchmark-api/internal/models"
+)
+
+type RunRepository struct {
+	db *gorm.DB
+}
+
+func NewRunRepository(db *gorm.DB) *RunRepository {
+	return &RunRepository{db: db}
+}
+
+func (r *RunRepository) Create(run *models.BenchmarkRun) error {
+	return r.db.Omit(clause.Associations).Create(run).Error
+}
+
+func (r *RunRepository) GetByID(id uint) (*models.BenchmarkRun, error) {
+	var run models.BenchmarkRun
+	err := r.db.Preload("Executions").Preload("Results").First(&run, id).Error
+	return &run, err
+}
+
+// GetByIDWithoutAssociations returns the run alone, for callers that only
+// read its own fields, without loading its executions and results
+func (r *RunRepository) GetByIDWithoutAssociations(id uint) (*models.BenchmarkRun, error) {
+	var run models.BenchmarkRun
+	err := r.db.First(&run, id).Error
+	return &run, err
+}
+
+func (r *RunRepository) GetByBenchmarkID(benchmarkID uint, limit, offset int) ([]models.BenchmarkRun, error) {
+	var runs []models.BenchmarkRun
+	err := r.db.Where("benchmark_id = ?", benchmarkID).
+		Order("id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&runs).Error
+	return runs, err
+}
+
+func (r *RunRepository) GetLatest(benchmarkID uint) (*models.BenchmarkRun, error) {
+	var run models.BenchmarkRun
+	err := r.db.Where("benchmark_id = ?", benchmarkID).Order("id DESC").First(&run).Error
+	return &run, err
+}
+
+// GetCompletedBefore returns the benchmark's last completed runs older than
+// runID with their executions, newest first
+func (r *RunRepository) GetCompletedBefore(benchmarkID, runID uint, limit int) ([]models.BenchmarkRun, error) {
+	var runs []models.BenchmarkRun
+	err := r.db.Preload("Executions").
+		Where("benchmark_id = ? AND id < ? AND status = ?", benchmarkID, runID, models.StatusCompleted).
+		Order("id DESC").
+		Limit(limit).
+		Find(&runs).Error
+	return runs, err
+}
+
+// GetCompletedOnDataset returns the completed runs of every benchmark on the
+// same dataset as the given one, whatever their table format, newest first
+func (r *RunRepository) GetCompletedOnDataset(benchmarkID uint) ([]models.BenchmarkRun, error) {
+	var runs []models.BenchmarkRun
+	err := r.db.Joins("JOIN benchmarks b ON b.id = benchmark_runs.benchmark_id AND b.deleted_at IS NULL").
+		Joins("JOIN benchmarks own ON own.id = ? AND own.dataset_name = b.dataset_name AND own.dataset_size IS NOT DISTINCT FROM b.dataset_size", benchmarkID).
+		Where("benchmark_runs.status = ?", models.StatusCompleted).
+		Order("benchmark_runs.id DESC").
+		Find(&runs).Error
+	return runs, err
+}
+
+func (r *RunRepository) Update(run *models.BenchmarkRun) error {
+	return r.db.Omit(clause.Associations).Save(run).Error
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"benchmark-api/internal/repository"
+)
+
+// ErrInvalidAnalytics is returned for an analytics request with an unknown
+// bucket, dimension or an empty date range
+var ErrInvalidAnalytics = errors.New("invalid analytics request")
+
+// Analytics bucket sizes
+const (
+	BucketHour  = "hour"
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// defaultAnalyticsRange is how far back analytics look without a start date
+const defaultAnalyticsRange = 30 * 24 * time.Hour
+
+// AnalyticsRequest selects the time series to compute. GroupBy and the keys
+// of Filters are dimensions: engine, table_format, dataset_size, query_type,
+// complexity, concurrency or arrival_rate. Filters may also hold benchmark_id.
+type AnalyticsRequest struct {
+	Bucket  string
+	GroupBy []string
+	From    *time.Time
+	To      *time.Time
+	Filters map[string]interface{}
+}
+
+// Analytics holds one time series per group
+type Analytics struct {
+	Bucket  string            `json:"bucket"`
+	From    time.Time         `json:"from"`
+	To      time.Time         `json:"to"`
+	GroupBy []string          `json:"group_by"`
+	Series  []AnalyticsSeries `json:"series"`
+}
+
+// AnalyticsSeries is the time series of one combination of the grouped
+// dimensions, e.g. {"engine": "trino", "table_format": "iceberg"}
+type AnalyticsSeries struct {
+	Group  map[string]string `json:"group"`
+	Points []AnalyticsPoint  `json:"points"`
+}
+
+// AnalyticsPoint aggregates the executions that started within a bucket
+type AnalyticsPoint struct {
+	Time            time.Time `json:"time"`
+	Executions      int64     `json:"executions"`
+	Completed       int64     `json:"completed"`
+	Failed          int64     `json:"failed"`
+	FailureRate     float64   `json:"failure_rate"` // failed / executions
+	AvgLatencyMs    *float64  `json:"avg_latency_ms"`
+	MedianLatencyMs *float64  `json:"median_latency_ms"`
+	P95LatencyMs    *float64  `json:"p95_latency_ms"`
+	// Throughput is completed executions per second of the time runs spent
+	// executing in the bucket, each run's window being the wall-clock time
+	// between its first start and last end as in Result
+	Throughput float64 `json:"throughput"`
+}
+
+// GetAnalytics computes time series of latency, throughput and failure rate
+// from the executions of all runs
+func (s *ResultService) GetAnalytics(req AnalyticsRequest) (*Analytics, error) {
+	bucket := req.Bucket
+	if bucket == "" {
+		bucket = BucketDay
+	}
+	switch bucket {
+	case BucketHour, BucketDay, BucketWeek, BucketMonth:
+	default:
+		return nil, fmt.Errorf("%w: bucket must be one of hour, day, week or month", ErrInvalidAnalytics)
+	}
+
+	for _, dimension := range req.GroupBy {
+		if _, ok := repository.AnalyticsDimensions[dimension]; !ok {
+			return nil, fmt.Errorf("%w: unknown dimension %q", ErrInvalidAnalytics, dimension)
+		}
+	}
+	for key := range req.Filters {
+		if _, ok := repository.AnalyticsDimensions[key]; !ok && key != "benchmark_id" {
+			return nil, fmt.Errorf("%w: unknown filter %q", ErrInvalidAnalytics, key)
+		}
+	}
+
+	to := time.Now().UTC()
+	if req.To != nil {
+		to = *req.To
+	}
+	from := to.Add(-defaultAnalyticsRange)
+	if req.From != nil {
+		from = *req.From
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidAnalytics)
+	}
+
+	groupBy := req.GroupBy
+	if groupBy == nil {
+		groupBy = []string{}
+	}
+
+	rows, err := s.executionRepo.Analytics(repository.AnalyticsQuery{
+		Bucket:  bucket,
+		GroupBy: groupBy,
+		From:    from,
+		To:      to,
+		Filters: req.Filters,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Analytics{
+		Bucket:  bucket,
+		From:    from,
+		To:      to,
+		GroupBy: groupBy,
+		Series:  buildSeries(rows, groupBy),
+	}, nil
+}
+
+// buildSeries splits the rows, ordered by bucket, into one series per group
+func buildSeries(rows []repository.AnalyticsRow, groupBy []string) []AnalyticsSeries {
+	index := make(map[string]int)
+	series := []AnalyticsSeries{}
+	for _, row := range rows {
+		group := make(map[string]string, len(groupBy))
+		keyParts := make([]string, 0, len(groupBy))
+		for _, dimension := range groupBy {
+			value := dimensionValue(row, dimension)
+			group[dimension] = value
+			keyParts = append(keyParts, value)
+		}
+		key := strings.Join(keyParts, "\x00")
+
+		i, ok := index[key]
+		if !ok {
+			i = len(series)
+			index[key] = i
+			series = append(series, AnalyticsSeries{Group: group})
+		}
+
+		point := AnalyticsPoint{
+			Time:            row.Bucket,
+			Executions:      row.Completed + row.Failed,
+			Completed:       row.Completed,
+			Failed:          row.Failed,
+			AvgLatencyMs:    row.AvgLatencyMs,
+			MedianLatencyMs: row.MedianLatencyMs,
+			P95LatencyMs:    row.P95LatencyMs,
+		}
+		if point.Executions > 0 {
+			point.FailureRate = float64(point.Failed) / float64(point.Executions)
+		}
+		if row.WindowSeconds > 0 {
+			point.Throughput = float64(row.Completed) / row.WindowSeconds
+		}
+		series[i].Points = append(series[i].Points, point)
+	}
+
+	sort.SliceStable(series, func(i, j int) bool {
+		for _, dimension := range groupBy {
+			if a, b := series[i].Group[dimension], series[j].Group[dimension]; a != b {
+				return a < b
+			}
+		}
+		return false
+	})
+	return series
+}
+
+func dimensionValue(row repository.AnalyticsRow, dimension string) string {
+	switch dimension {
+	case "engine":
+		return row.Engine
+	case "table_format":
+		return row.TableFormat
+	case "dataset_size":
+		return row.DatasetSize
+	case "query_type":
+		return row.QueryType
+	case "complexity":
+		return row.Complexity
+	case "concurrency":
+		return row.Concurrency
+	case "arrival_rate":
+		return row.ArrivalRate
+	default:
+		return ""
+	}
+}
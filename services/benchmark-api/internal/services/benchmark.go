@@ -1,25 +1,45 @@
 package services
 
 import (
+	"errors"
+	"time"
+
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 	"benchmark-api/internal/models"
 	"benchmark-api/internal/repository"
 )
 
 type BenchmarkService struct {
-	repo   *repository.BenchmarkRepository
-	logger *logrus.Logger
+	repo          *repository.BenchmarkRepository
+	runRepo       *repository.RunRepository
+	executionRepo *repository.ExecutionRepository
+	runner        *BenchmarkRunner
+	results       *ResultService
+	regressions   *RegressionService
+	events        *EventBroker
+	logger        *logrus.Logger
 }
 
-func NewBenchmarkService(repo *repository.BenchmarkRepository, logger *logrus.Logger) *BenchmarkService {
+func NewBenchmarkService(repo *repository.BenchmarkRepository, runRepo *repository.RunRepository, executionRepo *repository.ExecutionRepository, runner *BenchmarkRunner, results *ResultService, regressions *RegressionService, events *EventBroker, logger *logrus.Logger) *BenchmarkService {
 	return &BenchmarkService{
-		repo:   repo,
-		logger: logger,
+		repo:          repo,
+		runRepo:       runRepo,
+		executionRepo: executionRepo,
+		runner:        runner,
+		results:       results,
+		regressions:   regressions,
+		events:        events,
+		logger:        logger,
 	}
 }
 
 func (s *BenchmarkService) CreateBenchmark(benchmark *models.Benchmark) error {
 	s.logger.WithField("benchmark_name", benchmark.Name).Info("Creating benchmark")
+	benchmark.MeasuredIterations = measuredIterations(benchmark)
+	if err := normalizeWorkload(&benchmark.Workload); err != nil {
+		return err
+	}
 	return s.repo.Create(benchmark)
 }
 
@@ -32,6 +52,10 @@ func (s *BenchmarkService) ListBenchmarks(filters map[string]interface{}, limit,
 }
 
 func (s *BenchmarkService) UpdateBenchmark(benchmark *models.Benchmark) error {
+	benchmark.MeasuredIterations = measuredIterations(benchmark)
+	if err := normalizeWorkload(&benchmark.Workload); err != nil {
+		return err
+	}
 	return s.repo.Update(benchmark)
 }
 
@@ -39,23 +63,153 @@ func (s *BenchmarkService) DeleteBenchmark(id uint) error {
 	return s.repo.Delete(id)
 }
 
-func (s *BenchmarkService) RunBenchmark(id uint) error {
-	// TODO: Implement benchmark execution logic
-	s.logger.WithField("benchmark_id", id).Info("Starting benchmark execution")
-	return nil
+func (s *BenchmarkService) RunBenchmark(id uint, triggeredBy string) (*models.BenchmarkRun, error) {
+	benchmark, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.runner.Start(benchmark, triggeredBy)
+}
+
+func (s *BenchmarkService) ListBenchmarkRuns(id uint, limit, offset int) ([]models.BenchmarkRun, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	return s.runRepo.GetByBenchmarkID(id, limit, offset)
+}
+
+func (s *BenchmarkService) GetBenchmarkRun(id, runID uint) (*models.BenchmarkRun, error) {
+	run, err := s.runRepo.GetByID(runID)
+	if err != nil {
+		return nil, err
+	}
+	if run.BenchmarkID != id {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return run, nil
+}
+
+// GetRunStatistics summarizes the measured iterations of every query of a
+// run, per engine
+func (s *BenchmarkService) GetRunStatistics(id, runID uint) ([]QueryStatistics, error) {
+	benchmark, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	run, err := s.GetBenchmarkRun(id, runID)
+	if err != nil {
+		return nil, err
+	}
+	return computeStatistics(benchmark, run.Executions), nil
 }
 
-func (s *BenchmarkService) GetBenchmarkStatus(id uint) (map[string]interface{}, error) {
-	// TODO: Implement status retrieval logic
-	status := map[string]interface{}{
-		"benchmark_id": id,
-		"status":       "running",
-		"progress":     50,
+// GetRunCorrectness compares the result checksums of a run across its
+// engines and against another run, which may belong to another benchmark.
+// Without againstRunID that's the latest completed run on a different table
+// format of the run's dataset, if any, see latestFormatRuns. Queries of
+// different benchmarks are matched by name.
+func (s *BenchmarkService) GetRunCorrectness(id, runID uint, againstRunID *uint) (*CorrectnessReport, error) {
+	benchmark, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	run, err := s.GetBenchmarkRun(id, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	if againstRunID == nil {
+		latest, formats, err := latestFormatRuns(s.runRepo, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, format := range formats {
+			if format != run.Config.TableFormat {
+				other := latest[format].ID
+				againstRunID = &other
+				break
+			}
+		}
+	}
+	if againstRunID == nil || *againstRunID == run.ID {
+		return checkCorrectness(matchQueries(benchmark.Queries), run), nil
+	}
+
+	against, err := s.runRepo.GetByID(*againstRunID)
+	if err != nil {
+		return nil, err
+	}
+	queries := benchmark.Queries
+	if against.BenchmarkID != benchmark.ID {
+		againstBenchmark, err := s.repo.GetByID(against.BenchmarkID)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(append([]models.Query(nil), queries...), againstBenchmark.Queries...)
 	}
-	return status, nil
+	return checkCorrectness(matchQueries(queries), run, against), nil
+}
+
+func (s *BenchmarkService) GetBenchmarkStatus(id uint) (*BenchmarkStatus, error) {
+	benchmark, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	run, err := s.runRepo.GetLatest(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &BenchmarkStatus{
+			BenchmarkID: id,
+			Status:      benchmark.Status,
+			Engines:     []EngineProgress{},
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	executions, err := s.executionRepo.GetByRunID(run.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return computeProgress(benchmark, run, executions, time.Now()), nil
 }
 
 func (s *BenchmarkService) GetBenchmarkResults(id uint) ([]models.Result, error) {
-	// TODO: Implement results retrieval logic
-	return []models.Result{}, nil
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	return s.results.GetBenchmarkResults(id)
+}
+
+// AggregateBenchmarkRun recomputes the results and regressions of a run from
+// its executions, e.g. after executions were re-ingested
+func (s *BenchmarkService) AggregateBenchmarkRun(id, runID uint) ([]models.Result, error) {
+	if _, err := s.GetBenchmarkRun(id, runID); err != nil {
+		return nil, err
+	}
+	results, err := s.results.AggregateRun(runID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.regressions.DetectRun(runID); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *BenchmarkService) ListBenchmarkRegressions(id uint, filters map[string]interface{}, limit, offset int) ([]models.Regression, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	return s.regressions.ListRegressions(id, filters, limit, offset)
+}
+
+func (s *BenchmarkService) SubscribeEvents(id uint, lastEventID uint64) (<-chan RunEvent, func(), error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, nil, err
+	}
+	events, unsubscribe := s.events.Subscribe(id, lastEventID)
+	return events, unsubscribe, nil
 }
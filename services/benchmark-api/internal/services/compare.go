@@ -0,0 +1,413 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"benchmark-api/internal/models"
+	"benchmark-api/pkg/stats"
+)
+
+// ErrInvalidComparison is returned when a comparison request doesn't select
+// at least two comparable sides
+var ErrInvalidComparison = errors.New("invalid comparison")
+
+// Dimensions a benchmark's results can be pivoted on
+const (
+	PivotEngine      = "engine"
+	PivotTableFormat = "table_format"
+)
+
+// Outcomes of a query in a comparison
+const (
+	OutcomeBothSucceeded   = "both_succeeded"
+	OutcomeBaselineFailed  = "baseline_failed"
+	OutcomeCandidateFailed = "candidate_failed"
+	OutcomeBothFailed      = "both_failed"
+)
+
+// CompareRequest selects what to compare: two or more results, two or more
+// runs, or a benchmark pivoted on an engine or table format
+type CompareRequest struct {
+	ResultIDs   []uint
+	RunIDs      []uint
+	BenchmarkID uint
+	Pivot       string
+}
+
+// ComparisonSide is one of the things being compared
+type ComparisonSide struct {
+	Label       string `json:"label"`
+	RunID       uint   `json:"run_id"`
+	ResultID    *uint  `json:"result_id,omitempty"`
+	Engine      string `json:"engine,omitempty"` // empty if the side spans several engines
+	TableFormat string `json:"table_format"`
+
+	benchmarkID uint
+	executions  []models.QueryExecution
+}
+
+// Comparison compares every side against the first one, the baseline
+type Comparison struct {
+	Baseline    ComparisonSide   `json:"baseline"`
+	Comparisons []SideComparison `json:"comparisons"`
+}
+
+// SideComparison compares one side against the baseline, query by query
+type SideComparison struct {
+	Candidate ComparisonSide `json:"candidate"`
+	// GeometricMeanSpeedup is the geometric mean of baseline / candidate
+	// median latency over the queries that succeeded on both sides. Above 1
+	// the candidate is faster.
+	GeometricMeanSpeedup *float64          `json:"geometric_mean_speedup"`
+	Wins                 int               `json:"wins"`   // queries the candidate ran faster
+	Losses               int               `json:"losses"` // queries the candidate ran slower
+	Ties                 int               `json:"ties"`
+	SignificantWins      int               `json:"significant_wins"`
+	SignificantLosses    int               `json:"significant_losses"`
+	Mismatches           int               `json:"mismatches"` // queries that succeeded on one side only
+	Queries              []QueryComparison `json:"queries"`
+}
+
+// QueryComparison compares the measured iterations of one query
+type QueryComparison struct {
+	QueryID           uint     `json:"query_id"`
+	QueryName         string   `json:"query_name"`
+	Engine            string   `json:"engine,omitempty"` // set when the sides span several engines
+	Concurrency       int      `json:"concurrency"`
+	ArrivalRate       float64  `json:"arrival_rate"`
+	Outcome           string   `json:"outcome"`
+	BaselineMedianMs  *float64 `json:"baseline_median_ms"`
+	CandidateMedianMs *float64 `json:"candidate_median_ms"`
+	Ratio             *float64 `json:"ratio"` // candidate / baseline median latency
+	// Significant is set if the mean latencies differ at
+	// stats.ConfidenceLevel by Welch's t-test
+	Significant    bool    `json:"significant"`
+	BaselineError  *string `json:"baseline_error,omitempty"`
+	CandidateError *string `json:"candidate_error,omitempty"`
+}
+
+// comparisonKey identifies a query across sides by its logical key, see
+// queryMatcher. The engine is only part of the key when the sides span
+// several engines; executions under different load levels are never
+// compared with each other.
+type comparisonKey struct {
+	query       string
+	engine      string
+	concurrency int
+	arrivalRate float64
+}
+
+// queryOutcome is what a side recorded for one query
+type queryOutcome struct {
+	queryID   uint
+	latencies []float64
+	failed    bool
+	err       *string
+}
+
+// Compare resolves the sides of a request and compares each against the first
+func (s *ResultService) Compare(req CompareRequest) (*Comparison, error) {
+	var (
+		sides       []ComparisonSide
+		keyByEngine bool
+		err         error
+	)
+	switch {
+	case len(req.ResultIDs) > 0:
+		sides, err = s.resultSides(req.ResultIDs)
+	case len(req.RunIDs) > 0:
+		sides, err = s.runSides(req.RunIDs)
+		keyByEngine = true
+	case req.BenchmarkID != 0 && req.Pivot == PivotEngine:
+		sides, err = s.engineSides(req.BenchmarkID)
+	case req.BenchmarkID != 0 && req.Pivot == PivotTableFormat:
+		sides, err = s.tableFormatSides(req.BenchmarkID)
+		keyByEngine = true
+	case req.BenchmarkID != 0:
+		return nil, fmt.Errorf("%w: pivot must be %q or %q", ErrInvalidComparison, PivotEngine, PivotTableFormat)
+	default:
+		return nil, fmt.Errorf("%w: result_ids, run_ids or benchmark_id is required", ErrInvalidComparison)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(sides) < 2 {
+		return nil, fmt.Errorf("%w: need at least two sides to compare, found %d", ErrInvalidComparison, len(sides))
+	}
+
+	benchmarkIDs := make([]uint, 0, len(sides))
+	for _, side := range sides {
+		benchmarkIDs = append(benchmarkIDs, side.benchmarkID)
+	}
+	queries, err := loadQueryMatcher(s.queryRepo, benchmarkIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := &Comparison{Baseline: sides[0]}
+	baseline := groupOutcomes(sides[0].executions, keyByEngine, queries)
+	for _, side := range sides[1:] {
+		candidate := groupOutcomes(side.executions, keyByEngine, queries)
+		sideComparison := compareSides(side, baseline, candidate, queries)
+		if len(sideComparison.Queries) == 0 {
+			return nil, fmt.Errorf("%w: %s and %s share no queries", ErrInvalidComparison, sides[0].Label, side.Label)
+		}
+		comparison.Comparisons = append(comparison.Comparisons, sideComparison)
+	}
+	return comparison, nil
+}
+
+func (s *ResultService) resultSides(ids []uint) ([]ComparisonSide, error) {
+	sides := make([]ComparisonSide, 0, len(ids))
+	for _, id := range ids {
+		result, err := s.repo.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if result.RunID == nil {
+			return nil, fmt.Errorf("%w: result %d doesn't belong to a run", ErrInvalidComparison, id)
+		}
+		run, err := s.runRepo.GetByID(*result.RunID)
+		if err != nil {
+			return nil, err
+		}
+
+		resultID := result.ID
+		side := ComparisonSide{
+			Label:       fmt.Sprintf("run %d %s/%s", run.ID, result.Engine, result.TableFormat),
+			RunID:       run.ID,
+			benchmarkID: run.BenchmarkID,
+			ResultID:    &resultID,
+			Engine:      result.Engine,
+			TableFormat: result.TableFormat,
+		}
+		for _, execution := range run.Executions {
+			if execution.Engine == result.Engine {
+				side.executions = append(side.executions, execution)
+			}
+		}
+		sides = append(sides, side)
+	}
+	return sides, nil
+}
+
+func (s *ResultService) runSides(ids []uint) ([]ComparisonSide, error) {
+	sides := make([]ComparisonSide, 0, len(ids))
+	for _, id := range ids {
+		run, err := s.runRepo.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		sides = append(sides, ComparisonSide{
+			Label:       fmt.Sprintf("run %d", run.ID),
+			RunID:       run.ID,
+			benchmarkID: run.BenchmarkID,
+			TableFormat: run.Config.TableFormat,
+			executions:  run.Executions,
+		})
+	}
+	return sides, nil
+}
+
+// engineSides splits the benchmark's latest completed run by engine
+func (s *ResultService) engineSides(benchmarkID uint) ([]ComparisonSide, error) {
+	runs, err := s.completedRuns(benchmarkID)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("%w: benchmark %d has no completed run", ErrInvalidComparison, benchmarkID)
+	}
+	run, err := s.runRepo.GetByID(runs[0].ID)
+	if err != nil {
+		return nil, err
+	}
+
+	byEngine := make(map[string][]models.QueryExecution)
+	var engines []string
+	for _, execution := range run.Executions {
+		if _, ok := byEngine[execution.Engine]; !ok {
+			engines = append(engines, execution.Engine)
+		}
+		byEngine[execution.Engine] = append(byEngine[execution.Engine], execution)
+	}
+	sort.Strings(engines)
+
+	sides := make([]ComparisonSide, 0, len(engines))
+	for _, engine := range engines {
+		sides = append(sides, ComparisonSide{
+			Label:       engine,
+			RunID:       run.ID,
+			benchmarkID: run.BenchmarkID,
+			Engine:      engine,
+			TableFormat: run.Config.TableFormat,
+			executions:  byEngine[engine],
+		})
+	}
+	return sides, nil
+}
+
+// tableFormatSides takes the latest completed run of each table format the
+// benchmark's dataset was run with, see latestFormatRuns
+func (s *ResultService) tableFormatSides(benchmarkID uint) ([]ComparisonSide, error) {
+	latest, formats, err := latestFormatRuns(s.runRepo, benchmarkID)
+	if err != nil {
+		return nil, err
+	}
+
+	sides := make([]ComparisonSide, 0, len(formats))
+	for _, format := range formats {
+		run, err := s.runRepo.GetByID(latest[format].ID)
+		if err != nil {
+			return nil, err
+		}
+		sides = append(sides, ComparisonSide{
+			Label:       format,
+			RunID:       run.ID,
+			benchmarkID: run.BenchmarkID,
+			TableFormat: format,
+			executions:  run.Executions,
+		})
+	}
+	return sides, nil
+}
+
+// completedRuns returns the completed runs of a benchmark, newest first
+func (s *ResultService) completedRuns(benchmarkID uint) ([]models.BenchmarkRun, error) {
+	runs, err := s.runRepo.GetByBenchmarkID(benchmarkID, -1, -1)
+	if err != nil {
+		return nil, err
+	}
+	completed := runs[:0]
+	for _, run := range runs {
+		if run.Status == models.StatusCompleted {
+			completed = append(completed, run)
+		}
+	}
+	return completed, nil
+}
+
+// groupOutcomes collects the measured latencies and failures of each query
+func groupOutcomes(executions []models.QueryExecution, keyByEngine bool, queries *queryMatcher) map[comparisonKey]*queryOutcome {
+	outcomes := make(map[comparisonKey]*queryOutcome)
+	for _, execution := range executions {
+		k := comparisonKey{query: queries.key(execution.QueryID), concurrency: execution.Concurrency, arrivalRate: execution.ArrivalRate}
+		if keyByEngine {
+			k.engine = execution.Engine
+		}
+		outcome, ok := outcomes[k]
+		if !ok {
+			outcome = &queryOutcome{queryID: execution.QueryID}
+			outcomes[k] = outcome
+		}
+
+		switch execution.Status {
+		case models.StatusCompleted:
+			if execution.ExecutionTimeMs != nil {
+				outcome.latencies = append(outcome.latencies, float64(*execution.ExecutionTimeMs))
+			}
+		case models.StatusFailed, models.StatusCancelled, models.StatusTimedOut:
+			outcome.failed = true
+			if execution.ErrorMessage != nil {
+				outcome.err = execution.ErrorMessage
+			}
+		}
+	}
+	return outcomes
+}
+
+// compareSides compares the queries both sides ran. A query succeeded on a
+// side if any of its measured iterations completed. Queries are reported
+// with the baseline's query ID.
+func compareSides(side ComparisonSide, baseline, candidate map[comparisonKey]*queryOutcome, queries *queryMatcher) SideComparison {
+	comparison := SideComparison{Candidate: side, Queries: []QueryComparison{}}
+
+	var speedups []float64
+	for k, b := range baseline {
+		c, ok := candidate[k]
+		if !ok {
+			continue
+		}
+		bOK, cOK := len(b.latencies) > 0, len(c.latencies) > 0
+		if !bOK && !cOK && !b.failed && !c.failed {
+			continue
+		}
+
+		query := QueryComparison{
+			QueryID:     b.queryID,
+			QueryName:   queries.name(b.queryID),
+			Engine:      k.engine,
+			Concurrency: k.concurrency,
+			ArrivalRate: k.arrivalRate,
+		}
+		if bOK {
+			median := stats.Percentile(b.latencies, 50)
+			query.BaselineMedianMs = &median
+		} else {
+			query.BaselineError = b.err
+		}
+		if cOK {
+			median := stats.Percentile(c.latencies, 50)
+			query.CandidateMedianMs = &median
+		} else {
+			query.CandidateError = c.err
+		}
+
+		switch {
+		case bOK && cOK:
+			query.Outcome = OutcomeBothSucceeded
+			query.Significant = stats.SignificantlyDifferent(b.latencies, c.latencies)
+			baselineMs, candidateMs := *query.BaselineMedianMs, *query.CandidateMedianMs
+			if baselineMs > 0 {
+				ratio := candidateMs / baselineMs
+				query.Ratio = &ratio
+			}
+			if baselineMs > 0 && candidateMs > 0 {
+				speedups = append(speedups, baselineMs/candidateMs)
+			}
+			switch {
+			case candidateMs < baselineMs:
+				comparison.Wins++
+				if query.Significant {
+					comparison.SignificantWins++
+				}
+			case candidateMs > baselineMs:
+				comparison.Losses++
+				if query.Significant {
+					comparison.SignificantLosses++
+				}
+			default:
+				comparison.Ties++
+			}
+		case bOK:
+			query.Outcome = OutcomeCandidateFailed
+			comparison.Mismatches++
+		case cOK:
+			query.Outcome = OutcomeBaselineFailed
+			comparison.Mismatches++
+		default:
+			query.Outcome = OutcomeBothFailed
+		}
+		comparison.Queries = append(comparison.Queries, query)
+	}
+
+	if len(speedups) > 0 {
+		speedup := stats.GeometricMean(speedups)
+		comparison.GeometricMeanSpeedup = &speedup
+	}
+	sort.Slice(comparison.Queries, func(i, j int) bool {
+		if comparison.Queries[i].QueryID != comparison.Queries[j].QueryID {
+			return comparison.Queries[i].QueryID < comparison.Queries[j].QueryID
+		}
+		if comparison.Queries[i].Engine != comparison.Queries[j].Engine {
+			return comparison.Queries[i].Engine < comparison.Queries[j].Engine
+		}
+		if comparison.Queries[i].Concurrency != comparison.Queries[j].Concurrency {
+			return comparison.Queries[i].Concurrency < comparison.Queries[j].Concurrency
+		}
+		return comparison.Queries[i].ArrivalRate < comparison.Queries[j].ArrivalRate
+	})
+	return comparison
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"benchmark-api/internal/models"
+)
+
+// CorrectnessReport compares the result checksums of a run's queries across
+// its engines and, optionally, against another run such as one on the other
+// table format. A report against a run that shares no checksummed query
+// with this one isn't consistent, since nothing could be verified.
+type CorrectnessReport struct {
+	RunID        uint   `json:"run_id"`
+	AgainstRunID *uint  `json:"against_run_id"`
+	Consistent   bool   `json:"consistent"`
+	Warning      string `json:"warning,omitempty"`
+	Mismatches   int   `json:"mismatches"` // queries whose checksums differ between engines or runs
+	// Nondeterministic counts queries whose checksum changed between the
+	// iterations on one engine, e.g. a LIMIT without ORDER BY
+	Nondeterministic int                `json:"nondeterministic"`
+	Queries          []QueryCorrectness `json:"queries"`
+}
+
+// QueryCorrectness holds the checksums of one query
+type QueryCorrectness struct {
+	QueryID          uint             `json:"query_id"`
+	QueryName        string           `json:"query_name"`
+	Consistent       bool             `json:"consistent"`
+	Nondeterministic bool             `json:"nondeterministic"`
+	Checksums        []ResultChecksum `json:"checksums"`
+}
+
+// ResultChecksum is the checksum a query's result had on one engine in one run
+type ResultChecksum struct {
+	RunID       uint   `json:"run_id"`
+	Engine      string `json:"engine"`
+	TableFormat string `json:"table_format"`
+	Checksum    string `json:"checksum"`
+	// Variants are the other checksums seen across iterations, if any
+	Variants []string `json:"variants,omitempty"`
+}
+
+// checkCorrectness compares the checksums of the completed executions of
+// every query across the engines of the given runs, matching queries as
+// the matcher does. The first iteration's checksum stands for an engine;
+// executions without one are skipped. Queries are reported with their ID in
+// the first run that checksummed them.
+func checkCorrectness(queries *queryMatcher, runs ...*models.BenchmarkRun) *CorrectnessReport {
+	report := &CorrectnessReport{RunID: runs[0].ID, Consistent: true, Queries: []QueryCorrectness{}}
+	if len(runs) > 1 {
+		report.AgainstRunID = &runs[1].ID
+	}
+
+	type key struct {
+		runID  uint
+		engine string
+	}
+	byQuery := make(map[string]map[key]*ResultChecksum)
+	queryIDs := make(map[string]uint)
+	var order []key
+	seen := make(map[key]bool)
+	for _, run := range runs {
+		executions := append([]models.QueryExecution(nil), run.Executions...)
+		sort.SliceStable(executions, func(i, j int) bool { return executions[i].Iteration < executions[j].Iteration })
+
+		for _, execution := range executions {
+			if execution.Status != models.StatusCompleted || execution.ResultChecksum == nil {
+				continue
+			}
+			k := key{run.ID, execution.Engine}
+			if !seen[k] {
+				seen[k] = true
+				order = append(order, k)
+			}
+			queryKey := queries.key(execution.QueryID)
+			checksums, ok := byQuery[queryKey]
+			if !ok {
+				checksums = make(map[key]*ResultChecksum)
+				byQuery[queryKey] = checksums
+				queryIDs[queryKey] = execution.QueryID
+			}
+
+			checksum := *execution.ResultChecksum
+			entry, ok := checksums[k]
+			if !ok {
+				checksums[k] = &ResultChecksum{
+					RunID:       run.ID,
+					Engine:      execution.Engine,
+					TableFormat: run.Config.TableFormat,
+					Checksum:    checksum,
+				}
+				continue
+			}
+			if checksum != entry.Checksum && !containsString(entry.Variants, checksum) {
+				entry.Variants = append(entry.Variants, checksum)
+			}
+		}
+	}
+
+	queryKeys := make([]string, 0, len(byQuery))
+	for queryKey := range byQuery {
+		queryKeys = append(queryKeys, queryKey)
+	}
+	sort.Slice(queryKeys, func(i, j int) bool { return queryIDs[queryKeys[i]] < queryIDs[queryKeys[j]] })
+
+	compared := 0
+	for _, queryKey := range queryKeys {
+		queryID := queryIDs[queryKey]
+		query := QueryCorrectness{
+			QueryID:    queryID,
+			QueryName:  queries.name(queryID),
+			Consistent: true,
+		}
+		runIDs := make(map[uint]bool)
+		for _, k := range order {
+			entry, ok := byQuery[queryKey][k]
+			if !ok {
+				continue
+			}
+			runIDs[k.runID] = true
+			if len(query.Checksums) > 0 && entry.Checksum != query.Checksums[0].Checksum {
+				query.Consistent = false
+			}
+			if len(entry.Variants) > 0 {
+				query.Nondeterministic = true
+			}
+			query.Checksums = append(query.Checksums, *entry)
+		}
+
+		if !query.Consistent {
+			report.Consistent = false
+			report.Mismatches++
+		}
+		if query.Nondeterministic {
+			report.Nondeterministic++
+		}
+		if len(runIDs) == len(runs) {
+			compared++
+		}
+		report.Queries = append(report.Queries, query)
+	}
+
+	if len(runs) > 1 && compared == 0 {
+		report.Consistent = false
+		report.Warning = fmt.Sprintf("no comparable queries: runs %d and %d share no checksummed query", runs[0].ID, runs[1].ID)
+	}
+	return report
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
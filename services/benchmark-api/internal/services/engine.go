@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"benchmark-api/internal/config"
+	"benchmark-api/internal/models"
+	"benchmark-api/internal/repository"
+	"benchmark-api/pkg/queryclient"
+)
+
+// EngineMonitor copies the query-service's engine health checks into the
+// engines table in the background, so each engine's is_active and
+// last_checked follow the latest check. Engines not checked yet are left
+// alone.
+type EngineMonitor struct {
+	repo   *repository.EngineRepository
+	client *queryclient.Client
+	config config.EngineMonitorConfig
+	logger *logrus.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewEngineMonitor(repo *repository.EngineRepository, client *queryclient.Client, config config.EngineMonitorConfig, logger *logrus.Logger) *EngineMonitor {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &EngineMonitor{
+		repo:   repo,
+		client: client,
+		config: config,
+		logger: logger,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start syncs the engines right away and then every configured interval
+// until Stop is called
+func (m *EngineMonitor) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(m.config.Interval)
+		defer ticker.Stop()
+		for {
+			m.sync()
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends the background syncs and waits for one in progress
+func (m *EngineMonitor) Stop() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+func (m *EngineMonitor) sync() {
+	ctx, cancel := context.WithTimeout(m.ctx, m.config.Interval)
+	defer cancel()
+
+	engines, err := m.client.ListEngines(ctx)
+	if err != nil {
+		if m.ctx.Err() == nil {
+			m.logger.WithError(err).Warn("Failed to get engine health from query-service")
+		}
+		return
+	}
+
+	for _, info := range engines {
+		if info.Health == nil || info.Health.CheckedAt == nil {
+			continue
+		}
+		engine := &models.Engine{
+			Name:        info.Name,
+			Type:        info.Type,
+			Version:     info.Health.Version,
+			IsActive:    info.Health.Status == "healthy",
+			LastChecked: *info.Health.CheckedAt,
+		}
+		if err := m.repo.SaveHealth(engine); err != nil {
+			m.logger.WithError(err).WithField("engine", info.Name).Error("Failed to record engine health")
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// Run event types published while a benchmark executes
+const (
+	EventQueryStarted  = "query-started"
+	EventQueryFinished = "query-finished"
+	EventEngineFailed  = "engine-failed"
+	EventRunCompleted  = "run-completed"
+)
+
+const (
+	// eventHistorySize is how many events are kept per benchmark for replay
+	eventHistorySize = 1000
+	// subscriberBufferSize is how far a subscriber may fall behind before
+	// it's disconnected and has to resume with Last-Event-ID
+	subscriberBufferSize = 256
+)
+
+// RunEvent is a single progress notification for a benchmark run
+type RunEvent struct {
+	ID          uint64    `json:"id"`
+	Type        string    `json:"type"`
+	BenchmarkID uint      `json:"benchmark_id"`
+	RunID       uint      `json:"run_id"`
+	Engine      string    `json:"engine,omitempty"`
+	QueryID     uint      `json:"query_id,omitempty"`
+	ExecutionID uint      `json:"execution_id,omitempty"`
+	Iteration   int       `json:"iteration,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	LatencyMs   *int64    `json:"latency_ms,omitempty"`
+	Rows        *int64    `json:"rows,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// EventBroker fans run events out to subscribers and keeps a bounded
+// history per benchmark so reconnecting clients can resume where they
+// left off. Event IDs increase monotonically across all benchmarks.
+type EventBroker struct {
+	mu     sync.Mutex
+	nextID uint64
+	logs   map[uint]*eventLog
+	closed bool
+}
+
+type eventLog struct {
+	history     []RunEvent
+	subscribers map[chan RunEvent]struct{}
+}
+
+func NewEventBroker() *EventBroker {
+	return &EventBroker{logs: make(map[uint]*eventLog)}
+}
+
+// Publish assigns the event an ID, records it and delivers it to every
+// subscriber of the benchmark
+func (b *EventBroker) Publish(event RunEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event.ID = b.nextID
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	log := b.log(event.BenchmarkID)
+	log.history = append(log.history, event)
+	if len(log.history) > eventHistorySize {
+		log.history = log.history[len(log.history)-eventHistorySize:]
+	}
+
+	for ch := range log.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Too slow to keep up; the client reconnects and replays
+			delete(log.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe returns a channel of the benchmark's events. Buffered events
+// newer than lastEventID are replayed first. The returned function must be
+// called to release the subscription.
+func (b *EventBroker) Subscribe(benchmarkID uint, lastEventID uint64) (<-chan RunEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	log := b.log(benchmarkID)
+	var replay []RunEvent
+	for _, event := range log.history {
+		if event.ID > lastEventID {
+			replay = append(replay, event)
+		}
+	}
+
+	ch := make(chan RunEvent, subscriberBufferSize+len(replay))
+	for _, event := range replay {
+		ch <- event
+	}
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	log.subscribers[ch] = struct{}{}
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := log.subscribers[ch]; ok {
+			delete(log.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// Close ends every subscription so open streams return on shutdown instead
+// of holding the server until its deadline. Later subscriptions only get the
+// replay.
+func (b *EventBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, log := range b.logs {
+		for ch := range log.subscribers {
+			delete(log.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (b *EventBroker) log(benchmarkID uint) *eventLog {
+	log, ok := b.logs[benchmarkID]
+	if !ok {
+		log = &eventLog{subscribers: make(map[chan RunEvent]struct{})}
+		b.logs[benchmarkID] = log
+	}
+	return log
+}
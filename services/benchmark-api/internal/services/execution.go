@@ -0,0 +1,92 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"benchmark-api/internal/models"
+	"benchmark-api/internal/repository"
+)
+
+var ErrNoPlan = errors.New("execution has no plan")
+
+type ExecutionService struct {
+	repo      *repository.ExecutionRepository
+	runRepo   *repository.RunRepository
+	queryRepo *repository.QueryRepository
+	logger    *logrus.Logger
+}
+
+func NewExecutionService(repo *repository.ExecutionRepository, runRepo *repository.RunRepository, queryRepo *repository.QueryRepository, logger *logrus.Logger) *ExecutionService {
+	return &ExecutionService{
+		repo:      repo,
+		runRepo:   runRepo,
+		queryRepo: queryRepo,
+		logger:    logger,
+	}
+}
+
+// ExecutionPlan is the query plan captured for an execution. Plan holds the
+// engine's JSON plan as is, or the plan text as a JSON string.
+type ExecutionPlan struct {
+	ExecutionID uint                 `json:"execution_id"`
+	QueryID     uint                 `json:"query_id"`
+	RunID       *uint                `json:"run_id"`
+	Engine      string               `json:"engine"`
+	PlanType    string               `json:"plan_type"` // "explain" or "analyze"
+	Format      string               `json:"format"`    // "json" or "text"
+	Plan        json.RawMessage      `json:"plan"`
+	Analysis    *models.PlanAnalysis `json:"analysis"` // nil if the plan couldn't be parsed
+}
+
+func (s *ExecutionService) GetExecution(id uint) (*models.QueryExecution, error) {
+	return s.repo.GetByID(id)
+}
+
+// GetPlan returns the plan captured for an execution, or ErrNoPlan if none
+// was
+func (s *ExecutionService) GetPlan(id uint) (*ExecutionPlan, error) {
+	execution, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if execution.QueryPlan == nil {
+		return nil, ErrNoPlan
+	}
+
+	plan := &ExecutionPlan{
+		ExecutionID: execution.ID,
+		QueryID:     execution.QueryID,
+		RunID:       execution.RunID,
+		Engine:      execution.Engine,
+		PlanType:    models.PlanExplain,
+		Format:      "json",
+		Plan:        json.RawMessage(*execution.QueryPlan),
+		Analysis:    planAnalysis(execution),
+	}
+	if execution.PlanType != nil {
+		plan.PlanType = *execution.PlanType
+	}
+	if !json.Valid(plan.Plan) {
+		text, err := json.Marshal(*execution.QueryPlan)
+		if err != nil {
+			return nil, err
+		}
+		plan.Format = "text"
+		plan.Plan = text
+	}
+	return plan, nil
+}
+
+// planAnalysis returns the analysis stored with an execution's plan, or
+// analyzes the plan if it was captured before plans were analyzed
+func planAnalysis(execution *models.QueryExecution) *models.PlanAnalysis {
+	if execution.PlanAnalysis != nil {
+		return execution.PlanAnalysis
+	}
+	if execution.QueryPlan == nil {
+		return nil
+	}
+	return analyzePlan(*execution.QueryPlan)
+}
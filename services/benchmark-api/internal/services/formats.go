@@ -0,0 +1,90 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"benchmark-api/internal/models"
+	"benchmark-api/internal/repository"
+)
+
+// latestFormatRuns returns the latest completed run of each table format a
+// benchmark's dataset was run with, and the formats in alphabetical order. A
+// benchmark has a single table format, so the other formats' runs come from
+// the benchmarks on the same dataset with those formats. The benchmark's own
+// runs take precedence for its format.
+func latestFormatRuns(runRepo *repository.RunRepository, benchmarkID uint) (map[string]models.BenchmarkRun, []string, error) {
+	runs, err := runRepo.GetCompletedOnDataset(benchmarkID)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].BenchmarkID == benchmarkID && runs[j].BenchmarkID != benchmarkID
+	})
+
+	latest := make(map[string]models.BenchmarkRun)
+	var formats []string
+	for _, run := range runs {
+		if _, ok := latest[run.Config.TableFormat]; !ok {
+			latest[run.Config.TableFormat] = run
+			formats = append(formats, run.Config.TableFormat)
+		}
+	}
+	sort.Strings(formats)
+	return latest, formats, nil
+}
+
+// queryMatcher identifies the same logical query across benchmarks. The
+// queries of benchmarks on different table formats read different tables
+// and have their own IDs, so across benchmarks they are matched by name.
+type queryMatcher struct {
+	names  map[uint]string
+	byName bool
+}
+
+// loadQueryMatcher matches the queries of the given benchmarks
+func loadQueryMatcher(queryRepo *repository.QueryRepository, benchmarkIDs ...uint) (*queryMatcher, error) {
+	var sets [][]models.Query
+	seen := make(map[uint]bool)
+	for _, id := range benchmarkIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		queries, err := queryRepo.GetByBenchmarkID(id)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, queries)
+	}
+	return matchQueries(sets...), nil
+}
+
+// matchQueries matches the given queries, by name if they come from more
+// than one benchmark
+func matchQueries(sets ...[]models.Query) *queryMatcher {
+	m := &queryMatcher{names: make(map[uint]string)}
+	benchmarks := make(map[uint]bool)
+	for _, queries := range sets {
+		for _, query := range queries {
+			m.names[query.ID] = query.Name
+			benchmarks[query.BenchmarkID] = true
+		}
+	}
+	m.byName = len(benchmarks) > 1
+	return m
+}
+
+// key returns the logical key of a query: its name across benchmarks, and
+// its ID within one
+func (m *queryMatcher) key(queryID uint) string {
+	if m.byName {
+		return "name:" + m.names[queryID]
+	}
+	return fmt.Sprintf("id:%d", queryID)
+}
+
+// name returns a query's name
+func (m *queryMatcher) name(queryID uint) string {
+	return m.names[queryID]
+}
@@ -0,0 +1,706 @@
+package services
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"benchmark-api/internal/models"
+)
+
+// planOp is a plan operator as parsed from any of the plan formats, before
+// it's summarized into a models.PlanAnalysis
+type planOp struct {
+	id                 string
+	name               string
+	descriptor         map[string]string
+	details            []string
+	estimatedRows      *float64
+	outputRows         *int64 // EXPLAIN ANALYZE only
+	inputRows          *int64 // EXPLAIN ANALYZE only
+	physicalInputBytes *int64 // EXPLAIN ANALYZE only
+	sourceFragments    []string
+	children           []*planOp
+}
+
+// analyzePlan parses a captured plan: a Trino or Presto EXPLAIN (FORMAT JSON)
+// plan, the text of a Trino or Presto EXPLAIN ANALYZE, or a StarRocks plan.
+// It returns nil if no operators could be found in the plan.
+func analyzePlan(plan string) *models.PlanAnalysis {
+	plan = strings.TrimSpace(plan)
+	var root *planOp
+	switch {
+	case strings.HasPrefix(plan, "{"):
+		root = parseJSONPlan(plan)
+	case strings.Contains(plan, "PLAN FRAGMENT"):
+		root = parseStarRocksPlan(plan)
+	default:
+		root = parseTextPlan(plan)
+	}
+	if root == nil {
+		return nil
+	}
+
+	analysis := &models.PlanAnalysis{
+		Tables:        []models.ScannedTable{},
+		Joins:         []models.PlanJoin{},
+		EstimatedRows: root.estimatedRows,
+		ActualRows:    root.outputRows,
+	}
+	analysis.Root = summarizeOp(root, "", analysis, make(map[*planOp]bool))
+	return analysis
+}
+
+// summarizeOp converts an operator and its inputs, collecting the scanned
+// tables and joins on the way. residual is a predicate still being filtered
+// above the operator, which falls to the table scan below it if nothing but
+// projections sit in between.
+func summarizeOp(op *planOp, residual string, analysis *models.PlanAnalysis, seen map[*planOp]bool) *models.PlanNode {
+	seen[op] = true
+	node := &models.PlanNode{
+		ID:            op.id,
+		Name:          op.name,
+		Descriptor:    op.descriptor,
+		EstimatedRows: op.estimatedRows,
+		ActualRows:    op.outputRows,
+	}
+
+	switch {
+	case isScan(op):
+		analysis.Tables = append(analysis.Tables, scannedTable(op, residual))
+	case isJoin(op):
+		analysis.Joins = append(analysis.Joins, planJoin(op))
+	}
+
+	var childResidual string
+	switch op.name {
+	case "Filter", "FilterProject":
+		childResidual = firstOf(op.descriptor, "filterPredicate", "predicate")
+	case "Project":
+		childResidual = residual
+	}
+	for _, child := range op.children {
+		if seen[child] {
+			continue
+		}
+		node.Children = append(node.Children, summarizeOp(child, childResidual, analysis, seen))
+	}
+	return node
+}
+
+func isScan(op *planOp) bool {
+	return strings.Contains(op.name, "Scan") && op.descriptor["table"] != ""
+}
+
+func isJoin(op *planOp) bool {
+	return strings.Contains(strings.ToLower(op.name), "join")
+}
+
+var (
+	partitionsPattern = regexp.MustCompile(`(?i)\bpartitions(?:[ _]?read)?\s*[=:]\s*(\d+)(?:\s*/\s*(\d+))?`)
+	filesPattern      = regexp.MustCompile(`(?i)\b(?:data[ _]?)?files(?:[ _]?read)?['"]?\s*[=:]\s*['"]?(\d+)`)
+	splitsPattern     = regexp.MustCompile(`(?i)\bsplits\s*[=:]\s*(\d+)`)
+)
+
+// scanCatalogs are the catalogs implied by StarRocks scan operators, whose
+// plans name the table alone
+var scanCatalogs = map[string]string{
+	"HdfsScanNode":    "hive",
+	"HiveScanNode":    "hive",
+	"IcebergScanNode": "iceberg",
+}
+
+func scannedTable(op *planOp, residual string) models.ScannedTable {
+	ref := op.descriptor["table"]
+	catalog, name := parseTableName(ref)
+	if catalog == "" {
+		catalog = scanCatalogs[op.name]
+	}
+	table := models.ScannedTable{
+		Table:              name,
+		Catalog:            catalog,
+		Constraint:         tableConstraint(op, ref),
+		FilterPredicate:    op.descriptor["filterPredicate"],
+		EstimatedRows:      op.estimatedRows,
+		ActualRows:         op.inputRows,
+		PhysicalInputBytes: op.physicalInputBytes,
+	}
+	if table.ActualRows == nil && op.name == "TableScan" {
+		table.ActualRows = op.outputRows
+	}
+	if residual != "" {
+		if table.FilterPredicate != "" {
+			table.FilterPredicate += " AND "
+		}
+		table.FilterPredicate += residual
+	}
+
+	switch {
+	case table.Constraint != "" && table.FilterPredicate == "":
+		table.PredicatePushdown = models.PushdownFull
+	case table.Constraint != "":
+		table.PredicatePushdown = models.PushdownPartial
+	case table.FilterPredicate != "":
+		table.PredicatePushdown = models.PushdownNone
+	}
+
+	text := ref + "\n" + strings.Join(op.details, "\n")
+	if m := partitionsPattern.FindStringSubmatch(text); m != nil {
+		table.PartitionsRead = parseCount(m[1])
+		table.PartitionsTotal = parseCount(m[2])
+	}
+	if m := filesPattern.FindStringSubmatch(text); m != nil {
+		table.FilesRead = parseCount(m[1])
+	}
+	if m := splitsPattern.FindStringSubmatch(text); m != nil {
+		table.Splits = parseCount(m[1])
+	}
+	return table
+}
+
+var (
+	prestoConnectorPattern = regexp.MustCompile(`connectorId='([^']*)'`)
+	prestoSchemaPattern    = regexp.MustCompile(`schemaName=([\w.]+)`)
+	prestoTablePattern     = regexp.MustCompile(`tableName=([\w.]+)`)
+	prestoDomainsPattern   = regexp.MustCompile(`domains=\{(.+?)\}\}`)
+)
+
+// parseTableName splits a table reference into its catalog and schema.table.
+// Trino writes references like "hive:tpch:customer" and
+// "iceberg:tpch.customer$data@123 constraint on [c_nationkey]", Presto like
+// "TableHandle {connectorId='hive', connectorHandle='HiveTableHandle{schemaName=tpch, tableName=customer}', ...}"
+// and StarRocks just the table.
+func parseTableName(ref string) (catalog, table string) {
+	if m := prestoConnectorPattern.FindStringSubmatch(ref); m != nil {
+		catalog = m[1]
+		if t := prestoTablePattern.FindStringSubmatch(ref); t != nil {
+			table = t[1]
+			if s := prestoSchemaPattern.FindStringSubmatch(ref); s != nil {
+				table = s[1] + "." + table
+			}
+		}
+		return catalog, table
+	}
+
+	fields := strings.Fields(ref)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	table = fields[0]
+	if i := strings.Index(table, ":"); i >= 0 {
+		catalog, table = table[:i], table[i+1:]
+	}
+	table = strings.ReplaceAll(table, ":", ".")
+	if i := strings.IndexAny(table, "$@"); i >= 0 {
+		table = table[:i]
+	}
+	return catalog, table
+}
+
+// tableConstraint returns the predicate a scan handed to the connector, if
+// the plan shows one
+func tableConstraint(op *planOp, ref string) string {
+	if i := strings.Index(ref, "constraint on "); i >= 0 {
+		return strings.TrimSpace(ref[i+len("constraint on "):])
+	}
+	if m := prestoDomainsPattern.FindStringSubmatch(ref); m != nil {
+		return m[1]
+	}
+	if predicates := op.descriptor["predicates"]; predicates != "" {
+		return predicates
+	}
+
+	// Trino lists the domains handed to the connector under their columns,
+	// like "c_nationkey := c_nationkey:bigint:REGULAR" then ":: [[15]]"
+	var domains []string
+	for i, detail := range op.details {
+		detail = strings.TrimSpace(detail)
+		if !strings.HasPrefix(detail, "::") || i == 0 {
+			continue
+		}
+		column, _, _ := strings.Cut(strings.TrimSpace(op.details[i-1]), " := ")
+		domains = append(domains, column+" "+detail)
+	}
+	return strings.Join(domains, ", ")
+}
+
+var distributionPattern = regexp.MustCompile(`(?i)^distribution:\s*(\w+)`)
+
+func planJoin(op *planOp) models.PlanJoin {
+	join := models.PlanJoin{
+		Type:          firstOf(op.descriptor, "joinType"),
+		Distribution:  strings.ToUpper(firstOf(op.descriptor, "distribution")),
+		Criteria:      firstOf(op.descriptor, "criteria", "identifier"),
+		EstimatedRows: op.estimatedRows,
+		ActualRows:    op.outputRows,
+	}
+	if join.Type == "" {
+		join.Type = op.name
+	}
+	if join.Distribution == "" {
+		for _, detail := range op.details {
+			if m := distributionPattern.FindStringSubmatch(strings.TrimSpace(detail)); m != nil {
+				join.Distribution = strings.ToUpper(m[1])
+				break
+			}
+		}
+	}
+	return join
+}
+
+func firstOf(descriptor map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value := descriptor[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// linkFragments hangs the root operator of every fragment under the remote
+// source operators reading from it, and returns the root of the plan: the
+// root of fragment 0, or else of the lowest numbered fragment
+func linkFragments(fragments map[string]*planOp, order []string) *planOp {
+	if len(order) == 0 {
+		return nil
+	}
+	var link func(op *planOp, seen map[*planOp]bool)
+	link = func(op *planOp, seen map[*planOp]bool) {
+		if seen[op] {
+			return
+		}
+		seen[op] = true
+		for _, id := range op.sourceFragments {
+			if source, ok := fragments[id]; ok && !seen[source] {
+				op.children = append(op.children, source)
+			}
+		}
+		for _, child := range op.children {
+			link(child, seen)
+		}
+	}
+
+	ids := append([]string(nil), order...)
+	sort.SliceStable(ids, func(i, j int) bool {
+		a, errA := strconv.Atoi(ids[i])
+		b, errB := strconv.Atoi(ids[j])
+		if errA != nil || errB != nil {
+			return false
+		}
+		return a < b
+	})
+	root := fragments[ids[0]]
+	link(root, make(map[*planOp]bool))
+	return root
+}
+
+// jsonPlanNode is a node of a Trino or Presto EXPLAIN (FORMAT JSON) plan.
+// Trino describes a node with a descriptor and a list of details, Presto
+// with an identifier and a details string.
+type jsonPlanNode struct {
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	Descriptor    map[string]string `json:"descriptor"`
+	Identifier    string            `json:"identifier"`
+	Details       json.RawMessage   `json:"details"`
+	Estimates     []jsonEstimate    `json:"estimates"`
+	Children      []jsonPlanNode    `json:"children"`
+	RemoteSources []string          `json:"remoteSources"`
+}
+
+type jsonEstimate struct {
+	OutputRowCount planEstimate `json:"outputRowCount"`
+}
+
+// planEstimate is a planner estimate, which engines write as "NaN" when
+// they have none
+type planEstimate struct {
+	value float64
+	known bool
+}
+
+func (e *planEstimate) UnmarshalJSON(data []byte) error {
+	var value float64
+	if err := json.Unmarshal(data, &value); err != nil {
+		// "NaN", "Infinity" or null
+		return nil
+	}
+	e.value, e.known = value, true
+	return nil
+}
+
+// bareNonFinite matches NaN and Infinity written as bare JSON tokens
+var bareNonFinite = regexp.MustCompile(`([:\[,]\s*)-?(?:NaN|Infinity)(\s*[,\]}])`)
+
+// parseJSONPlan parses a single plan tree, as EXPLAIN (TYPE LOGICAL) gives,
+// or a map of fragment IDs to their trees, as EXPLAIN (TYPE DISTRIBUTED) does
+func parseJSONPlan(plan string) *planOp {
+	for i := 0; i < 2; i++ {
+		plan = bareNonFinite.ReplaceAllString(plan, "${1}null${2}")
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(plan), &fields); err != nil {
+		return nil
+	}
+	if _, ok := fields["name"]; ok {
+		var node jsonPlanNode
+		if err := json.Unmarshal([]byte(plan), &node); err != nil {
+			return nil
+		}
+		return convertJSONNode(node)
+	}
+
+	fragments := make(map[string]*planOp, len(fields))
+	order := make([]string, 0, len(fields))
+	for id, raw := range fields {
+		var node jsonPlanNode
+		if err := json.Unmarshal(raw, &node); err != nil || node.Name == "" {
+			continue
+		}
+		fragments[id] = convertJSONNode(node)
+		order = append(order, id)
+	}
+	sort.Strings(order)
+	return linkFragments(fragments, order)
+}
+
+func convertJSONNode(node jsonPlanNode) *planOp {
+	op := &planOp{
+		id:         node.ID,
+		name:       node.Name,
+		descriptor: node.Descriptor,
+	}
+	if op.descriptor == nil {
+		op.descriptor = parseDescriptor(node.Identifier)
+	}
+
+	var details []string
+	if err := json.Unmarshal(node.Details, &details); err == nil {
+		op.details = details
+	} else {
+		var text string
+		if err := json.Unmarshal(node.Details, &text); err == nil && text != "" {
+			op.details = strings.Split(text, "\n")
+		}
+	}
+
+	for _, estimate := range node.Estimates {
+		if estimate.OutputRowCount.known {
+			rows := estimate.OutputRowCount.value
+			op.estimatedRows = &rows
+			break
+		}
+	}
+
+	op.sourceFragments = node.RemoteSources
+	if ids, ok := op.descriptor["sourceFragmentIds"]; ok {
+		op.sourceFragments = fragmentIDs(ids)
+	}
+	for _, child := range node.Children {
+		op.children = append(op.children, convertJSONNode(child))
+	}
+	return op
+}
+
+// parseDescriptor parses the bracketed arguments of an operator, like
+// "[table = hive:tpch:customer, filterPredicate = (c_nationkey = 15)]".
+// Arguments that aren't key = value pairs are kept under "identifier".
+func parseDescriptor(identifier string) map[string]string {
+	descriptor := make(map[string]string)
+	identifier = strings.TrimSpace(identifier)
+	if !strings.HasPrefix(identifier, "[") {
+		if identifier != "" {
+			descriptor["identifier"] = identifier
+		}
+		return descriptor
+	}
+
+	// Only the first bracketed group; Presto appends the hash symbols in another
+	depth, end := 0, len(identifier)
+	for i, r := range identifier {
+		switch r {
+		case '[', '(', '{':
+			depth++
+		case ']', ')', '}':
+			depth--
+		}
+		if depth == 0 {
+			end = i
+			break
+		}
+	}
+	args := identifier[1:end]
+
+	var positional []string
+	for _, arg := range splitTopLevel(args) {
+		if key, value, ok := strings.Cut(arg, " = "); ok && !strings.ContainsAny(key, "([{'\" ") {
+			descriptor[key] = value
+			continue
+		}
+		if strings.HasPrefix(arg, "TableHandle") {
+			descriptor["table"] = arg
+			continue
+		}
+		positional = append(positional, arg)
+	}
+	if len(positional) > 0 {
+		descriptor["identifier"] = strings.Join(positional, ", ")
+	}
+	return descriptor
+}
+
+// splitTopLevel splits on the commas that aren't inside brackets or quotes
+func splitTopLevel(s string) []string {
+	var (
+		parts []string
+		depth int
+		quote rune
+		start int
+	)
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '[' || r == '(' || r == '{':
+			depth++
+		case r == ']' || r == ')' || r == '}':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if rest := strings.TrimSpace(s[start:]); rest != "" {
+		parts = append(parts, rest)
+	}
+	return parts
+}
+
+var fragmentIDPattern = regexp.MustCompile(`\d+`)
+
+func fragmentIDs(s string) []string {
+	return fragmentIDPattern.FindAllString(s, -1)
+}
+
+var (
+	textFragmentPattern = regexp.MustCompile(`^\s*Fragment (\d+)`)
+	textOperatorPattern = regexp.MustCompile(`^([\s│|]*)(?:[├└]─\s*|-\s+)?([A-Z][A-Za-z]*)(?:\([A-Z_]+\))?(\[.*\])?\s*(?:=>.*)?$`)
+	textEstimatePattern = regexp.MustCompile(`Estimates: \{rows: ([\d.]+)`)
+	textOutputPattern   = regexp.MustCompile(`\bOutput: ([\d.]+[KMB]?) rows?\b`)
+	textInputPattern    = regexp.MustCompile(`^Input: ([\d.]+[KMB]?) rows?\b`)
+	textPhysicalPattern = regexp.MustCompile(`Physical input: ([\d.]+)\s*([kKMGTP]?B)\b`)
+)
+
+// parseTextPlan parses the text of a Trino or Presto EXPLAIN ANALYZE, where
+// operators are nested by indentation under the fragment they run in and
+// are followed by the lines describing them
+func parseTextPlan(plan string) *planOp {
+	type level struct {
+		indent int
+		op     *planOp
+	}
+
+	fragments := make(map[string]*planOp)
+	var (
+		order    []string
+		fragment string
+		stack    []level
+		current  *planOp
+	)
+	for _, line := range strings.Split(plan, "\n") {
+		if m := textFragmentPattern.FindStringSubmatch(line); m != nil {
+			fragment, stack, current = m[1], nil, nil
+			continue
+		}
+		if m := textOperatorPattern.FindStringSubmatch(line); m != nil {
+			op := &planOp{name: m[2], descriptor: parseDescriptor(m[3])}
+			if strings.HasPrefix(op.name, "Remote") {
+				op.sourceFragments = fragmentIDs(firstOf(op.descriptor, "sourceFragmentIds", "identifier"))
+			}
+			indent := len([]rune(line)) - len([]rune(strings.TrimLeft(line, " │|├└─-")))
+
+			for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1].op
+				parent.children = append(parent.children, op)
+			} else if _, ok := fragments[fragment]; !ok {
+				fragments[fragment] = op
+				order = append(order, fragment)
+			}
+			stack = append(stack, level{indent, op})
+			current = op
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		detail := strings.TrimSpace(strings.TrimLeft(line, " │|"))
+		if detail == "" {
+			continue
+		}
+		current.details = append(current.details, detail)
+		if m := textEstimatePattern.FindStringSubmatch(detail); m != nil && current.estimatedRows == nil {
+			if rows, err := strconv.ParseFloat(m[1], 64); err == nil {
+				current.estimatedRows = &rows
+			}
+		}
+		if m := textOutputPattern.FindStringSubmatch(detail); m != nil && strings.HasPrefix(detail, "CPU:") {
+			current.outputRows = parseCount(m[1])
+		}
+		if m := textInputPattern.FindStringSubmatch(detail); m != nil {
+			current.inputRows = parseCount(m[1])
+		}
+		if m := textPhysicalPattern.FindStringSubmatch(detail); m != nil {
+			current.physicalInputBytes = parseSize(m[1], m[2])
+		}
+	}
+	return linkFragments(fragments, order)
+}
+
+var (
+	starRocksFragmentPattern = regexp.MustCompile(`^\s*PLAN FRAGMENT (\d+)`)
+	starRocksOperatorPattern = regexp.MustCompile(`^([\s|]*?)(\|-+)?(\d+):([A-Z]\S*(?: \S+)*?)\s*$`)
+	starRocksExchangePattern = regexp.MustCompile(`EXCHANGE ID: 0*(\d+)`)
+	starRocksJoinPattern     = regexp.MustCompile(`join op: ([A-Z ]+JOIN)(?: \((\w+)\))?`)
+	starRocksCardinality     = regexp.MustCompile(`cardinality[=:]\s*(\d+)`)
+	starRocksTablePattern    = regexp.MustCompile(`^TABLE: (\S+)`)
+)
+
+// parseStarRocksPlan parses a StarRocks EXPLAIN. An operator's input is
+// printed below it in the same column, a join's second input is branched
+// off with "|----", and fragments send their output to the EXCHANGE
+// operator named in their "EXCHANGE ID".
+func parseStarRocksPlan(plan string) *planOp {
+	fragments := make(map[string]*planOp)
+	byID := make(map[string]*planOp)
+	exchanges := make(map[string]string) // fragment -> exchange it sends to
+	var (
+		order    []string
+		fragment string
+		byColumn map[int]*planOp
+		current  *planOp
+	)
+	for _, line := range strings.Split(plan, "\n") {
+		if m := starRocksFragmentPattern.FindStringSubmatch(line); m != nil {
+			fragment, byColumn, current = m[1], make(map[int]*planOp), nil
+			order = append(order, fragment)
+			continue
+		}
+		if byColumn == nil {
+			continue
+		}
+		if m := starRocksOperatorPattern.FindStringSubmatch(line); m != nil {
+			op := &planOp{id: m[3], name: strings.TrimSpace(m[4]), descriptor: make(map[string]string)}
+			column := len(m[1])
+			if m[2] != "" {
+				column += len(m[2])
+			}
+			parentColumn := column
+			if m[2] != "" {
+				parentColumn = strings.LastIndex(m[1]+m[2], "|")
+			}
+			if parent, ok := byColumn[parentColumn]; ok {
+				parent.children = append(parent.children, op)
+			} else if _, ok := fragments[fragment]; !ok {
+				fragments[fragment] = op
+			}
+			byColumn[column] = op
+			byID[op.id] = op
+			current = op
+			continue
+		}
+
+		detail := strings.TrimSpace(strings.TrimLeft(line, " |"))
+		if detail == "" {
+			continue
+		}
+		if m := starRocksExchangePattern.FindStringSubmatch(detail); m != nil {
+			exchanges[fragment] = m[1]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		current.details = append(current.details, detail)
+		switch {
+		case starRocksTablePattern.MatchString(detail):
+			current.descriptor["table"] = starRocksTablePattern.FindStringSubmatch(detail)[1]
+		case strings.HasPrefix(detail, "PREDICATES:"), strings.HasPrefix(detail, "PARTITION PREDICATES:"):
+			// Olap scans and the partition pruning of external scans
+			_, predicates, _ := strings.Cut(detail, "PREDICATES:")
+			current.descriptor["predicates"] = strings.TrimSpace(predicates)
+		case strings.HasPrefix(detail, "NON-PARTITION PREDICATES:"):
+			current.descriptor["filterPredicate"] = strings.TrimSpace(strings.TrimPrefix(detail, "NON-PARTITION PREDICATES:"))
+		case strings.HasPrefix(detail, "equal join conjunct:"):
+			current.descriptor["criteria"] = strings.TrimSpace(strings.TrimPrefix(detail, "equal join conjunct:"))
+		case starRocksJoinPattern.MatchString(detail):
+			m := starRocksJoinPattern.FindStringSubmatch(detail)
+			current.descriptor["joinType"] = m[1]
+			current.descriptor["distribution"] = m[2]
+		case starRocksCardinality.MatchString(detail) && current.estimatedRows == nil:
+			if rows, err := strconv.ParseFloat(starRocksCardinality.FindStringSubmatch(detail)[1], 64); err == nil {
+				current.estimatedRows = &rows
+			}
+		}
+	}
+
+	for fragment, exchange := range exchanges {
+		if op, ok := byID[exchange]; ok && fragments[fragment] != nil {
+			op.children = append(op.children, fragments[fragment])
+		}
+	}
+	// Linked through the exchanges above rather than remote sources
+	var linked []string
+	for _, id := range order {
+		if fragments[id] != nil {
+			linked = append(linked, id)
+		}
+	}
+	return linkFragments(fragments, linked)
+}
+
+// parseCount parses a row count, which Trino may abbreviate like "1.50M"
+func parseCount(s string) *int64 {
+	if s == "" {
+		return nil
+	}
+	multiplier := 1.0
+	switch s[len(s)-1] {
+	case 'K':
+		multiplier, s = 1e3, s[:len(s)-1]
+	case 'M':
+		multiplier, s = 1e6, s[:len(s)-1]
+	case 'B':
+		multiplier, s = 1e9, s[:len(s)-1]
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	count := int64(value * multiplier)
+	return &count
+}
+
+// parseSize parses a data size like "1.23MB"
+func parseSize(value, unit string) *int64 {
+	size, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil
+	}
+	exponent := strings.IndexByte("BKMGTP", strings.ToUpper(unit)[0])
+	for i := 0; i < exponent; i++ {
+		size *= 1024
+	}
+	bytes := int64(size)
+	return &bytes
+}
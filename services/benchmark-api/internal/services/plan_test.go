@@ -0,0 +1,230 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"benchmark-api/internal/models"
+)
+
+func TestAnalyzePlan(t *testing.T) {
+	tests := []struct {
+		name          string
+		fixture       string
+		operators     []string // depth-first, across fragments
+		tables        []models.ScannedTable
+		joins         []models.PlanJoin
+		estimatedRows *float64
+		actualRows    *int64
+	}{
+		{
+			name:      "trino distributed json",
+			fixture:   "trino_explain.json",
+			operators: []string{"Output", "TopN", "RemoteSource", "TopNPartial", "InnerJoin", "ScanFilterProject", "LocalExchange", "RemoteSource", "TableScan"},
+			tables: []models.ScannedTable{
+				{
+					Table:             "default.orders_hive",
+					Catalog:           "hive",
+					FilterPredicate:   "(o_orderdate >= DATE '2023-01-01')",
+					PredicatePushdown: models.PushdownNone,
+					EstimatedRows:     float64Ptr(1500000),
+				},
+				{
+					Table:             "default.customer_hive",
+					Catalog:           "hive",
+					Constraint:        "c_nationkey :: [[1]]",
+					PredicatePushdown: models.PushdownFull,
+					EstimatedRows:     float64Ptr(6000),
+				},
+			},
+			joins: []models.PlanJoin{
+				{Type: "InnerJoin", Distribution: "REPLICATED", Criteria: "(o_custkey = c_custkey)", EstimatedRows: float64Ptr(60231.5)},
+			},
+			estimatedRows: float64Ptr(100),
+		},
+		{
+			name:      "trino explain analyze text",
+			fixture:   "trino_explain_analyze.txt",
+			operators: []string{"Output", "RemoteSource", "Aggregate", "ScanFilterProject"},
+			tables: []models.ScannedTable{
+				{
+					Table:              "default.customer_hive",
+					Catalog:            "hive",
+					FilterPredicate:    "(c_acctbal > DECIMAL '1000.00')",
+					PredicatePushdown:  models.PushdownNone,
+					Splits:             int64Ptr(4),
+					EstimatedRows:      float64Ptr(150000),
+					ActualRows:         int64Ptr(150000),
+					PhysicalInputBytes: int64Ptr(2369781),
+				},
+			},
+			joins:      []models.PlanJoin{},
+			actualRows: int64Ptr(5),
+		},
+		{
+			name:      "presto logical json",
+			fixture:   "presto_explain.json",
+			operators: []string{"Output", "TopN", "InnerJoin", "ScanFilter", "LocalExchange", "TableScan"},
+			tables: []models.ScannedTable{
+				{
+					Table:             "default.orders_hive",
+					Catalog:           "hive",
+					Constraint:        "o_orderdate=[ [[2023-01-01, <max>)] ]",
+					FilterPredicate:   "(o_orderdate) >= (DATE'2023-01-01')",
+					PredicatePushdown: models.PushdownPartial,
+					EstimatedRows:     float64Ptr(1500000),
+				},
+				{
+					Table:             "default.customer_hive",
+					Catalog:           "hive",
+					Constraint:        "c_nationkey=[ [[1]] ]",
+					PredicatePushdown: models.PushdownFull,
+					EstimatedRows:     float64Ptr(6000),
+				},
+			},
+			joins: []models.PlanJoin{
+				{Type: "InnerJoin", Distribution: "REPLICATED", Criteria: `("o_custkey" = "c_custkey")`, EstimatedRows: float64Ptr(60231.5)},
+			},
+			estimatedRows: float64Ptr(100),
+		},
+		{
+			name:      "starrocks explain",
+			fixture:   "starrocks_explain.txt",
+			operators: []string{"MERGING-EXCHANGE", "TOP-N", "Project", "HASH JOIN", "EXCHANGE", "HdfsScanNode", "HdfsScanNode"},
+			tables: []models.ScannedTable{
+				{
+					Table:             "customer_hive",
+					Catalog:           "hive",
+					Constraint:        "4: c_nationkey = 1",
+					PredicatePushdown: models.PushdownFull,
+					PartitionsRead:    int64Ptr(1),
+					PartitionsTotal:   int64Ptr(25),
+					EstimatedRows:     float64Ptr(6000),
+				},
+				{
+					Table:             "orders_hive",
+					Catalog:           "hive",
+					FilterPredicate:   "13: o_orderdate >= '2023-01-01'",
+					PredicatePushdown: models.PushdownNone,
+					PartitionsRead:    int64Ptr(3),
+					PartitionsTotal:   int64Ptr(3),
+					EstimatedRows:     float64Ptr(1500000),
+				},
+			},
+			joins: []models.PlanJoin{
+				{Type: "INNER JOIN", Distribution: "BROADCAST", Criteria: "10: o_custkey = 1: c_custkey"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := os.ReadFile(filepath.Join("testdata", "plans", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			analysis := analyzePlan(string(plan))
+			if analysis == nil {
+				t.Fatal("plan was not parsed")
+			}
+
+			if got := operatorNames(analysis.Root); !reflect.DeepEqual(got, tt.operators) {
+				t.Errorf("operators = %q, want %q", got, tt.operators)
+			}
+			if len(analysis.Tables) != len(tt.tables) {
+				t.Fatalf("got %d tables, want %d: %+v", len(analysis.Tables), len(tt.tables), analysis.Tables)
+			}
+			for i, want := range tt.tables {
+				if got := analysis.Tables[i]; !reflect.DeepEqual(got, want) {
+					t.Errorf("table %d = %s, want %s", i, describe(got), describe(want))
+				}
+			}
+			if !reflect.DeepEqual(analysis.Joins, tt.joins) {
+				t.Errorf("joins = %+v, want %+v", analysis.Joins, tt.joins)
+			}
+			if !reflect.DeepEqual(analysis.EstimatedRows, tt.estimatedRows) {
+				t.Errorf("estimated rows = %v, want %v", deref(analysis.EstimatedRows), deref(tt.estimatedRows))
+			}
+			if !reflect.DeepEqual(analysis.ActualRows, tt.actualRows) {
+				t.Errorf("actual rows = %v, want %v", deref(analysis.ActualRows), deref(tt.actualRows))
+			}
+		})
+	}
+}
+
+func TestAnalyzePlanUnrecognized(t *testing.T) {
+	for _, plan := range []string{"", "not a plan", "{\"error\": \"line 1:1: mismatched input\"}"} {
+		if analysis := analyzePlan(plan); analysis != nil {
+			t.Errorf("analyzePlan(%q) = %+v, want nil", plan, analysis)
+		}
+	}
+}
+
+func TestParseTableName(t *testing.T) {
+	tests := []struct {
+		ref     string
+		catalog string
+		table   string
+	}{
+		{"hive:tpch:customer", "hive", "tpch.customer"},
+		{"iceberg:tpch.customer$data@8046546578937423419 constraint on [c_nationkey]", "iceberg", "tpch.customer"},
+		{"delta:tpch.orders", "delta", "tpch.orders"},
+		{"TableHandle {connectorId='hive', connectorHandle='HiveTableHandle{schemaName=tpch, tableName=customer, analyzePartitionValues=Optional.empty}', layout='Optional[tpch.customer]'}", "hive", "tpch.customer"},
+		{"lineitem", "", "lineitem"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		catalog, table := parseTableName(tt.ref)
+		if catalog != tt.catalog || table != tt.table {
+			t.Errorf("parseTableName(%q) = %q, %q, want %q, %q", tt.ref, catalog, table, tt.catalog, tt.table)
+		}
+	}
+}
+
+func TestParseCount(t *testing.T) {
+	tests := map[string]*int64{
+		"150000": int64Ptr(150000),
+		"1.50M":  int64Ptr(1500000),
+		"12.3K":  int64Ptr(12300),
+		"2B":     int64Ptr(2000000000),
+		"":       nil,
+		"rows":   nil,
+	}
+	for s, want := range tests {
+		if got := parseCount(s); !reflect.DeepEqual(got, want) {
+			t.Errorf("parseCount(%q) = %v, want %v", s, deref(got), deref(want))
+		}
+	}
+}
+
+func operatorNames(node *models.PlanNode) []string {
+	if node == nil {
+		return nil
+	}
+	names := []string{node.Name}
+	for _, child := range node.Children {
+		names = append(names, operatorNames(child)...)
+	}
+	return names
+}
+
+func describe(table models.ScannedTable) string {
+	return fmt.Sprintf("{table: %s, catalog: %s, constraint: %q, filter: %q, pushdown: %s, partitions: %v/%v, files: %v, splits: %v, estimated: %v, actual: %v, bytes: %v}",
+		table.Table, table.Catalog, table.Constraint, table.FilterPredicate, table.PredicatePushdown,
+		deref(table.PartitionsRead), deref(table.PartitionsTotal), deref(table.FilesRead), deref(table.Splits),
+		deref(table.EstimatedRows), deref(table.ActualRows), deref(table.PhysicalInputBytes))
+}
+
+func deref[T any](p *T) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func float64Ptr(v float64) *float64 { return &v }
@@ -0,0 +1,391 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"benchmark-api/internal/models"
+)
+
+// ErrInvalidPlanDiff is returned when a plan diff request doesn't select two
+// plans to compare
+var ErrInvalidPlanDiff = errors.New("invalid plan diff")
+
+// PlanDiffRequest selects the plans to compare: two executions, or a query
+// whose plans are taken from the latest completed run of its benchmark with
+// each of two table formats
+type PlanDiffRequest struct {
+	BaseID      uint
+	CandidateID uint
+	QueryID     uint
+	Engine      string // with QueryID; any engine with plans on both sides if empty
+}
+
+// PlanDiff shows what changed in a query's plan between two executions
+type PlanDiff struct {
+	Base      PlanDiffSide     `json:"base"`
+	Candidate PlanDiffSide     `json:"candidate"`
+	Changes   []string         `json:"changes"` // plan-wide changes
+	Operators []OperatorChange `json:"operators"`
+	Tables    []TableDiff      `json:"tables"`
+	Joins     []JoinDiff       `json:"joins"`
+}
+
+// PlanDiffSide is one of the plans being compared
+type PlanDiffSide struct {
+	ExecutionID uint                 `json:"execution_id"`
+	RunID       *uint                `json:"run_id"`
+	QueryID     uint                 `json:"query_id"`
+	Engine      string               `json:"engine"`
+	TableFormat string               `json:"table_format"`
+	PlanType    string               `json:"plan_type"`
+	Analysis    *models.PlanAnalysis `json:"analysis"`
+}
+
+// OperatorChange is an operator used a different number of times
+type OperatorChange struct {
+	Operator  string `json:"operator"`
+	Base      int    `json:"base"`
+	Candidate int    `json:"candidate"`
+}
+
+// TableDiff compares the scans of a table. Tables are matched on their name
+// without the catalog or a table format suffix, so customer_hive and
+// customer_iceberg are the same table.
+type TableDiff struct {
+	Table     string               `json:"table"`
+	Base      *models.ScannedTable `json:"base"`      // nil if only the candidate scans it
+	Candidate *models.ScannedTable `json:"candidate"` // nil if only the base scans it
+	Changes   []string             `json:"changes"`
+}
+
+// JoinDiff compares the joins found at the same position in both plans
+type JoinDiff struct {
+	Index     int              `json:"index"`
+	Base      *models.PlanJoin `json:"base"`
+	Candidate *models.PlanJoin `json:"candidate"`
+	Changes   []string         `json:"changes"`
+}
+
+// DiffPlans resolves the two sides of a request and compares their plans
+func (s *ExecutionService) DiffPlans(req PlanDiffRequest) (*PlanDiff, error) {
+	var (
+		base, candidate *models.QueryExecution
+		err             error
+	)
+	switch {
+	case req.BaseID != 0 && req.CandidateID != 0:
+		if base, err = s.repo.GetByID(req.BaseID); err != nil {
+			return nil, err
+		}
+		if candidate, err = s.repo.GetByID(req.CandidateID); err != nil {
+			return nil, err
+		}
+	case req.QueryID != 0:
+		if base, candidate, err = s.tableFormatPlans(req.QueryID, req.Engine); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: base and candidate, or query_id is required", ErrInvalidPlanDiff)
+	}
+
+	baseSide, err := s.planDiffSide(base)
+	if err != nil {
+		return nil, err
+	}
+	candidateSide, err := s.planDiffSide(candidate)
+	if err != nil {
+		return nil, err
+	}
+	return diffPlans(baseSide, candidateSide), nil
+}
+
+// tableFormatPlans picks the plans of a query from the latest completed runs
+// of its dataset with the first two table formats in alphabetical order,
+// hive and iceberg, see latestFormatRuns. The other format's runs belong to
+// another benchmark, whose query is matched by name.
+func (s *ExecutionService) tableFormatPlans(queryID uint, engine string) (*models.QueryExecution, *models.QueryExecution, error) {
+	query, err := s.queryRepo.GetByID(queryID)
+	if err != nil {
+		return nil, nil, err
+	}
+	latest, formats, err := latestFormatRuns(s.runRepo, query.BenchmarkID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(formats) < 2 {
+		return nil, nil, fmt.Errorf("%w: the dataset needs completed runs with two table formats, found %d", ErrInvalidPlanDiff, len(formats))
+	}
+
+	var sides [2][]models.QueryExecution
+	for i, format := range formats[:2] {
+		run := latest[format]
+		sideQueryID, err := s.matchingQuery(query, run.BenchmarkID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if sides[i], err = s.repo.GetPlanned(run.ID, sideQueryID, engine); err != nil {
+			return nil, nil, err
+		}
+	}
+	baseExecutions, candidateExecutions := sides[0], sides[1]
+
+	// The first engine with plans on both sides, preferring the same plan type
+	byEngine := make(map[string][]models.QueryExecution)
+	for _, execution := range candidateExecutions {
+		byEngine[execution.Engine] = append(byEngine[execution.Engine], execution)
+	}
+	sort.SliceStable(baseExecutions, func(i, j int) bool {
+		return baseExecutions[i].Engine < baseExecutions[j].Engine
+	})
+	for i := range baseExecutions {
+		matches := byEngine[baseExecutions[i].Engine]
+		if len(matches) == 0 {
+			continue
+		}
+		match := matches[len(matches)-1]
+		for j := len(matches) - 1; j >= 0; j-- {
+			if samePlanType(&baseExecutions[i], &matches[j]) {
+				match = matches[j]
+				break
+			}
+		}
+		return &baseExecutions[i], &match, nil
+	}
+	return nil, nil, fmt.Errorf("%w: no engine captured plans for query %d with both %s and %s", ErrNoPlan, queryID, formats[0], formats[1])
+}
+
+// matchingQuery returns the ID of the query of a benchmark with the same
+// name as query
+func (s *ExecutionService) matchingQuery(query *models.Query, benchmarkID uint) (uint, error) {
+	if query.BenchmarkID == benchmarkID {
+		return query.ID, nil
+	}
+	queries, err := s.queryRepo.GetByBenchmarkID(benchmarkID)
+	if err != nil {
+		return 0, err
+	}
+	for _, candidate := range queries {
+		if candidate.Name == query.Name {
+			return candidate.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: benchmark %d has no query named %q", ErrInvalidPlanDiff, benchmarkID, query.Name)
+}
+
+func samePlanType(a, b *models.QueryExecution) bool {
+	return a.PlanType != nil && b.PlanType != nil && *a.PlanType == *b.PlanType
+}
+
+func (s *ExecutionService) planDiffSide(execution *models.QueryExecution) (PlanDiffSide, error) {
+	if execution.QueryPlan == nil {
+		return PlanDiffSide{}, fmt.Errorf("%w: execution %d", ErrNoPlan, execution.ID)
+	}
+	analysis := planAnalysis(execution)
+	if analysis == nil {
+		return PlanDiffSide{}, fmt.Errorf("%w: the plan of execution %d couldn't be analyzed", ErrNoPlan, execution.ID)
+	}
+
+	side := PlanDiffSide{
+		ExecutionID: execution.ID,
+		RunID:       execution.RunID,
+		QueryID:     execution.QueryID,
+		Engine:      execution.Engine,
+		PlanType:    models.PlanExplain,
+		Analysis:    analysis,
+	}
+	if execution.PlanType != nil {
+		side.PlanType = *execution.PlanType
+	}
+	if execution.RunID != nil {
+		run, err := s.runRepo.GetByIDWithoutAssociations(*execution.RunID)
+		if err != nil {
+			return PlanDiffSide{}, err
+		}
+		side.TableFormat = run.Config.TableFormat
+	}
+	return side, nil
+}
+
+func diffPlans(base, candidate PlanDiffSide) *PlanDiff {
+	diff := &PlanDiff{
+		Base:      base,
+		Candidate: candidate,
+		Changes:   []string{},
+		Operators: []OperatorChange{},
+		Tables:    []TableDiff{},
+		Joins:     []JoinDiff{},
+	}
+	a, b := base.Analysis, candidate.Analysis
+	diff.Changes = appendChange(diff.Changes, "estimated_rows", formatEstimate(a.EstimatedRows), formatEstimate(b.EstimatedRows))
+	diff.Changes = appendChange(diff.Changes, "actual_rows", formatCount(a.ActualRows), formatCount(b.ActualRows))
+
+	baseOps, candidateOps := countOperators(a.Root), countOperators(b.Root)
+	names := make(map[string]bool)
+	for name := range baseOps {
+		names[name] = true
+	}
+	for name := range candidateOps {
+		names[name] = true
+	}
+	for name := range names {
+		if baseOps[name] != candidateOps[name] {
+			diff.Operators = append(diff.Operators, OperatorChange{Operator: name, Base: baseOps[name], Candidate: candidateOps[name]})
+		}
+	}
+	sort.Slice(diff.Operators, func(i, j int) bool { return diff.Operators[i].Operator < diff.Operators[j].Operator })
+
+	diff.Tables = diffTables(base, candidate)
+
+	for i := 0; i < len(a.Joins) || i < len(b.Joins); i++ {
+		join := JoinDiff{Index: i, Changes: []string{}}
+		if i < len(a.Joins) {
+			join.Base = &a.Joins[i]
+		}
+		if i < len(b.Joins) {
+			join.Candidate = &b.Joins[i]
+		}
+		switch {
+		case join.Base == nil:
+			join.Changes = append(join.Changes, "only in candidate")
+		case join.Candidate == nil:
+			join.Changes = append(join.Changes, "only in base")
+		default:
+			join.Changes = appendChange(join.Changes, "type", join.Base.Type, join.Candidate.Type)
+			join.Changes = appendChange(join.Changes, "distribution", join.Base.Distribution, join.Candidate.Distribution)
+			join.Changes = appendChange(join.Changes, "estimated_rows", formatEstimate(join.Base.EstimatedRows), formatEstimate(join.Candidate.EstimatedRows))
+			join.Changes = appendChange(join.Changes, "actual_rows", formatCount(join.Base.ActualRows), formatCount(join.Candidate.ActualRows))
+		}
+		diff.Joins = append(diff.Joins, join)
+	}
+	return diff
+}
+
+// diffTables matches the scans of both plans by table, in the order each
+// plan scans them when a table is scanned more than once
+func diffTables(base, candidate PlanDiffSide) []TableDiff {
+	type scans struct {
+		base, candidate []*models.ScannedTable
+	}
+	byTable := make(map[string]*scans)
+	var order []string
+	add := func(side PlanDiffSide, isBase bool) {
+		for i := range side.Analysis.Tables {
+			table := &side.Analysis.Tables[i]
+			key := tableKey(table, side.TableFormat)
+			entry, ok := byTable[key]
+			if !ok {
+				entry = &scans{}
+				byTable[key] = entry
+				order = append(order, key)
+			}
+			if isBase {
+				entry.base = append(entry.base, table)
+			} else {
+				entry.candidate = append(entry.candidate, table)
+			}
+		}
+	}
+	add(base, true)
+	add(candidate, false)
+
+	diffs := make([]TableDiff, 0, len(order))
+	for _, key := range order {
+		entry := byTable[key]
+		for i := 0; i < len(entry.base) || i < len(entry.candidate); i++ {
+			diff := TableDiff{Table: key, Changes: []string{}}
+			if i < len(entry.base) {
+				diff.Base = entry.base[i]
+			}
+			if i < len(entry.candidate) {
+				diff.Candidate = entry.candidate[i]
+			}
+			switch {
+			case diff.Base == nil:
+				diff.Changes = append(diff.Changes, "only in candidate")
+			case diff.Candidate == nil:
+				diff.Changes = append(diff.Changes, "only in base")
+			default:
+				diff.Changes = diffScans(diff.Changes, diff.Base, diff.Candidate)
+			}
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs
+}
+
+func diffScans(changes []string, a, b *models.ScannedTable) []string {
+	changes = appendChange(changes, "predicate_pushdown", a.PredicatePushdown, b.PredicatePushdown)
+	changes = appendChange(changes, "constraint", a.Constraint, b.Constraint)
+	changes = appendChange(changes, "filter_predicate", a.FilterPredicate, b.FilterPredicate)
+	changes = appendChange(changes, "partitions_read", formatCount(a.PartitionsRead), formatCount(b.PartitionsRead))
+	changes = appendChange(changes, "partitions_total", formatCount(a.PartitionsTotal), formatCount(b.PartitionsTotal))
+	changes = appendChange(changes, "files_read", formatCount(a.FilesRead), formatCount(b.FilesRead))
+	changes = appendChange(changes, "splits", formatCount(a.Splits), formatCount(b.Splits))
+	changes = appendChange(changes, "estimated_rows", formatEstimate(a.EstimatedRows), formatEstimate(b.EstimatedRows))
+	changes = appendChange(changes, "actual_rows", formatCount(a.ActualRows), formatCount(b.ActualRows))
+	changes = appendChange(changes, "physical_input_bytes", formatCount(a.PhysicalInputBytes), formatCount(b.PhysicalInputBytes))
+	return changes
+}
+
+// tableKey names a table independently of where it's stored: without its
+// catalog or schema, or a suffix naming the table format or catalog
+func tableKey(table *models.ScannedTable, tableFormat string) string {
+	name := strings.ToLower(table.Table)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, suffix := range []string{tableFormat, table.Catalog} {
+		if suffix != "" {
+			name = strings.TrimSuffix(name, "_"+strings.ToLower(suffix))
+		}
+	}
+	return name
+}
+
+func countOperators(node *models.PlanNode) map[string]int {
+	counts := make(map[string]int)
+	var walk func(node *models.PlanNode)
+	walk = func(node *models.PlanNode) {
+		if node == nil {
+			return
+		}
+		counts[node.Name]++
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(node)
+	return counts
+}
+
+// appendChange records a field that differs as "field: base -> candidate",
+// writing a value one side doesn't have as "-"
+func appendChange(changes []string, field, base, candidate string) []string {
+	if base == candidate {
+		return changes
+	}
+	if base == "" {
+		base = "-"
+	}
+	if candidate == "" {
+		candidate = "-"
+	}
+	return append(changes, fmt.Sprintf("%s: %s -> %s", field, base, candidate))
+}
+
+func formatCount(v *int64) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d", *v)
+}
+
+func formatEstimate(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.0f", *v)
+}
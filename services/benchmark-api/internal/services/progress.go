@@ -0,0 +1,213 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"benchmark-api/internal/models"
+)
+
+// BenchmarkStatus describes the progress of a benchmark's current run
+type BenchmarkStatus struct {
+	BenchmarkID uint             `json:"benchmark_id"`
+	RunID       *uint            `json:"run_id"`
+	Status      string           `json:"status"`
+	Progress    float64          `json:"progress"` // percent of executions finished
+	Total       int              `json:"total"`
+	Completed   int              `json:"completed"`
+	Failed      int              `json:"failed"`
+	Running     int              `json:"running"`
+	Pending     int              `json:"pending"`
+	StartTime   *time.Time       `json:"start_time"`
+	EndTime     *time.Time       `json:"end_time"`
+	ElapsedMs   int64            `json:"elapsed_ms"`
+	ETAMs       *int64           `json:"eta_ms"`
+	Engines     []EngineProgress `json:"engines"`
+}
+
+// EngineProgress is the per-engine breakdown of a run
+type EngineProgress struct {
+	Engine       string        `json:"engine"`
+	Total        int           `json:"total"`
+	Completed    int           `json:"completed"`
+	Failed       int           `json:"failed"`
+	Running      int           `json:"running"`
+	Pending      int           `json:"pending"`
+	AvgLatencyMs *float64      `json:"avg_latency_ms"`
+	ETAMs        *int64        `json:"eta_ms"`
+	CurrentQuery *CurrentQuery `json:"current_query,omitempty"`
+}
+
+// CurrentQuery is the query an engine is executing right now
+type CurrentQuery struct {
+	QueryID     uint      `json:"query_id"`
+	Name        string    `json:"name"`
+	ExecutionID uint      `json:"execution_id"`
+	StartTime   time.Time `json:"start_time"`
+	ElapsedMs   int64     `json:"elapsed_ms"`
+}
+
+// computeProgress derives a run's progress from its persisted executions.
+// Executions are only written once the runner picks a query up, so anything
+// without a row yet counts as pending. The ETA assumes each engine's
+// remaining queries take as long as its completed ones did on average, and
+// since engines run in parallel the run's ETA is the slowest engine's.
+func computeProgress(benchmark *models.Benchmark, run *models.BenchmarkRun, executions []models.QueryExecution, now time.Time) *BenchmarkStatus {
+	status := &BenchmarkStatus{
+		BenchmarkID: benchmark.ID,
+		RunID:       &run.ID,
+		Status:      run.Status,
+		StartTime:   run.StartTime,
+		EndTime:     run.EndTime,
+	}
+	if run.StartTime != nil {
+		end := now
+		if run.EndTime != nil {
+			end = *run.EndTime
+		}
+		status.ElapsedMs = end.Sub(*run.StartTime).Milliseconds()
+	}
+
+	queryNames := make(map[uint]string, len(benchmark.Queries))
+	for _, query := range benchmark.Queries {
+		queryNames[query.ID] = query.Name
+	}
+
+	engines := make([]string, 0, len(run.Config.Engines))
+	for _, engine := range run.Config.Engines {
+		engines = append(engines, engine.Name)
+	}
+	if len(engines) == 0 {
+		engines = benchmark.Engines
+	}
+
+	byEngine := make(map[string][]models.QueryExecution, len(engines))
+	for _, execution := range executions {
+		byEngine[execution.Engine] = append(byEngine[execution.Engine], execution)
+	}
+
+	perEngine := expectedExecutions(benchmark, run)
+	for _, engine := range engines {
+		progress := EngineProgress{Engine: engine, Total: perEngine}
+
+		var latencySum int64
+		var latencyCount int
+		var currentElapsed int64
+		for _, execution := range byEngine[engine] {
+			switch execution.Status {
+			case models.StatusCompleted:
+				progress.Completed++
+				if execution.ExecutionTimeMs != nil {
+					latencySum += *execution.ExecutionTimeMs
+					latencyCount++
+				}
+			case models.StatusFailed, models.StatusCancelled, models.StatusTimedOut:
+				progress.Failed++
+			case models.StatusRunning:
+				progress.Running++
+				if execution.StartTime != nil {
+					currentElapsed = now.Sub(*execution.StartTime).Milliseconds()
+					progress.CurrentQuery = &CurrentQuery{
+						QueryID:     execution.QueryID,
+						Name:        queryNames[execution.QueryID],
+						ExecutionID: execution.ID,
+						StartTime:   *execution.StartTime,
+						ElapsedMs:   currentElapsed,
+					}
+				}
+			}
+		}
+		if perEngine == 0 {
+			// A timed workload records however many executions fit
+			progress.Total = progress.Completed + progress.Failed + progress.Running
+		}
+		progress.Pending = progress.Total - progress.Completed - progress.Failed - progress.Running
+		if progress.Pending < 0 {
+			progress.Pending = 0
+		}
+
+		if latencyCount > 0 {
+			avg := float64(latencySum) / float64(latencyCount)
+			progress.AvgLatencyMs = &avg
+			if run.Status == models.StatusRunning && perEngine > 0 {
+				// Concurrent virtual users work through the pending
+				// executions in parallel
+				parallel := progress.Running
+				if parallel < 1 {
+					parallel = 1
+				}
+				remaining := int64(avg*float64(progress.Pending+progress.Running)/float64(parallel)) - currentElapsed
+				if remaining < 0 {
+					remaining = 0
+				}
+				progress.ETAMs = &remaining
+				if status.ETAMs == nil || remaining > *status.ETAMs {
+					status.ETAMs = &remaining
+				}
+			}
+		}
+
+		status.Total += progress.Total
+		status.Completed += progress.Completed
+		status.Failed += progress.Failed
+		status.Running += progress.Running
+		status.Pending += progress.Pending
+		status.Engines = append(status.Engines, progress)
+	}
+
+	if planned := plannedDuration(run); planned > 0 {
+		// Timed workloads progress with the clock rather than executions
+		elapsed := time.Duration(status.ElapsedMs) * time.Millisecond
+		status.Progress = math.Min(float64(elapsed)/float64(planned)*100, 100)
+		if run.Status == models.StatusRunning {
+			remaining := (planned - elapsed).Milliseconds()
+			if remaining < 0 {
+				remaining = 0
+			}
+			status.ETAMs = &remaining
+		}
+	} else if status.Total > 0 {
+		status.Progress = float64(status.Completed+status.Failed) / float64(status.Total) * 100
+	}
+
+	return status
+}
+
+// plannedDuration is how long a timed workload runs, over all of its
+// concurrency levels or arrival rates, or zero for workloads bound by
+// iterations
+func plannedDuration(run *models.BenchmarkRun) time.Duration {
+	workload := run.Config.Workload
+	if workload.DurationSeconds <= 0 {
+		return 0
+	}
+	switch workload.Mode {
+	case models.WorkloadConcurrent:
+		return time.Duration(len(workload.ConcurrencyLevels)*workload.DurationSeconds) * time.Second
+	case models.WorkloadOpenLoop:
+		return time.Duration(len(workload.ArrivalRates)*workload.DurationSeconds) * time.Second
+	default:
+		return 0
+	}
+}
+
+// expectedExecutions is the number of executions a run records per engine.
+// Warm-up iterations aren't recorded so they don't count. It's zero for timed
+// workloads, which can't tell in advance.
+func expectedExecutions(benchmark *models.Benchmark, run *models.BenchmarkRun) int {
+	if plannedDuration(run) > 0 {
+		return 0
+	}
+	if workload := run.Config.Workload; workload.Mode == models.WorkloadConcurrent {
+		users := 0
+		for _, level := range workload.ConcurrencyLevels {
+			users += level
+		}
+		return len(benchmark.Queries) * workload.Iterations * users
+	}
+	iterations := run.Config.MeasuredIterations
+	if iterations < 1 {
+		iterations = measuredIterations(benchmark)
+	}
+	return len(benchmark.Queries) * iterations
+}
@@ -1,23 +1,358 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 	"benchmark-api/internal/config"
+	"benchmark-api/internal/models"
 	"benchmark-api/internal/repository"
+	"benchmark-api/pkg/metrics"
+	"benchmark-api/pkg/queryclient"
 )
 
+var ErrInvalidQuery = errors.New("invalid query")
+
+// queryTypes and queryComplexities are the values the queries table allows
+var (
+	queryTypes        = []string{"select", "aggregation", "join", "window"}
+	queryComplexities = []string{"simple", "medium", "complex"}
+)
+
+// readStatements are the statements a benchmark query may start with
+var readStatements = []string{"select", "with", "values", "table"}
+
+// queryEngines are the engines a query can be executed on
+var queryEngines = []string{"trino", "presto", "starrocks"}
+
+// ExecuteQueryRequest runs a stored query once on an engine. Catalog
+// defaults to the table format of the query's benchmark, as in benchmark
+// runs. MaxRows limits the rows sent back, defaulting to the
+// query-service's own limit.
+type ExecuteQueryRequest struct {
+	Engine  string `json:"engine" binding:"required"`
+	Catalog string `json:"catalog,omitempty"`
+	MaxRows *int64 `json:"max_rows,omitempty"`
+	Timeout string `json:"timeout,omitempty"` // e.g. "30s", defaulting to the query-service's
+}
+
+// QueryExecutionResult is a recorded ad-hoc execution along with the rows it
+// returned
+type QueryExecutionResult struct {
+	Execution models.QueryExecution `json:"execution"`
+	Columns   []queryclient.Column  `json:"columns"`
+	Rows      [][]interface{}       `json:"rows"`
+	Truncated bool                  `json:"truncated"`
+}
+
 type QueryService struct {
-	repo   *repository.QueryRepository
-	config *config.Config
-	logger *logrus.Logger
+	repo          *repository.QueryRepository
+	benchmarkRepo *repository.BenchmarkRepository
+	executionRepo *repository.ExecutionRepository
+	client        *queryclient.Client
+	config        *config.Config
+	logger        *logrus.Logger
 }
 
-func NewQueryService(repo *repository.QueryRepository, config *config.Config, logger *logrus.Logger) *QueryService {
+func NewQueryService(repo *repository.QueryRepository, benchmarkRepo *repository.BenchmarkRepository, executionRepo *repository.ExecutionRepository, client *queryclient.Client, config *config.Config, logger *logrus.Logger) *QueryService {
 	return &QueryService{
-		repo:   repo,
-		config: config,
-		logger: logger,
+		repo:          repo,
+		benchmarkRepo: benchmarkRepo,
+		executionRepo: executionRepo,
+		client:        client,
+		config:        config,
+		logger:        logger,
+	}
+}
+
+func (s *QueryService) ListEngines(ctx context.Context) ([]queryclient.EngineInfo, error) {
+	return s.client.ListEngines(ctx)
+}
+
+// GetEngineStatus returns the query-service's latest health check of an
+// engine, or has it check the engine now if refresh is set
+func (s *QueryService) GetEngineStatus(ctx context.Context, engine string, refresh bool) (*queryclient.EngineHealth, error) {
+	return s.client.EngineStatus(ctx, engine, refresh)
+}
+
+func (s *QueryService) CreateQuery(query *models.Query) error {
+	if err := s.validateQuery(query); err != nil {
+		return err
+	}
+	s.logger.WithFields(logrus.Fields{
+		"benchmark_id": query.BenchmarkID,
+		"query_name":   query.Name,
+	}).Info("Creating query")
+	return s.repo.Create(query)
+}
+
+func (s *QueryService) GetQueryByID(id uint) (*models.Query, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *QueryService) ListQueries(filters map[string]interface{}, limit, offset int) ([]models.Query, error) {
+	return s.repo.List(filters, limit, offset)
+}
+
+// UpdateQuery replaces a query's fields, keeping when it was created
+func (s *QueryService) UpdateQuery(query *models.Query) error {
+	existing, err := s.repo.GetByID(query.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.validateQuery(query); err != nil {
+		return err
+	}
+	query.CreatedAt = existing.CreatedAt
+	return s.repo.Update(query)
+}
+
+func (s *QueryService) DeleteQuery(id uint) error {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+// ExecuteQuery runs a stored query once on the requested engine through the
+// query-service and records it as a QueryExecution outside any benchmark
+// run. A query that fails on the engine is recorded and returned like any
+// other; only failing to record it is an error.
+func (s *QueryService) ExecuteQuery(ctx context.Context, id uint, req ExecuteQueryRequest) (*QueryExecutionResult, error) {
+	if !contains(queryEngines, req.Engine) {
+		return nil, fmt.Errorf("%w: engine must be one of %s", ErrInvalidQuery, strings.Join(queryEngines, ", "))
+	}
+	if req.MaxRows != nil && *req.MaxRows < 0 {
+		return nil, fmt.Errorf("%w: max_rows must not be negative", ErrInvalidQuery)
+	}
+	if req.Timeout != "" {
+		if _, err := time.ParseDuration(req.Timeout); err != nil {
+			return nil, fmt.Errorf("%w: invalid timeout %q", ErrInvalidQuery, req.Timeout)
+		}
+	}
+
+	query, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	benchmark, err := s.benchmarkRepo.GetByID(query.BenchmarkID)
+	if err != nil {
+		return nil, err
+	}
+	catalog := req.Catalog
+	if catalog == "" {
+		catalog = benchmark.TableFormat
+	}
+
+	log := s.logger.WithFields(logrus.Fields{
+		"query_id": query.ID,
+		"engine":   req.Engine,
+		"catalog":  catalog,
+	})
+
+	start := time.Now()
+	execution := &models.QueryExecution{
+		QueryID:     query.ID,
+		Engine:      req.Engine,
+		Iteration:   1,
+		Concurrency: 1,
+		VirtualUser: 1,
+		Status:      models.StatusRunning,
+		StartTime:   &start,
+	}
+	if err := s.executionRepo.Create(execution); err != nil {
+		return nil, fmt.Errorf("failed to record query execution: %w", err)
+	}
+
+	resp, err := s.client.Execute(ctx, queryclient.ExecuteRequest{
+		Handle:  fmt.Sprintf("query-%d-execution-%d", query.ID, execution.ID),
+		Engine:  req.Engine,
+		SQL:     query.SQLQuery,
+		Catalog: catalog,
+		Timeout: req.Timeout,
+		MaxRows: req.MaxRows,
+	})
+
+	end := time.Now()
+	execution.EndTime = &end
+	result := &QueryExecutionResult{}
+	if err != nil {
+		msg := err.Error()
+		execution.Status = models.StatusFailed
+		switch {
+		case ctx.Err() != nil:
+			execution.Status = models.StatusCancelled
+		case resp != nil && (resp.Status == models.StatusCancelled || resp.Status == models.StatusTimedOut):
+			execution.Status = resp.Status
+		}
+		execution.ErrorMessage = &msg
+		log.WithError(err).WithField("status", execution.Status).Warn("Query execution failed")
+	} else {
+		execution.Status = models.StatusCompleted
+		execution.ExecutionTimeMs = &resp.ExecutionTimeMs
+		execution.ServiceTimeMs = &resp.ExecutionTimeMs
+		execution.RowsReturned = &resp.RowsReturned
+		execution.RowsProcessed = &resp.RowsReturned
+		applyQueryStats(execution, resp.Stats)
+		if resp.Checksum != "" {
+			execution.ResultChecksum = &resp.Checksum
+		}
+		metrics.RecordQueryExecution(req.Engine, benchmark.TableFormat, query.QueryType, float64(resp.ExecutionTimeMs)/1000)
+
+		result.Columns = resp.Columns
+		result.Rows = resp.Rows
+		result.Truncated = resp.Truncated
+		log.WithField("execution_time_ms", resp.ExecutionTimeMs).Info("Query executed")
+	}
+
+	if err := s.executionRepo.Update(execution); err != nil {
+		return nil, fmt.Errorf("failed to update query execution: %w", err)
+	}
+	result.Execution = *execution
+	return result, nil
+}
+
+// ListQueryExecutions returns a page of a query's executions, newest first,
+// whether they ran in a benchmark run or ad hoc
+func (s *QueryService) ListQueryExecutions(id uint, filters map[string]interface{}, limit, offset int) ([]models.QueryExecution, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	return s.executionRepo.ListByQueryID(id, filters, limit, offset)
+}
+
+// validateQuery checks a query against the queries table's constraints and
+// that its SQL is a single read statement, which it's trimmed to
+func (s *QueryService) validateQuery(query *models.Query) error {
+	query.Name = strings.TrimSpace(query.Name)
+	if query.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidQuery)
+	}
+	if !contains(queryTypes, query.QueryType) {
+		return fmt.Errorf("%w: query_type must be one of %s", ErrInvalidQuery, strings.Join(queryTypes, ", "))
+	}
+	if !contains(queryComplexities, query.Complexity) {
+		return fmt.Errorf("%w: complexity must be one of %s", ErrInvalidQuery, strings.Join(queryComplexities, ", "))
+	}
+
+	statement, err := singleStatement(query.SQLQuery)
+	if err != nil {
+		return fmt.Errorf("%w: sql_query %v", ErrInvalidQuery, err)
 	}
+	query.SQLQuery = statement
+
+	if query.BenchmarkID == 0 {
+		return fmt.Errorf("%w: benchmark_id is required", ErrInvalidQuery)
+	}
+	if _, err := s.benchmarkRepo.GetByID(query.BenchmarkID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: benchmark %d does not exist", ErrInvalidQuery, query.BenchmarkID)
+		}
+		return err
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
-// TODO: Implement query service methods
+// singleStatement checks that sql holds exactly one read statement with
+// balanced parentheses and terminated strings and comments, and returns it
+// without a trailing semicolon, which Trino and Presto reject
+func singleStatement(sql string) (string, error) {
+	var (
+		depth      int
+		terminator = -1
+		firstWord  = -1
+	)
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case strings.HasPrefix(sql[i:], "--"):
+			if j := strings.IndexByte(sql[i:], '\n'); j >= 0 {
+				i += j + 1
+			} else {
+				i = len(sql)
+			}
+			continue
+		case strings.HasPrefix(sql[i:], "/*"):
+			j := strings.Index(sql[i+2:], "*/")
+			if j < 0 {
+				return "", errors.New("has an unterminated comment")
+			}
+			i += j + 4
+			continue
+		case terminator >= 0 && !isSpace(c):
+			return "", errors.New("must be a single statement")
+		case c == '\'' || c == '"' || c == '`':
+			// Quotes are escaped by doubling them
+			j := i + 1
+			for {
+				k := strings.IndexByte(sql[j:], c)
+				if k < 0 {
+					return "", fmt.Errorf("has an unterminated %c quote", c)
+				}
+				j += k + 1
+				if j < len(sql) && sql[j] == c {
+					j++
+					continue
+				}
+				break
+			}
+			i = j
+			continue
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return "", errors.New("has unbalanced parentheses")
+			}
+		case c == ';':
+			terminator = i
+		case firstWord < 0 && isLetter(c):
+			firstWord = i
+		}
+		i++
+	}
+	if depth != 0 {
+		return "", errors.New("has unbalanced parentheses")
+	}
+	if firstWord < 0 {
+		return "", errors.New("is required")
+	}
+
+	end := firstWord
+	for end < len(sql) && isLetter(sql[end]) {
+		end++
+	}
+	if keyword := strings.ToLower(sql[firstWord:end]); !contains(readStatements, keyword) {
+		last := len(readStatements) - 1
+		allowed := strings.ToUpper(strings.Join(readStatements[:last], ", ")) + " or " + strings.ToUpper(readStatements[last])
+		return "", fmt.Errorf("must be a %s statement, not %s", allowed, strings.ToUpper(keyword))
+	}
+
+	if terminator >= 0 {
+		sql = sql[:terminator]
+	}
+	return strings.TrimSpace(sql), nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,93 @@
+package services
+
+import "testing"
+
+func TestSingleStatement(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+		err  string
+	}{
+		{
+			name: "select",
+			sql:  "  SELECT * FROM orders  \n",
+			want: "SELECT * FROM orders",
+		},
+		{
+			name: "trailing semicolon",
+			sql:  "SELECT 1;\n",
+			want: "SELECT 1",
+		},
+		{
+			name: "trailing semicolon and comments",
+			sql:  "SELECT 1; -- done\n/* ; */",
+			want: "SELECT 1",
+		},
+		{
+			name: "semicolons in strings and identifiers",
+			sql:  `SELECT ';', 'it''s; fine', "a;b", ` + "`c;d`" + ` FROM t;`,
+			want: `SELECT ';', 'it''s; fine', "a;b", ` + "`c;d`" + ` FROM t`,
+		},
+		{
+			name: "semicolons in comments",
+			sql:  "SELECT 1 -- first; second\nFROM t /* ; DROP TABLE t; */",
+			want: "SELECT 1 -- first; second\nFROM t /* ; DROP TABLE t; */",
+		},
+		{
+			name: "leading comments",
+			sql:  "-- DELETE FROM t\n/* INSERT */ SELECT 1",
+			want: "-- DELETE FROM t\n/* INSERT */ SELECT 1",
+		},
+		{
+			name: "parenthesized select",
+			sql:  "(SELECT a FROM t) UNION ALL (SELECT a FROM u)",
+			want: "(SELECT a FROM t) UNION ALL (SELECT a FROM u)",
+		},
+		{
+			name: "with",
+			sql:  "WITH recent AS (SELECT * FROM orders WHERE o_orderdate > DATE '2024-01-01') SELECT count(*) FROM recent;",
+			want: "WITH recent AS (SELECT * FROM orders WHERE o_orderdate > DATE '2024-01-01') SELECT count(*) FROM recent",
+		},
+		{
+			name: "values",
+			sql:  "values (1, 'a')",
+			want: "values (1, 'a')",
+		},
+		{
+			name: "parentheses in strings",
+			sql:  "SELECT '(' FROM t",
+			want: "SELECT '(' FROM t",
+		},
+		{name: "multiple statements", sql: "SELECT 1; SELECT 2", err: "must be a single statement"},
+		{name: "multiple semicolons", sql: "SELECT 1;;", err: "must be a single statement"},
+		{name: "statement after a comment", sql: "SELECT 1; -- done\nDROP TABLE t", err: "must be a single statement"},
+		{name: "unterminated string", sql: "SELECT 'abc FROM t", err: "has an unterminated ' quote"},
+		{name: "unterminated escaped string", sql: "SELECT 'it''s", err: "has an unterminated ' quote"},
+		{name: "unterminated identifier", sql: `SELECT "abc FROM t`, err: `has an unterminated " quote`},
+		{name: "unterminated comment", sql: "SELECT 1 /* FROM t", err: "has an unterminated comment"},
+		{name: "unbalanced parentheses", sql: "SELECT (1", err: "has unbalanced parentheses"},
+		{name: "closing parenthesis first", sql: "SELECT 1) + (2", err: "has unbalanced parentheses"},
+		{name: "empty", sql: " ; -- nothing", err: "is required"},
+		{name: "delete", sql: "DELETE FROM orders", err: "must be a SELECT, WITH, VALUES or TABLE statement, not DELETE"},
+		{name: "insert after a comment", sql: "/* load */ insert INTO t SELECT 1", err: "must be a SELECT, WITH, VALUES or TABLE statement, not INSERT"},
+		{name: "parenthesized delete", sql: "(DELETE FROM t)", err: "must be a SELECT, WITH, VALUES or TABLE statement, not DELETE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := singleStatement(tt.sql)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("singleStatement(%q) error = %v, want %q", tt.sql, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("singleStatement(%q) error = %v", tt.sql, err)
+			}
+			if got != tt.want {
+				t.Errorf("singleStatement(%q) = %q, want %q", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
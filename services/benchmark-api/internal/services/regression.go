@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"benchmark-api/internal/config"
+	"benchmark-api/internal/models"
+	"benchmark-api/internal/repository"
+	"benchmark-api/pkg/stats"
+)
+
+// Statistical tests regressions can be detected with
+const (
+	TestWelch       = "welch"
+	TestMannWhitney = "mann_whitney"
+)
+
+type RegressionService struct {
+	repo          *repository.RegressionRepository
+	benchmarkRepo *repository.BenchmarkRepository
+	runRepo       *repository.RunRepository
+	config        config.RegressionConfig
+	logger        *logrus.Logger
+}
+
+func NewRegressionService(repo *repository.RegressionRepository, benchmarkRepo *repository.BenchmarkRepository, runRepo *repository.RunRepository, cfg config.RegressionConfig, logger *logrus.Logger) *RegressionService {
+	if cfg.Test != TestWelch && cfg.Test != TestMannWhitney {
+		logger.WithField("test", cfg.Test).Warn("Unknown regression test, using welch")
+		cfg.Test = TestWelch
+	}
+	if cfg.BaselineRuns < 1 {
+		cfg.BaselineRuns = 1
+	}
+	return &RegressionService{
+		repo:          repo,
+		benchmarkRepo: benchmarkRepo,
+		runRepo:       runRepo,
+		config:        cfg,
+		logger:        logger,
+	}
+}
+
+func (s *RegressionService) ListRegressions(benchmarkID uint, filters map[string]interface{}, limit, offset int) ([]models.Regression, error) {
+	return s.repo.GetByBenchmarkID(benchmarkID, filters, limit, offset)
+}
+
+// RegressionCheck is the outcome of checking a run for regressions
+type RegressionCheck struct {
+	Regressions    []models.Regression
+	BaselineRunIDs []uint
+	// InsufficientSamples counts the queries on an engine and load level
+	// that couldn't be tested for lack of samples on either side
+	InsufficientSamples int
+}
+
+// DetectRun compares every query of a run on every engine against the
+// baseline and records the ones that regressed, replacing any recorded
+// before. The baseline is the benchmark's pinned run, or else the pooled
+// samples of its last completed runs before this one; without a baseline
+// nothing is recorded. Queries with fewer than two samples on either side
+// can't be tested, which is logged and counted in the check.
+func (s *RegressionService) DetectRun(runID uint) (*RegressionCheck, error) {
+	run, err := s.runRepo.GetByID(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	baselines, err := s.baselinesFor(run)
+	if err != nil {
+		return nil, err
+	}
+	if len(baselines) == 0 {
+		s.logger.WithField("run_id", run.ID).Debug("No baseline run to detect regressions against")
+		return &RegressionCheck{}, s.repo.ReplaceForRun(run.ID, nil)
+	}
+
+	check := s.detect(run, baselines)
+	if err := s.repo.ReplaceForRun(run.ID, check.Regressions); err != nil {
+		return nil, err
+	}
+
+	log := s.logger.WithFields(logrus.Fields{
+		"benchmark_id":     run.BenchmarkID,
+		"run_id":           run.ID,
+		"baseline_run_ids": check.BaselineRunIDs,
+		"regressions":      len(check.Regressions),
+	})
+	if check.InsufficientSamples > 0 {
+		log.WithField("insufficient_samples", check.InsufficientSamples).
+			Warn("Too few samples to test some queries for regressions, measure at least two iterations per query")
+	}
+	log.Info("Checked benchmark run for regressions")
+	return check, nil
+}
+
+// baselinesFor returns the pinned baseline run, or else the last
+// BaselineRuns completed runs before run, newest first
+func (s *RegressionService) baselinesFor(run *models.BenchmarkRun) ([]models.BenchmarkRun, error) {
+	benchmark, err := s.benchmarkRepo.GetByID(run.BenchmarkID)
+	if err != nil {
+		return nil, err
+	}
+	if pinned := benchmark.BaselineRunID; pinned != nil && *pinned != run.ID {
+		baseline, err := s.runRepo.GetByID(*pinned)
+		if err == nil && baseline.BenchmarkID == run.BenchmarkID {
+			return []models.BenchmarkRun{*baseline}, nil
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		s.logger.WithFields(logrus.Fields{
+			"benchmark_id":    run.BenchmarkID,
+			"baseline_run_id": *pinned,
+		}).Warn("Pinned baseline run not found, using the last completed runs")
+	}
+
+	return s.runRepo.GetCompletedBefore(run.BenchmarkID, run.ID, s.config.BaselineRuns)
+}
+
+// detect flags the queries whose median latency grew by more than the
+// threshold with a p-value below alpha. Only completed iterations count, and
+// the tests need at least two of them on each side. The baseline's samples
+// are pooled across its runs, and the newest run is recorded as the
+// regression's baseline.
+func (s *RegressionService) detect(run *models.BenchmarkRun, baselines []models.BenchmarkRun) *RegressionCheck {
+	check := &RegressionCheck{}
+	var pooled []models.QueryExecution
+	for _, baseline := range baselines {
+		check.BaselineRunIDs = append(check.BaselineRunIDs, baseline.ID)
+		pooled = append(pooled, baseline.Executions...)
+	}
+	current := latenciesByQuery(run.Executions)
+	previous := latenciesByQuery(pooled)
+
+	for k, samples := range current {
+		baselineSamples, ok := previous[k]
+		if !ok {
+			continue
+		}
+		if len(samples) < 2 || len(baselineSamples) < 2 {
+			check.InsufficientSamples++
+			continue
+		}
+
+		baselineMedian := stats.Percentile(baselineSamples, 50)
+		median := stats.Percentile(samples, 50)
+		if baselineMedian <= 0 {
+			continue
+		}
+		magnitude := median/baselineMedian - 1
+		if magnitude <= s.config.Threshold {
+			continue
+		}
+
+		var p float64
+		switch s.config.Test {
+		case TestMannWhitney:
+			p = stats.MannWhitneyU(baselineSamples, samples)
+		default:
+			p = stats.WelchTTest(baselineSamples, samples)
+		}
+		if p >= s.config.Alpha {
+			continue
+		}
+
+		check.Regressions = append(check.Regressions, models.Regression{
+			BenchmarkID:      run.BenchmarkID,
+			RunID:            run.ID,
+			BaselineRunID:    baselines[0].ID,
+			QueryID:          k.queryID,
+			Engine:           k.engine,
+			Concurrency:      k.concurrency,
+			ArrivalRate:      k.arrivalRate,
+			Test:             s.config.Test,
+			BaselineMedianMs: baselineMedian,
+			MedianMs:         median,
+			Magnitude:        magnitude,
+			PValue:           p,
+		})
+	}
+	return check
+}
+
+// regressionKey identifies a query on an engine under one load level. Runs
+// of the same benchmark share query IDs.
+type regressionKey struct {
+	queryID     uint
+	engine      string
+	concurrency int
+	arrivalRate float64
+}
+
+// latenciesByQuery collects the latencies of the completed executions of
+// each query on each engine and load level
+func latenciesByQuery(executions []models.QueryExecution) map[regressionKey][]float64 {
+	latencies := make(map[regressionKey][]float64)
+	for _, execution := range executions {
+		if execution.Status != models.StatusCompleted || execution.ExecutionTimeMs == nil {
+			continue
+		}
+		k := regressionKey{
+			queryID:     execution.QueryID,
+			engine:      execution.Engine,
+			concurrency: execution.Concurrency,
+			arrivalRate: execution.ArrivalRate,
+		}
+		latencies[k] = append(latencies[k], float64(*execution.ExecutionTimeMs))
+	}
+	return latencies
+}
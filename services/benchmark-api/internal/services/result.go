@@ -1,20 +1,264 @@
 package services
 
 import (
+	"math"
+	"sort"
+	"time"
+
 	"github.com/sirupsen/logrus"
+	"benchmark-api/internal/config"
+	"benchmark-api/internal/models"
 	"benchmark-api/internal/repository"
+	"benchmark-api/pkg/stats"
 )
 
 type ResultService struct {
-	repo   *repository.ResultRepository
-	logger *logrus.Logger
+	repo          *repository.ResultRepository
+	runRepo       *repository.RunRepository
+	executionRepo *repository.ExecutionRepository
+	queryRepo     *repository.QueryRepository
+	scoring       config.ScoringConfig
+	logger        *logrus.Logger
 }
 
-func NewResultService(repo *repository.ResultRepository, logger *logrus.Logger) *ResultService {
+func NewResultService(repo *repository.ResultRepository, runRepo *repository.RunRepository, executionRepo *repository.ExecutionRepository, queryRepo *repository.QueryRepository, scoring config.ScoringConfig, logger *logrus.Logger) *ResultService {
 	return &ResultService{
-		repo:   repo,
-		logger: logger,
+		repo:          repo,
+		runRepo:       runRepo,
+		executionRepo: executionRepo,
+		queryRepo:     queryRepo,
+		scoring:       scoring,
+		logger:        logger,
+	}
+}
+
+func (s *ResultService) GetResult(id uint) (*models.Result, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *ResultService) ListResults(filters map[string]interface{}, limit, offset int) ([]models.Result, error) {
+	return s.repo.List(filters, limit, offset)
+}
+
+func (s *ResultService) GetBenchmarkResults(benchmarkID uint) ([]models.Result, error) {
+	return s.repo.GetByBenchmarkID(benchmarkID)
+}
+
+// AggregateRun rolls the executions of a run up into one Result per engine,
+// table format and load level, replacing any results computed before. It's safe to
+// call again whenever the run's executions change.
+func (s *ResultService) AggregateRun(runID uint) ([]models.Result, error) {
+	run, err := s.runRepo.GetByID(runID)
+	if err != nil {
+		return nil, err
 	}
+
+	results := aggregateExecutions(run, run.Executions, s.scoring)
+	if err := s.repo.ReplaceForRun(run.ID, results); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"benchmark_id": run.BenchmarkID,
+		"run_id":       run.ID,
+		"results":      len(results),
+	}).Info("Aggregated benchmark run results")
+	return results, nil
 }
 
-// TODO: Implement result service methods
+// aggregateExecutions builds the results of a run. Latency statistics come
+// from the completed executions only, while every finished execution counts
+// towards the totals. Throughput is completed executions per second of the
+// wall-clock time between the group's first start and last end, which under
+// load is the level's queries per second.
+func aggregateExecutions(run *models.BenchmarkRun, executions []models.QueryExecution, scoring config.ScoringConfig) []models.Result {
+	type key struct {
+		engine      string
+		concurrency int
+		arrivalRate float64
+	}
+	byKey := make(map[key][]models.QueryExecution)
+	var keys []key
+	for _, execution := range executions {
+		k := key{execution.Engine, execution.Concurrency, execution.ArrivalRate}
+		if k.concurrency < 1 {
+			k.concurrency = 1
+		}
+		if _, ok := byKey[k]; !ok {
+			keys = append(keys, k)
+		}
+		byKey[k] = append(byKey[k], execution)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].engine != keys[j].engine {
+			return keys[i].engine < keys[j].engine
+		}
+		if keys[i].concurrency != keys[j].concurrency {
+			return keys[i].concurrency < keys[j].concurrency
+		}
+		return keys[i].arrivalRate < keys[j].arrivalRate
+	})
+
+	results := make([]models.Result, 0, len(keys))
+	for _, k := range keys {
+		runID := run.ID
+		result := models.Result{
+			BenchmarkID: run.BenchmarkID,
+			RunID:       &runID,
+			Engine:      k.engine,
+			TableFormat: run.Config.TableFormat,
+			Concurrency: k.concurrency,
+			ArrivalRate: k.arrivalRate,
+		}
+
+		var (
+			latencies           []float64
+			queryLatencies      = make(map[uint][]float64)
+			cpuSum              float64
+			cpuCount            int
+			memorySum           float64
+			memoryCount         int
+			firstStart, lastEnd *time.Time
+		)
+		for _, execution := range byKey[k] {
+			switch execution.Status {
+			case models.StatusCompleted:
+				result.SuccessfulQueries++
+				if execution.ExecutionTimeMs != nil {
+					latencies = append(latencies, float64(*execution.ExecutionTimeMs))
+					queryLatencies[execution.QueryID] = append(queryLatencies[execution.QueryID], float64(*execution.ExecutionTimeMs))
+				}
+			case models.StatusFailed, models.StatusCancelled, models.StatusTimedOut:
+				result.FailedQueries++
+			default:
+				continue
+			}
+			result.TotalQueries++
+
+			result.TotalRowsProcessed += valueOf(execution.RowsProcessed)
+			result.TotalBytesProcessed += valueOf(execution.BytesProcessed)
+			result.TotalIOReadBytes += valueOf(execution.IOReadBytes)
+			result.TotalIOWriteBytes += valueOf(execution.IOWriteBytes)
+			if execution.CPUUsage != nil {
+				cpuSum += *execution.CPUUsage
+				cpuCount++
+			}
+			if execution.MemoryUsage != nil {
+				memorySum += float64(*execution.MemoryUsage)
+				memoryCount++
+			}
+
+			if execution.StartTime != nil && (firstStart == nil || execution.StartTime.Before(*firstStart)) {
+				firstStart = execution.StartTime
+			}
+			if execution.EndTime != nil && (lastEnd == nil || execution.EndTime.After(*lastEnd)) {
+				lastEnd = execution.EndTime
+			}
+		}
+		if result.TotalQueries == 0 {
+			continue
+		}
+
+		result.ErrorRate = float64(result.FailedQueries) / float64(result.TotalQueries)
+		if cpuCount > 0 {
+			result.AvgCPUUsage = cpuSum / float64(cpuCount)
+		}
+		if memoryCount > 0 {
+			result.AvgMemoryUsage = memorySum / float64(memoryCount)
+		}
+
+		summary := stats.Summarize(latencies)
+		result.AvgExecutionTimeMs = summary.Mean
+		result.MinExecutionTimeMs = summary.Min
+		result.MaxExecutionTimeMs = summary.Max
+		result.MedianExecutionTimeMs = summary.Median
+		result.P90ExecutionTimeMs = summary.P90
+		result.P95ExecutionTimeMs = summary.P95
+		result.P99ExecutionTimeMs = summary.P99
+		result.StdDevExecutionTimeMs = summary.StdDev
+		result.CoefficientOfVariation = summary.CV
+		result.CILowerMs = summary.CILower
+		result.CIUpperMs = summary.CIUpper
+
+		if firstStart != nil && lastEnd != nil {
+			if elapsed := lastEnd.Sub(*firstStart).Seconds(); elapsed > 0 {
+				result.Throughput = float64(result.SuccessfulQueries) / elapsed
+			}
+		}
+		// An engine that can't keep up with the arrival rate builds a
+		// backlog, which stretches the window its queries complete in
+		result.Saturated = result.ArrivalRate > 0 && result.Throughput < saturationThreshold*result.ArrivalRate
+		result.EfficiencyScore = EfficiencyScore(&result, meanQueryCV(queryLatencies), scoring)
+
+		results = append(results, result)
+	}
+	return results
+}
+
+// EfficiencyScore rates a result from 0 to 100 as the weighted mean of three
+// components, each between 0 and 1:
+//
+//   - latency:     R / (R + median latency), where R is the reference
+//     latency, so a median of R scores 0.5 and faster medians approach 1
+//   - reliability: successful / total executions
+//   - stability:   1 / (1 + queryCV), where queryCV is the coefficient of
+//     variation of each query's latencies over its iterations, averaged
+//     across queries, so differences between queries don't count
+//
+// The weights and R come from ScoringConfig (EFFICIENCY_LATENCY_WEIGHT,
+// EFFICIENCY_RELIABILITY_WEIGHT, EFFICIENCY_STABILITY_WEIGHT and
+// EFFICIENCY_REFERENCE_LATENCY_MS). A result with no successful execution
+// gets no latency or stability credit. Without a queryCV, when no query
+// completed more than once, stability is left out of the mean.
+func EfficiencyScore(result *models.Result, queryCV *float64, scoring config.ScoringConfig) float64 {
+	stabilityWeight := scoring.StabilityWeight
+	if queryCV == nil && result.SuccessfulQueries > 0 {
+		stabilityWeight = 0
+	}
+	totalWeight := scoring.LatencyWeight + scoring.ReliabilityWeight + stabilityWeight
+	if totalWeight <= 0 || result.TotalQueries == 0 {
+		return 0
+	}
+
+	var latency, stability float64
+	if result.SuccessfulQueries > 0 {
+		if scoring.ReferenceLatencyMs > 0 {
+			latency = scoring.ReferenceLatencyMs / (scoring.ReferenceLatencyMs + result.MedianExecutionTimeMs)
+		}
+		if queryCV != nil {
+			stability = 1 / (1 + *queryCV)
+		}
+	}
+	reliability := float64(result.SuccessfulQueries) / float64(result.TotalQueries)
+
+	score := (scoring.LatencyWeight*latency +
+		scoring.ReliabilityWeight*reliability +
+		stabilityWeight*stability) / totalWeight * 100
+	return math.Round(score*100) / 100
+}
+
+// meanQueryCV averages the coefficient of variation of the latencies of
+// each query that completed at least twice, or returns nil if none did
+func meanQueryCV(latencies map[uint][]float64) *float64 {
+	var sum float64
+	var count int
+	for _, samples := range latencies {
+		if len(samples) < 2 {
+			continue
+		}
+		sum += stats.Summarize(samples).CV
+		count++
+	}
+	if count == 0 {
+		return nil
+	}
+	cv := sum / float64(count)
+	return &cv
+}
+
+func valueOf(v *int64) int64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -0,0 +1,638 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"benchmark-api/internal/config"
+	"benchmark-api/internal/models"
+	"benchmark-api/internal/repository"
+	"benchmark-api/pkg/metrics"
+	"benchmark-api/pkg/queryclient"
+)
+
+var (
+	ErrBenchmarkRunning = errors.New("benchmark is already running")
+	ErrBenchmarkEmpty   = errors.New("benchmark has no queries or engines")
+)
+
+// BenchmarkRunner executes benchmarks in the background. Each engine of a
+// benchmark gets its own goroutine which runs the benchmark's queries in
+// order through the query-service. Every query is first run for the
+// benchmark's warm-up iterations, which are discarded, and then once per
+// measured iteration, each recorded as its own QueryExecution against a
+// BenchmarkRun. Benchmarks with a concurrent workload instead run their
+// queries from several virtual users at once, see runConcurrent, and ones
+// with an open-loop workload issue them at target rates, see runOpenLoop.
+type BenchmarkRunner struct {
+	benchmarkRepo *repository.BenchmarkRepository
+	runRepo       *repository.RunRepository
+	executionRepo *repository.ExecutionRepository
+	client        *queryclient.Client
+	engines       config.EnginesConfig
+	checksum      config.ChecksumConfig
+	plans         config.PlanConfig
+	results       *ResultService
+	regressions   *RegressionService
+	events        *EventBroker
+	logger        *logrus.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	active map[uint]uint // benchmark ID -> run ID
+}
+
+func NewBenchmarkRunner(benchmarkRepo *repository.BenchmarkRepository, runRepo *repository.RunRepository, executionRepo *repository.ExecutionRepository, client *queryclient.Client, engines config.EnginesConfig, checksum config.ChecksumConfig, plans config.PlanConfig, results *ResultService, regressions *RegressionService, events *EventBroker, logger *logrus.Logger) *BenchmarkRunner {
+	switch plans.Capture {
+	case "none", models.PlanExplain, models.PlanAnalyze:
+	default:
+		logger.WithField("capture", plans.Capture).Warn("Unknown plan capture, capturing no plans")
+		plans.Capture = "none"
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &BenchmarkRunner{
+		benchmarkRepo: benchmarkRepo,
+		runRepo:       runRepo,
+		executionRepo: executionRepo,
+		client:        client,
+		engines:       engines,
+		checksum:      checksum,
+		plans:         plans,
+		results:       results,
+		regressions:   regressions,
+		events:        events,
+		logger:        logger,
+		ctx:           ctx,
+		cancel:        cancel,
+		active:        make(map[uint]uint),
+	}
+}
+
+// Start records a new run of the benchmark and executes it in the background
+func (r *BenchmarkRunner) Start(benchmark *models.Benchmark, triggeredBy string) (*models.BenchmarkRun, error) {
+	if len(benchmark.Queries) == 0 || len(benchmark.Engines) == 0 {
+		return nil, ErrBenchmarkEmpty
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, running := r.active[benchmark.ID]; running {
+		return nil, ErrBenchmarkRunning
+	}
+
+	now := time.Now()
+	run := &models.BenchmarkRun{
+		BenchmarkID: benchmark.ID,
+		Status:      models.StatusRunning,
+		TriggeredBy: triggeredBy,
+		Config:      r.snapshot(benchmark),
+		StartTime:   &now,
+	}
+	if err := r.runRepo.Create(run); err != nil {
+		return nil, err
+	}
+	if err := r.benchmarkRepo.UpdateStatus(benchmark.ID, models.StatusRunning); err != nil {
+		r.finishRun(run, models.StatusFailed, err)
+		return nil, err
+	}
+
+	r.active[benchmark.ID] = run.ID
+	metrics.SetActiveBenchmarks(float64(len(r.active)))
+
+	// The goroutine owns run from here on; hand the caller a copy
+	started := *run
+	r.wg.Add(1)
+	go r.run(run, benchmark)
+
+	return &started, nil
+}
+
+// ActiveRun returns the ID of the benchmark's in-flight run, if any
+func (r *BenchmarkRunner) ActiveRun(benchmarkID uint) (uint, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	runID, ok := r.active[benchmarkID]
+	return runID, ok
+}
+
+// Stop cancels all in-flight runs and waits for them to record their outcome
+func (r *BenchmarkRunner) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *BenchmarkRunner) run(run *models.BenchmarkRun, benchmark *models.Benchmark) {
+	defer r.wg.Done()
+	defer r.release(benchmark.ID)
+
+	log := r.logger.WithFields(logrus.Fields{
+		"benchmark_id": benchmark.ID,
+		"run_id":       run.ID,
+	})
+	log.WithField("engines", benchmark.Engines).Info("Starting benchmark execution")
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		failed  bool
+		started = time.Now()
+	)
+	for _, engine := range benchmark.Engines {
+		wg.Add(1)
+		go func(engine string) {
+			defer wg.Done()
+
+			var failures, total, skipped int
+			switch run.Config.Workload.Mode {
+			case models.WorkloadConcurrent:
+				failures, total, skipped = r.runConcurrent(run, benchmark, engine)
+			case models.WorkloadOpenLoop:
+				failures, total = r.runOpenLoop(run, benchmark, engine)
+			default:
+				failures, total = r.runSequential(run, benchmark, engine)
+			}
+			if run.Config.PlanCapture == models.PlanAnalyze && underLoad(run.Config.Workload) && r.ctx.Err() == nil {
+				r.analyzeAfterLoad(run, benchmark, engine)
+			}
+			if r.checksum.Enabled && r.ctx.Err() == nil {
+				r.verifyResults(run, benchmark, engine)
+			}
+
+			status := models.StatusCompleted
+			var failure string
+			switch {
+			case skipped > 0:
+				failure = fmt.Sprintf("engine unavailable, %d concurrency levels ended early", skipped)
+			case failures > 0 && !underLoad(run.Config.Workload):
+				// Under load failures are part of the measurement, reported
+				// as each level's error rate, so they don't fail the run
+				failure = fmt.Sprintf("%d of %d query executions failed", failures, total)
+			}
+			if failure != "" {
+				status = models.StatusFailed
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+				r.events.Publish(RunEvent{
+					Type:        EventEngineFailed,
+					BenchmarkID: benchmark.ID,
+					RunID:       run.ID,
+					Engine:      engine,
+					Status:      status,
+					Error:       failure,
+				})
+			}
+			metrics.RecordBenchmarkExecution(engine, benchmark.TableFormat, status)
+		}(engine)
+	}
+	wg.Wait()
+
+	status := models.StatusCompleted
+	var runErr error
+	if r.ctx.Err() != nil {
+		status = models.StatusFailed
+		runErr = errors.New("benchmark run cancelled")
+	} else if failed {
+		status = models.StatusFailed
+	}
+	r.finishRun(run, status, runErr)
+	if err := r.benchmarkRepo.UpdateStatus(benchmark.ID, status); err != nil {
+		log.WithError(err).Error("Failed to update benchmark status")
+	}
+	if _, err := r.results.AggregateRun(run.ID); err != nil {
+		log.WithError(err).Error("Failed to aggregate benchmark results")
+	}
+	r.verifyChecksums(benchmark, run)
+	// A cancelled run is incomplete, so it can't be judged against a baseline
+	if r.ctx.Err() == nil {
+		if _, err := r.regressions.DetectRun(run.ID); err != nil {
+			log.WithError(err).Error("Failed to detect regressions")
+		}
+	}
+
+	completed := RunEvent{
+		Type:        EventRunCompleted,
+		BenchmarkID: benchmark.ID,
+		RunID:       run.ID,
+		Status:      status,
+	}
+	if runErr != nil {
+		completed.Error = runErr.Error()
+	}
+	r.events.Publish(completed)
+
+	log.WithFields(logrus.Fields{
+		"status":   status,
+		"duration": time.Since(started).String(),
+	}).Info("Benchmark execution finished")
+}
+
+// runSequential runs the queries of a benchmark on an engine one after the
+// other, each for the warm-up and then the measured iterations. It returns
+// how many executions failed out of how many were planned.
+func (r *BenchmarkRunner) runSequential(run *models.BenchmarkRun, benchmark *models.Benchmark, engine string) (failures, total int) {
+	total = len(benchmark.Queries) * run.Config.MeasuredIterations
+	for _, query := range benchmark.Queries {
+		for i := 1; i <= run.Config.WarmupIterations && r.ctx.Err() == nil; i++ {
+			r.warmUp(run, query, engine, i)
+		}
+		for i := 1; i <= run.Config.MeasuredIterations; i++ {
+			if r.ctx.Err() != nil {
+				return failures + 1, total
+			}
+			if r.executeQuery(run, benchmark, query, engine, i, sequentialLoad) != nil {
+				failures++
+			}
+		}
+	}
+	return failures, total
+}
+
+// warmUp runs a warm-up iteration of a query to fill the engine's caches.
+// Nothing is recorded and failures only get logged.
+func (r *BenchmarkRunner) warmUp(run *models.BenchmarkRun, query models.Query, engine string, iteration int) {
+	noRows := int64(0)
+	_, err := r.client.Execute(r.ctx, queryclient.ExecuteRequest{
+		Handle:  fmt.Sprintf("run-%d-%s-query-%d-warmup-%d", run.ID, engine, query.ID, iteration),
+		Engine:  engine,
+		SQL:     query.SQLQuery,
+		Catalog: run.Config.Catalog,
+		MaxRows: &noRows,
+	})
+	if err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"run_id":    run.ID,
+			"query_id":  query.ID,
+			"engine":    engine,
+			"iteration": iteration,
+		}).Warn("Warm-up iteration failed")
+	}
+}
+
+// executeQuery runs one measured iteration of a query on one engine and
+// persists the attempt, along with the load it ran under. It returns why the
+// query failed, if it did.
+func (r *BenchmarkRunner) executeQuery(run *models.BenchmarkRun, benchmark *models.Benchmark, query models.Query, engine string, iteration int, load load) error {
+	log := r.logger.WithFields(logrus.Fields{
+		"benchmark_id": benchmark.ID,
+		"run_id":       run.ID,
+		"query_id":     query.ID,
+		"engine":       engine,
+		"iteration":    iteration,
+		"concurrency":  load.concurrency,
+		"virtual_user": load.user,
+		"arrival_rate": load.arrivalRate,
+	})
+
+	start := time.Now()
+	execution := &models.QueryExecution{
+		QueryID:     query.ID,
+		RunID:       &run.ID,
+		Engine:      engine,
+		Iteration:   iteration,
+		Concurrency: load.concurrency,
+		VirtualUser: load.user,
+		ArrivalRate: load.arrivalRate,
+		Status:      models.StatusRunning,
+		StartTime:   &start,
+	}
+	if !load.scheduled.IsZero() {
+		execution.ScheduledTime = &load.scheduled
+	}
+	if err := r.executionRepo.Create(execution); err != nil {
+		log.WithError(err).Error("Failed to record query execution")
+		return err
+	}
+	r.events.Publish(RunEvent{
+		Type:        EventQueryStarted,
+		BenchmarkID: benchmark.ID,
+		RunID:       run.ID,
+		Engine:      engine,
+		QueryID:     query.ID,
+		ExecutionID: execution.ID,
+		Iteration:   iteration,
+		Status:      execution.Status,
+	})
+
+	noRows := int64(0)
+	req := queryclient.ExecuteRequest{
+		Handle:  fmt.Sprintf("run-%d-execution-%d", run.ID, execution.ID),
+		Engine:  engine,
+		SQL:     query.SQLQuery,
+		Catalog: run.Config.Catalog,
+		MaxRows: &noRows,
+	}
+	// Plans are captured before the query so it runs against the same
+	// state, except for open-loop queries which mustn't be held up
+	planFirst := run.Config.PlanCapture == models.PlanExplain && load.scheduled.IsZero()
+	if planFirst {
+		r.capturePlan(run, execution, query, engine, false)
+	}
+
+	resp, err := r.client.Execute(r.ctx, req)
+
+	end := time.Now()
+	execution.EndTime = &end
+	if err != nil {
+		msg := err.Error()
+		execution.Status = models.StatusFailed
+		switch {
+		case r.ctx.Err() != nil:
+			execution.Status = models.StatusCancelled
+		case resp != nil && (resp.Status == models.StatusCancelled || resp.Status == models.StatusTimedOut):
+			execution.Status = resp.Status
+		}
+		execution.ErrorMessage = &msg
+		log.WithError(err).WithField("status", execution.Status).Warn("Query execution failed")
+	} else {
+		latencyMs := resp.ExecutionTimeMs
+		if execution.ScheduledTime != nil {
+			// Correct for coordinated omission: the query's latency is what
+			// a client arriving on schedule would have seen
+			latencyMs = end.Sub(*execution.ScheduledTime).Milliseconds()
+		}
+		execution.Status = models.StatusCompleted
+		execution.ExecutionTimeMs = &latencyMs
+		execution.ServiceTimeMs = &resp.ExecutionTimeMs
+		execution.RowsReturned = &resp.RowsReturned
+		execution.RowsProcessed = &resp.RowsReturned
+		applyQueryStats(execution, resp.Stats)
+		if resp.Checksum != "" {
+			execution.ResultChecksum = &resp.Checksum
+		}
+		metrics.RecordQueryExecution(engine, benchmark.TableFormat, query.QueryType, float64(latencyMs)/1000)
+
+		switch {
+		case run.Config.PlanCapture == models.PlanAnalyze && !underLoad(run.Config.Workload):
+			// Under load it would add to the load the others are measured
+			// under, so it waits for analyzeAfterLoad
+			r.capturePlan(run, execution, query, engine, true)
+		case run.Config.PlanCapture == models.PlanExplain && !planFirst:
+			r.capturePlan(run, execution, query, engine, false)
+		}
+	}
+
+	if err := r.executionRepo.Update(execution); err != nil {
+		log.WithError(err).Error("Failed to update query execution")
+	}
+
+	finished := RunEvent{
+		Type:        EventQueryFinished,
+		BenchmarkID: benchmark.ID,
+		RunID:       run.ID,
+		Engine:      engine,
+		QueryID:     query.ID,
+		ExecutionID: execution.ID,
+		Iteration:   iteration,
+		Status:      execution.Status,
+		LatencyMs:   execution.ExecutionTimeMs,
+		Rows:        execution.RowsReturned,
+	}
+	if execution.ErrorMessage != nil {
+		finished.Error = *execution.ErrorMessage
+	}
+	r.events.Publish(finished)
+
+	return err
+}
+
+// applyQueryStats fills an execution's resource usage from the engine's own
+// statistics. Without them RowsProcessed is left at the rows returned.
+func applyQueryStats(execution *models.QueryExecution, stats *queryclient.QueryStats) {
+	if stats == nil {
+		return
+	}
+	if stats.ProcessedRows != nil {
+		execution.RowsProcessed = stats.ProcessedRows
+	}
+	execution.BytesProcessed = stats.ProcessedInputBytes
+	execution.CPUTimeMs = stats.CPUTimeMs
+	if stats.CPUTimeMs != nil && execution.ServiceTimeMs != nil && *execution.ServiceTimeMs > 0 {
+		cores := float64(*stats.CPUTimeMs) / float64(*execution.ServiceTimeMs)
+		execution.CPUUsage = &cores
+	}
+	execution.MemoryUsage = stats.PeakMemoryBytes
+	execution.IOReadBytes = stats.PhysicalInputBytes
+	execution.IOReadRows = stats.PhysicalInputRows
+	execution.QueuedTimeMs = stats.QueuedTimeMs
+	execution.PlanningTimeMs = stats.PlanningTimeMs
+}
+
+// capturePlan stores the plan of a query on its execution along with its
+// analysis, running the query again under EXPLAIN ANALYZE if analyze is set.
+// Failing to get a plan is logged but doesn't fail the execution.
+func (r *BenchmarkRunner) capturePlan(run *models.BenchmarkRun, execution *models.QueryExecution, query models.Query, engine string, analyze bool) {
+	planType := models.PlanExplain
+	if analyze {
+		planType = models.PlanAnalyze
+	}
+
+	resp, err := r.client.Explain(r.ctx, queryclient.ExplainRequest{
+		Handle:  fmt.Sprintf("run-%d-execution-%d-%s", run.ID, execution.ID, planType),
+		Engine:  engine,
+		SQL:     query.SQLQuery,
+		Catalog: run.Config.Catalog,
+		Analyze: analyze,
+	})
+	if err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"run_id":       run.ID,
+			"execution_id": execution.ID,
+			"engine":       engine,
+			"plan_type":    planType,
+		}).Warn("Failed to capture query plan")
+		return
+	}
+	execution.QueryPlan = &resp.Plan
+	execution.PlanType = &planType
+	execution.PlanAnalysis = analyzePlan(resp.Plan)
+}
+
+// analyzeAfterLoad captures the EXPLAIN ANALYZE plan of every query of a
+// concurrent or open-loop run on an engine once its load has finished, on
+// the query's last completed execution. It runs before verifyResults, since
+// the executions are saved whole.
+func (r *BenchmarkRunner) analyzeAfterLoad(run *models.BenchmarkRun, benchmark *models.Benchmark, engine string) {
+	executions, err := r.executionRepo.GetByRunID(run.ID)
+	if err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"run_id": run.ID,
+			"engine": engine,
+		}).Error("Failed to load executions to capture plans for")
+		return
+	}
+
+	// Ordered by ID, so the last completed execution of each query wins
+	last := make(map[uint]*models.QueryExecution)
+	for i := range executions {
+		if executions[i].Engine == engine && executions[i].Status == models.StatusCompleted {
+			last[executions[i].QueryID] = &executions[i]
+		}
+	}
+
+	for _, query := range benchmark.Queries {
+		execution, ok := last[query.ID]
+		if !ok || r.ctx.Err() != nil {
+			continue
+		}
+		r.capturePlan(run, execution, query, engine, true)
+		if execution.QueryPlan == nil {
+			continue
+		}
+		if err := r.executionRepo.Update(execution); err != nil {
+			r.logger.WithError(err).WithField("execution_id", execution.ID).Error("Failed to update query execution")
+		}
+	}
+}
+
+// verifyResults runs every query of a benchmark on an engine again, after
+// its measured iterations, to checksum the results. Checksumming scans and
+// hashes every row, so it's kept out of the timed executions. The checksum
+// of the i-th verification is stored on the query's measured executions of
+// iteration i and the last one's on the later iterations too, so a query
+// whose result changes between verifications shows up as nondeterministic.
+func (r *BenchmarkRunner) verifyResults(run *models.BenchmarkRun, benchmark *models.Benchmark, engine string) {
+	options := &queryclient.ChecksumOptions{
+		FloatPrecision:    r.checksum.FloatPrecision,
+		EmptyStringAsNull: r.checksum.EmptyStringAsNull,
+		NaNAsNull:         r.checksum.NaNAsNull,
+	}
+	passes := max(r.checksum.Verifications, 1)
+
+	for _, query := range benchmark.Queries {
+		log := r.logger.WithFields(logrus.Fields{
+			"run_id":   run.ID,
+			"query_id": query.ID,
+			"engine":   engine,
+		})
+		for pass := 1; pass <= passes && r.ctx.Err() == nil; pass++ {
+			noRows := int64(0)
+			resp, err := r.client.Execute(r.ctx, queryclient.ExecuteRequest{
+				Handle:   fmt.Sprintf("run-%d-%s-query-%d-verify-%d", run.ID, engine, query.ID, pass),
+				Engine:   engine,
+				SQL:      query.SQLQuery,
+				Catalog:  run.Config.Catalog,
+				MaxRows:  &noRows,
+				Checksum: options,
+			})
+			if err != nil {
+				log.WithError(err).Warn("Result verification failed")
+				break
+			}
+			if err := r.executionRepo.SetChecksum(run.ID, engine, query.ID, pass, pass == passes, resp.Checksum); err != nil {
+				log.WithError(err).Error("Failed to record result checksum")
+			}
+		}
+	}
+}
+
+// verifyChecksums warns about queries whose results differed between the
+// run's engines
+func (r *BenchmarkRunner) verifyChecksums(benchmark *models.Benchmark, run *models.BenchmarkRun) {
+	executions, err := r.executionRepo.GetByRunID(run.ID)
+	if err != nil {
+		r.logger.WithError(err).WithField("run_id", run.ID).Error("Failed to load query executions")
+		return
+	}
+	run.Executions = executions
+
+	report := checkCorrectness(matchQueries(benchmark.Queries), run)
+	for _, query := range report.Queries {
+		if query.Consistent {
+			continue
+		}
+		checksums := make(map[string]string, len(query.Checksums))
+		for _, checksum := range query.Checksums {
+			checksums[checksum.Engine] = checksum.Checksum
+		}
+		r.logger.WithFields(logrus.Fields{
+			"benchmark_id": benchmark.ID,
+			"run_id":       run.ID,
+			"query_id":     query.QueryID,
+			"checksums":    checksums,
+		}).Warn("Query results differ between engines")
+	}
+}
+
+func (r *BenchmarkRunner) finishRun(run *models.BenchmarkRun, status string, err error) {
+	end := time.Now()
+	run.Status = status
+	run.EndTime = &end
+	if err != nil {
+		msg := err.Error()
+		run.ErrorMessage = &msg
+	}
+	if err := r.runRepo.Update(run); err != nil {
+		r.logger.WithError(err).WithField("run_id", run.ID).Error("Failed to update benchmark run")
+	}
+}
+
+// snapshot captures the configuration the run is about to use so later
+// changes to the benchmark or the deployment don't rewrite history. The
+// table format doubles as the catalog name on every engine.
+func (r *BenchmarkRunner) snapshot(benchmark *models.Benchmark) models.RunConfig {
+	cfg := models.RunConfig{
+		TableFormat:        benchmark.TableFormat,
+		Catalog:            benchmark.TableFormat,
+		DatasetName:        benchmark.DatasetName,
+		DatasetSize:        benchmark.DatasetSize,
+		WarmupIterations:   benchmark.WarmupIterations,
+		MeasuredIterations: measuredIterations(benchmark),
+		Workload:           benchmark.Workload,
+	}
+	if r.plans.Capture != "none" {
+		cfg.PlanCapture = r.plans.Capture
+	}
+	cfg.Workload.ConcurrencyLevels = append([]int(nil), benchmark.Workload.ConcurrencyLevels...)
+	cfg.Workload.ArrivalRates = append([]float64(nil), benchmark.Workload.ArrivalRates...)
+	if underLoad(cfg.Workload) && cfg.Workload.Seed == 0 {
+		// Record the seed so the run's query order can be reproduced
+		cfg.Workload.Seed = time.Now().UnixNano()
+	}
+	for _, name := range benchmark.Engines {
+		engine := models.RunEngineConfig{Name: name}
+		if engineCfg, ok := r.engineConfig(name); ok {
+			engine.Host = engineCfg.Host
+			engine.Username = engineCfg.Username
+		}
+		cfg.Engines = append(cfg.Engines, engine)
+	}
+	return cfg
+}
+
+// measuredIterations returns how many times each query is measured, where
+// zero means once
+func measuredIterations(benchmark *models.Benchmark) int {
+	if benchmark.MeasuredIterations < 1 {
+		return 1
+	}
+	return benchmark.MeasuredIterations
+}
+
+func (r *BenchmarkRunner) engineConfig(name string) (config.EngineConfig, bool) {
+	switch name {
+	case "trino":
+		return r.engines.Trino, true
+	case "presto":
+		return r.engines.Presto, true
+	case "starrocks":
+		return r.engines.StarRocks, true
+	default:
+		return config.EngineConfig{}, false
+	}
+}
+
+func (r *BenchmarkRunner) release(benchmarkID uint) {
+	r.mu.Lock()
+	delete(r.active, benchmarkID)
+	metrics.SetActiveBenchmarks(float64(len(r.active)))
+	r.mu.Unlock()
+}
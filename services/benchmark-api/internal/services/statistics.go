@@ -0,0 +1,89 @@
+package services
+
+import (
+	"sort"
+
+	"benchmark-api/internal/models"
+	"benchmark-api/pkg/stats"
+)
+
+// QueryStatistics summarizes the measured iterations of one query on one
+// engine within a run
+type QueryStatistics struct {
+	QueryID     uint    `json:"query_id"`
+	QueryName   string  `json:"query_name"`
+	Engine      string  `json:"engine"`
+	Concurrency int     `json:"concurrency"`
+	ArrivalRate float64 `json:"arrival_rate"`
+	Iterations  int     `json:"iterations"`
+	Failed      int     `json:"failed"`
+	// Latency summarizes the execution time in milliseconds of the
+	// successful iterations; nil if none succeeded
+	Latency *stats.Summary `json:"latency_ms"`
+}
+
+// computeStatistics groups a run's executions by query, engine and load
+// level and summarizes each group's latencies, in that order
+func computeStatistics(benchmark *models.Benchmark, executions []models.QueryExecution) []QueryStatistics {
+	type key struct {
+		queryID     uint
+		engine      string
+		concurrency int
+		arrivalRate float64
+	}
+
+	queryNames := make(map[uint]string, len(benchmark.Queries))
+	for _, query := range benchmark.Queries {
+		queryNames[query.ID] = query.Name
+	}
+
+	groups := make(map[key]*QueryStatistics)
+	samples := make(map[key][]float64)
+	for _, execution := range executions {
+		k := key{execution.QueryID, execution.Engine, execution.Concurrency, execution.ArrivalRate}
+		group, ok := groups[k]
+		if !ok {
+			group = &QueryStatistics{
+				QueryID:     execution.QueryID,
+				QueryName:   queryNames[execution.QueryID],
+				Engine:      execution.Engine,
+				Concurrency: execution.Concurrency,
+				ArrivalRate: execution.ArrivalRate,
+			}
+			groups[k] = group
+		}
+
+		switch execution.Status {
+		case models.StatusCompleted:
+			group.Iterations++
+			if execution.ExecutionTimeMs != nil {
+				samples[k] = append(samples[k], float64(*execution.ExecutionTimeMs))
+			}
+		case models.StatusFailed, models.StatusCancelled, models.StatusTimedOut:
+			group.Iterations++
+			group.Failed++
+		}
+	}
+
+	result := make([]QueryStatistics, 0, len(groups))
+	for k, group := range groups {
+		if len(samples[k]) > 0 {
+			summary := stats.Summarize(samples[k])
+			group.Latency = &summary
+		}
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].QueryID != result[j].QueryID {
+			return result[i].QueryID < result[j].QueryID
+		}
+		if result[i].Engine != result[j].Engine {
+			return result[i].Engine < result[j].Engine
+		}
+		if result[i].Concurrency != result[j].Concurrency {
+			return result[i].Concurrency < result[j].Concurrency
+		}
+		return result[i].ArrivalRate < result[j].ArrivalRate
+	})
+	return result
+}
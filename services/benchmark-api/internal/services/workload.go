@@ -0,0 +1,310 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"benchmark-api/internal/models"
+	"benchmark-api/pkg/queryclient"
+)
+
+var ErrInvalidWorkload = errors.New("invalid workload")
+
+// defaultConcurrencyLevels is the sweep a concurrent workload runs when it
+// doesn't list its own levels
+var defaultConcurrencyLevels = []int{1, 2, 4, 8, 16}
+
+// defaultOpenLoopDuration is how long an open-loop workload holds each
+// arrival rate when it doesn't say
+const defaultOpenLoopDuration = 60
+
+// saturationThreshold is the fraction of an open-loop arrival rate an engine
+// has to complete to not count as saturated
+const saturationThreshold = 0.9
+
+// load describes the load an execution runs under
+type load struct {
+	concurrency int
+	user        int
+	arrivalRate float64   // open loop only
+	scheduled   time.Time // open loop: when the query was due to start
+}
+
+// sequentialLoad is a single user running one query at a time
+var sequentialLoad = load{concurrency: 1, user: 1}
+
+// underLoad reports whether a workload measures the engines under load, where
+// failing queries are results rather than failures of the run
+func underLoad(workload models.WorkloadConfig) bool {
+	return workload.Mode == models.WorkloadConcurrent || workload.Mode == models.WorkloadOpenLoop
+}
+
+// normalizeWorkload validates a benchmark's workload and fills in the
+// defaults: the sequential mode; for the concurrent mode the default
+// concurrency sweep and a single pass when neither a duration nor iterations
+// are given; and for the open-loop mode Poisson arrivals for a minute per rate
+func normalizeWorkload(workload *models.WorkloadConfig) error {
+	switch workload.Mode {
+	case "":
+		workload.Mode = models.WorkloadSequential
+	case models.WorkloadSequential, models.WorkloadConcurrent:
+	case models.WorkloadOpenLoop:
+		return normalizeOpenLoop(workload)
+	default:
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidWorkload, workload.Mode)
+	}
+	if workload.Mode != models.WorkloadConcurrent {
+		return nil
+	}
+
+	if len(workload.ConcurrencyLevels) == 0 {
+		workload.ConcurrencyLevels = append([]int(nil), defaultConcurrencyLevels...)
+	}
+	for _, level := range workload.ConcurrencyLevels {
+		if level < 1 {
+			return fmt.Errorf("%w: concurrency levels must be at least 1", ErrInvalidWorkload)
+		}
+	}
+	if workload.DurationSeconds < 0 || workload.Iterations < 0 || workload.ThinkTimeMs < 0 {
+		return fmt.Errorf("%w: duration, iterations and think time can't be negative", ErrInvalidWorkload)
+	}
+	if workload.DurationSeconds == 0 && workload.Iterations == 0 {
+		workload.Iterations = 1
+	}
+	return nil
+}
+
+func normalizeOpenLoop(workload *models.WorkloadConfig) error {
+	if len(workload.ArrivalRates) == 0 {
+		return fmt.Errorf("%w: open loop needs at least one arrival rate", ErrInvalidWorkload)
+	}
+	for _, rate := range workload.ArrivalRates {
+		if rate <= 0 {
+			return fmt.Errorf("%w: arrival rates must be positive", ErrInvalidWorkload)
+		}
+	}
+	switch workload.Arrival {
+	case "":
+		workload.Arrival = models.ArrivalPoisson
+	case models.ArrivalPoisson, models.ArrivalConstant:
+	default:
+		return fmt.Errorf("%w: unknown arrival schedule %q", ErrInvalidWorkload, workload.Arrival)
+	}
+	if workload.DurationSeconds < 0 || workload.MaxInFlight < 0 {
+		return fmt.Errorf("%w: duration and max in flight can't be negative", ErrInvalidWorkload)
+	}
+	if workload.DurationSeconds == 0 {
+		workload.DurationSeconds = defaultOpenLoopDuration
+	}
+	return nil
+}
+
+// failureBackoffMin and failureBackoffMax bound the pause of a virtual user
+// after a failed query, which doubles with every failure in a row, so
+// queries that fail at once don't flood the run with executions
+const (
+	failureBackoffMin = 100 * time.Millisecond
+	failureBackoffMax = 5 * time.Second
+)
+
+// runConcurrent drives an engine with the run's concurrent workload: after
+// warming up every query, it runs each concurrency level in turn with that
+// many virtual users. It returns how many executions failed out of how many
+// were recorded, and how many levels were ended early because the engine
+// was unavailable.
+func (r *BenchmarkRunner) runConcurrent(run *models.BenchmarkRun, benchmark *models.Benchmark, engine string) (failures, total, skipped int) {
+	for _, query := range benchmark.Queries {
+		for i := 1; i <= run.Config.WarmupIterations && r.ctx.Err() == nil; i++ {
+			r.warmUp(run, query, engine, i)
+		}
+	}
+
+	workload := run.Config.Workload
+	var mu sync.Mutex
+	for _, level := range workload.ConcurrencyLevels {
+		if r.ctx.Err() != nil {
+			break
+		}
+		var deadline time.Time
+		if workload.DurationSeconds > 0 {
+			deadline = time.Now().Add(time.Duration(workload.DurationSeconds) * time.Second)
+		}
+
+		var (
+			wg          sync.WaitGroup
+			unavailable atomic.Bool
+		)
+		for user := 1; user <= level; user++ {
+			wg.Add(1)
+			go func(level, user int) {
+				defer wg.Done()
+				// Every user gets its own stream, reproducible from the seed
+				rng := rand.New(rand.NewSource(workload.Seed + int64(level)<<16 + int64(user)))
+				f, t := r.runVirtualUser(run, benchmark, engine, level, user, rng, deadline, &unavailable)
+				mu.Lock()
+				failures += f
+				total += t
+				mu.Unlock()
+			}(level, user)
+		}
+		wg.Wait()
+
+		if unavailable.Load() {
+			skipped++
+			r.logger.WithFields(logrus.Fields{
+				"run_id":      run.ID,
+				"engine":      engine,
+				"concurrency": level,
+			}).Warn("Engine unavailable, ended concurrency level early")
+		}
+	}
+	return failures, total, skipped
+}
+
+// runVirtualUser runs the queries in a freshly shuffled order on every pass,
+// until the deadline if there is one and otherwise for the workload's
+// iterations. Queries already started when the deadline passes are let
+// finish. A failed query is followed by a backoff, and the engine being
+// unavailable ends the level for every user of it, which unavailable tells
+// them.
+func (r *BenchmarkRunner) runVirtualUser(run *models.BenchmarkRun, benchmark *models.Benchmark, engine string, level, user int, rng *rand.Rand, deadline time.Time, unavailable *atomic.Bool) (failures, total int) {
+	workload := run.Config.Workload
+	thinkTime := time.Duration(workload.ThinkTimeMs) * time.Millisecond
+	done := func() bool {
+		return r.ctx.Err() != nil || unavailable.Load() || (!deadline.IsZero() && !time.Now().Before(deadline))
+	}
+
+	var backoff time.Duration
+	for iteration := 1; !deadline.IsZero() || iteration <= workload.Iterations; iteration++ {
+		for _, i := range rng.Perm(len(benchmark.Queries)) {
+			if done() {
+				return failures, total
+			}
+			total++
+			pause := thinkTime
+			if err := r.executeQuery(run, benchmark, benchmark.Queries[i], engine, iteration, load{concurrency: level, user: user}); err != nil {
+				failures++
+				if errors.Is(err, queryclient.ErrUnavailable) {
+					unavailable.Store(true)
+					return failures, total
+				}
+				backoff = min(max(backoff*2, failureBackoffMin), failureBackoffMax)
+				pause = max(pause, backoff)
+			} else {
+				backoff = 0
+			}
+			if pause > 0 {
+				select {
+				case <-r.ctx.Done():
+					return failures, total
+				case <-time.After(pause):
+				}
+			}
+		}
+	}
+	return failures, total
+}
+
+// runOpenLoop drives an engine with the run's open-loop workload: after
+// warming up every query, it issues queries at each arrival rate in turn for
+// the workload's duration. It returns how many executions failed out of how
+// many were recorded.
+func (r *BenchmarkRunner) runOpenLoop(run *models.BenchmarkRun, benchmark *models.Benchmark, engine string) (failures, total int) {
+	for _, query := range benchmark.Queries {
+		for i := 1; i <= run.Config.WarmupIterations && r.ctx.Err() == nil; i++ {
+			r.warmUp(run, query, engine, i)
+		}
+	}
+
+	for step, rate := range run.Config.Workload.ArrivalRates {
+		if r.ctx.Err() != nil {
+			break
+		}
+		// Every step gets its own schedule, reproducible from the seed
+		rng := rand.New(rand.NewSource(run.Config.Workload.Seed + int64(step)))
+		f, t := r.runArrivals(run, benchmark, engine, rate, rng)
+		failures += f
+		total += t
+	}
+	return failures, total
+}
+
+// runArrivals issues queries on a constant or Poisson schedule at the given
+// rate, in shuffled passes over the benchmark's queries. A query is started
+// when it's due whether or not earlier ones have finished, unless
+// MaxInFlight are already running, in which case it waits for one of them
+// while its latency keeps counting from when it was due.
+func (r *BenchmarkRunner) runArrivals(run *models.BenchmarkRun, benchmark *models.Benchmark, engine string, rate float64, rng *rand.Rand) (failures, total int) {
+	workload := run.Config.Workload
+	var slots chan struct{}
+	if workload.MaxInFlight > 0 {
+		slots = make(chan struct{}, workload.MaxInFlight)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		order []int
+	)
+	start := time.Now()
+	end := start.Add(time.Duration(workload.DurationSeconds) * time.Second)
+	next := start
+	for arrival := 1; ; arrival++ {
+		gap := 1 / rate
+		if workload.Arrival == models.ArrivalPoisson {
+			gap = rng.ExpFloat64() / rate
+		}
+		next = next.Add(time.Duration(gap * float64(time.Second)))
+		if !next.Before(end) {
+			break
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-r.ctx.Done():
+			timer.Stop()
+			wg.Wait()
+			return failures, total
+		case <-timer.C:
+		}
+
+		if len(order) == 0 {
+			order = rng.Perm(len(benchmark.Queries))
+		}
+		query := benchmark.Queries[order[0]]
+		order = order[1:]
+
+		wg.Add(1)
+		go func(arrival int, scheduled time.Time) {
+			defer wg.Done()
+			if slots != nil {
+				select {
+				case slots <- struct{}{}:
+					defer func() { <-slots }()
+				case <-r.ctx.Done():
+					return
+				}
+			}
+			err := r.executeQuery(run, benchmark, query, engine, arrival, load{
+				concurrency: 1,
+				user:        1,
+				arrivalRate: rate,
+				scheduled:   scheduled,
+			})
+			mu.Lock()
+			total++
+			if err != nil {
+				failures++
+			}
+			mu.Unlock()
+		}(arrival, next)
+	}
+	wg.Wait()
+	return failures, total
+}
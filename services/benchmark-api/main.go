@@ -24,6 +24,7 @@ import (
 	"benchmark-api/pkg/database"
 	"benchmark-api/pkg/logger"
 	"benchmark-api/pkg/metrics"
+	"benchmark-api/pkg/queryclient"
 )
 
 // @title Data Lake Benchmark API
@@ -71,27 +72,43 @@ func main() {
 	benchmarkRepo := repository.NewBenchmarkRepository(db)
 	queryRepo := repository.NewQueryRepository(db)
 	resultRepo := repository.NewResultRepository(db)
+	runRepo := repository.NewRunRepository(db)
+	executionRepo := repository.NewExecutionRepository(db)
+	regressionRepo := repository.NewRegressionRepository(db)
+	engineRepo := repository.NewEngineRepository(db)
+
+	// Initialize query-service client
+	queryClient := queryclient.New(cfg.QueryService.URL, cfg.QueryService.Timeout)
 
 	// Initialize services
-	benchmarkService := services.NewBenchmarkService(benchmarkRepo, logger)
-	queryService := services.NewQueryService(queryRepo, cfg, logger)
-	resultService := services.NewResultService(resultRepo, logger)
+	eventBroker := services.NewEventBroker()
+	resultService := services.NewResultService(resultRepo, runRepo, executionRepo, queryRepo, cfg.Scoring, logger)
+	regressionService := services.NewRegressionService(regressionRepo, benchmarkRepo, runRepo, cfg.Regression, logger)
+	benchmarkRunner := services.NewBenchmarkRunner(benchmarkRepo, runRepo, executionRepo, queryClient, cfg.Engines, cfg.Checksum, cfg.Plan, resultService, regressionService, eventBroker, logger)
+	benchmarkService := services.NewBenchmarkService(benchmarkRepo, runRepo, executionRepo, benchmarkRunner, resultService, regressionService, eventBroker, logger)
+	queryService := services.NewQueryService(queryRepo, benchmarkRepo, executionRepo, queryClient, cfg, logger)
+	executionService := services.NewExecutionService(executionRepo, runRepo, queryRepo, logger)
+	engineMonitor := services.NewEngineMonitor(engineRepo, queryClient, cfg.EngineMonitor, logger)
+	engineMonitor.Start()
 	// metricService := services.NewMetricService(cfg.Prometheus.URL, logger) // TODO: Use this service
 
 	// Initialize handlers
 	benchmarkHandler := handlers.NewBenchmarkHandler(benchmarkService, logger)
 	queryHandler := handlers.NewQueryHandler(queryService, logger)
 	resultHandler := handlers.NewResultHandler(resultService, logger)
+	executionHandler := handlers.NewExecutionHandler(executionService, logger)
 	healthHandler := handlers.NewHealthHandler(db, logger)
 
 	// Setup Gin router
-	router := setupRouter(cfg, benchmarkHandler, queryHandler, resultHandler, healthHandler)
+	router := setupRouter(cfg, benchmarkHandler, queryHandler, resultHandler, executionHandler, healthHandler)
 
 	// Start server
 	srv := &http.Server{
 		Addr:    ":" + cfg.Server.Port,
 		Handler: router,
 	}
+	// Shutdown doesn't cancel request contexts, so end the event streams
+	srv.RegisterOnShutdown(eventBroker.Close)
 
 	// Graceful server shutdown
 	go func() {
@@ -111,13 +128,17 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+		logger.WithError(err).Error("Server forced to shutdown")
 	}
 
+	// Cancel in-flight benchmark runs and wait for them to record their status
+	benchmarkRunner.Stop()
+	engineMonitor.Stop()
+
 	logger.Info("Server exited")
 }
 
-func setupRouter(cfg *config.Config, benchmarkHandler *handlers.BenchmarkHandler, queryHandler *handlers.QueryHandler, resultHandler *handlers.ResultHandler, healthHandler *handlers.HealthHandler) *gin.Engine {
+func setupRouter(cfg *config.Config, benchmarkHandler *handlers.BenchmarkHandler, queryHandler *handlers.QueryHandler, resultHandler *handlers.ResultHandler, executionHandler *handlers.ExecutionHandler, healthHandler *handlers.HealthHandler) *gin.Engine {
 	if cfg.Server.Mode == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -160,7 +181,14 @@ func setupRouter(cfg *config.Config, benchmarkHandler *handlers.BenchmarkHandler
 			benchmarks.DELETE("/:id", benchmarkHandler.DeleteBenchmark)
 			benchmarks.POST("/:id/run", benchmarkHandler.RunBenchmark)
 			benchmarks.GET("/:id/status", benchmarkHandler.GetBenchmarkStatus)
+			benchmarks.GET("/:id/events", benchmarkHandler.StreamBenchmarkEvents)
 			benchmarks.GET("/:id/results", benchmarkHandler.GetBenchmarkResults)
+			benchmarks.GET("/:id/regressions", benchmarkHandler.ListBenchmarkRegressions)
+			benchmarks.GET("/:id/runs", benchmarkHandler.ListBenchmarkRuns)
+			benchmarks.GET("/:id/runs/:run_id", benchmarkHandler.GetBenchmarkRun)
+			benchmarks.GET("/:id/runs/:run_id/statistics", benchmarkHandler.GetBenchmarkRunStatistics)
+			benchmarks.GET("/:id/runs/:run_id/correctness", benchmarkHandler.GetBenchmarkRunCorrectness)
+			benchmarks.POST("/:id/runs/:run_id/aggregate", benchmarkHandler.AggregateBenchmarkRun)
 		}
 
 		// Query routes
@@ -184,6 +212,14 @@ func setupRouter(cfg *config.Config, benchmarkHandler *handlers.BenchmarkHandler
 			results.GET("/analytics", resultHandler.GetAnalytics)
 		}
 
+		// Execution routes
+		executions := v1.Group("/executions")
+		{
+			executions.GET("/plan-diff", executionHandler.DiffExecutionPlans)
+			executions.GET("/:id", executionHandler.GetExecution)
+			executions.GET("/:id/plan", executionHandler.GetExecutionPlan)
+		}
+
 		// Engine routes
 		engines := v1.Group("/engines")
 		{
@@ -0,0 +1,246 @@
+package queryclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when the query-service doesn't know the engine or
+// query asked for
+var ErrNotFound = errors.New("not found")
+
+// ErrUnavailable is returned when the engine asked for isn't connected
+var ErrUnavailable = errors.New("engine unavailable")
+
+// Client talks to the query-service HTTP API
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// ExecuteRequest is the body of POST /api/v1/execute. MaxRows limits the
+// rows sent back; set it to zero to only get timings. Handle names the
+// query so it can be passed to Cancel while it runs.
+type ExecuteRequest struct {
+	Handle            string            `json:"handle,omitempty"`
+	Engine            string            `json:"engine"`
+	SQL               string            `json:"sql"`
+	Catalog           string            `json:"catalog,omitempty"`
+	Schema            string            `json:"schema,omitempty"`
+	SessionProperties map[string]string `json:"session_properties,omitempty"`
+	Timeout           string            `json:"timeout,omitempty"`
+	MaxRows           *int64            `json:"max_rows,omitempty"`
+	Checksum          *ChecksumOptions  `json:"checksum,omitempty"`
+}
+
+// ExplainRequest is the body of POST /api/v1/explain. Analyze runs the
+// query and returns its EXPLAIN ANALYZE output.
+type ExplainRequest struct {
+	Handle            string            `json:"handle,omitempty"`
+	Engine            string            `json:"engine"`
+	SQL               string            `json:"sql"`
+	Catalog           string            `json:"catalog,omitempty"`
+	Schema            string            `json:"schema,omitempty"`
+	SessionProperties map[string]string `json:"session_properties,omitempty"`
+	Analyze           bool              `json:"analyze,omitempty"`
+	Timeout           string            `json:"timeout,omitempty"`
+}
+
+// ExplainResponse is the plan of a query
+type ExplainResponse struct {
+	Handle          string `json:"handle"`
+	Engine          string `json:"engine"`
+	Analyze         bool   `json:"analyze"`
+	Format          string `json:"format"` // "json" or "text"
+	Plan            string `json:"plan"`
+	ExecutionTimeMs int64  `json:"execution_time_ms"`
+}
+
+// ChecksumOptions control how the query-service normalizes values before
+// checksumming a result set
+type ChecksumOptions struct {
+	FloatPrecision    int  `json:"float_precision"`
+	EmptyStringAsNull bool `json:"empty_string_as_null"`
+	NaNAsNull         bool `json:"nan_as_null"`
+}
+
+// ExecuteResponse is the result of a query run by the query-service
+type ExecuteResponse struct {
+	Handle           string          `json:"handle"`
+	QueryID          string          `json:"query_id"`
+	Status           string          `json:"status"`
+	Engine           string          `json:"engine"`
+	ExecutionTimeMs  int64           `json:"execution_time_ms"`
+	TimeToFirstRowMs int64           `json:"time_to_first_row_ms"`
+	RowsReturned     int64           `json:"rows_returned"`
+	Truncated        bool            `json:"truncated,omitempty"`
+	Checksum         string          `json:"checksum,omitempty"`
+	Error            string          `json:"error,omitempty"`
+	ErrorType        string          `json:"error_type,omitempty"`
+	Columns          []Column        `json:"columns,omitempty"`
+	Rows             [][]interface{} `json:"rows,omitempty"`
+	Stats            *QueryStats     `json:"stats,omitempty"`
+}
+
+// QueryStats are the engine's own runtime statistics for a query. Fields the
+// engine doesn't report are nil.
+type QueryStats struct {
+	CPUTimeMs           *int64 `json:"cpu_time_ms"`
+	PeakMemoryBytes     *int64 `json:"peak_memory_bytes"` // peak user memory
+	PhysicalInputBytes  *int64 `json:"physical_input_bytes"`
+	PhysicalInputRows   *int64 `json:"physical_input_rows"`
+	ProcessedInputBytes *int64 `json:"processed_input_bytes"`
+	ProcessedRows       *int64 `json:"processed_rows"`
+	QueuedTimeMs        *int64 `json:"queued_time_ms"`
+	PlanningTimeMs      *int64 `json:"planning_time_ms"`
+}
+
+// Column describes a column of a query result
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// EngineInfo describes an engine registered with the query-service. Status
+// is that of its latest health check.
+type EngineInfo struct {
+	Name         string        `json:"name"`
+	Type         string        `json:"type"`
+	Status       string        `json:"status"` // "healthy", "unhealthy", "unavailable" or "unknown"
+	Health       *EngineHealth `json:"health,omitempty"`
+	Capabilities Capabilities  `json:"capabilities"`
+}
+
+// EngineHealth is the outcome of a health check of an engine. Fields the
+// engine doesn't report are nil.
+type EngineHealth struct {
+	Engine        string     `json:"engine"`
+	Type          string     `json:"type"`
+	Status        string     `json:"status"`
+	Version       string     `json:"version"`
+	UptimeSeconds *int64     `json:"uptime_seconds,omitempty"`
+	Workers       *int       `json:"workers,omitempty"`
+	LatencyMs     *int64     `json:"latency_ms,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	CheckedAt     *time.Time `json:"checked_at,omitempty"`
+}
+
+// Capabilities describes the optional features of an engine
+type Capabilities struct {
+	ExplainJSON    bool `json:"explain_json"`
+	ExplainAnalyze bool `json:"explain_analyze"`
+	Cancel         bool `json:"cancel"`
+	QueryInfo      bool `json:"query_info"`
+}
+
+// New creates a client for the query-service at baseURL
+func New(baseURL string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Execute runs a query on the requested engine and waits for it to finish
+func (c *Client) Execute(ctx context.Context, req ExecuteRequest) (*ExecuteResponse, error) {
+	var resp ExecuteResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/execute", req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return &resp, fmt.Errorf("%s: %s", resp.Engine, resp.Error)
+	}
+	return &resp, nil
+}
+
+// Explain returns the plan of a query on the requested engine
+func (c *Client) Explain(ctx context.Context, req ExplainRequest) (*ExplainResponse, error) {
+	var resp ExplainResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/explain", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Cancel stops a query started with the given handle
+func (c *Client) Cancel(ctx context.Context, handle string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/queries/"+url.PathEscape(handle), nil, nil)
+}
+
+// ListEngines returns the engines the query-service can run queries on
+func (c *Client) ListEngines(ctx context.Context) ([]EngineInfo, error) {
+	var engines []EngineInfo
+	if err := c.do(ctx, http.MethodGet, "/api/v1/engines", nil, &engines); err != nil {
+		return nil, err
+	}
+	return engines, nil
+}
+
+// EngineStatus returns the latest health check of an engine, or checks it
+// now if refresh is set
+func (c *Client) EngineStatus(ctx context.Context, engine string, refresh bool) (*EngineHealth, error) {
+	path := "/api/v1/engines/" + url.PathEscape(engine) + "/status"
+	if refresh {
+		path += "?refresh=true"
+	}
+	var health EngineHealth
+	if err := c.do(ctx, http.MethodGet, path, nil, &health); err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, &payload)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call query-service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		msg := apiErr.Error
+		if msg == "" {
+			msg = apiErr.Message
+		}
+		switch resp.StatusCode {
+		case http.StatusNotFound:
+			return fmt.Errorf("%w: %s", ErrNotFound, msg)
+		case http.StatusServiceUnavailable:
+			return fmt.Errorf("%w: %s", ErrUnavailable, msg)
+		}
+		return fmt.Errorf("query-service returned %d: %s", resp.StatusCode, msg)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
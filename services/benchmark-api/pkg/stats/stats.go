@@ -0,0 +1,313 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// ConfidenceLevel is the level of the confidence interval in a Summary
+const ConfidenceLevel = 0.95
+
+// Summary describes the distribution of a set of samples
+type Summary struct {
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Median float64 `json:"median"`
+	P90    float64 `json:"p90"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+	StdDev float64 `json:"stddev"`
+	// CV is the coefficient of variation, StdDev / Mean
+	CV float64 `json:"cv"`
+	// CILower and CIUpper bound the ConfidenceLevel interval of the mean
+	CILower float64 `json:"ci_lower"`
+	CIUpper float64 `json:"ci_upper"`
+}
+
+// Summarize computes the Summary of samples. The standard deviation is the
+// sample standard deviation and the confidence interval uses Student's t
+// distribution, so both are zero-width for fewer than two samples.
+func Summarize(samples []float64) Summary {
+	if len(samples) == 0 {
+		return Summary{}
+	}
+
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+
+	s := Summary{
+		Count:  len(sorted),
+		Mean:   Mean(sorted),
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		Median: percentileSorted(sorted, 50),
+		P90:    percentileSorted(sorted, 90),
+		P95:    percentileSorted(sorted, 95),
+		P99:    percentileSorted(sorted, 99),
+		StdDev: StdDev(sorted),
+	}
+	if s.Mean != 0 {
+		s.CV = s.StdDev / s.Mean
+	}
+
+	s.CILower, s.CIUpper = s.Mean, s.Mean
+	if s.Count > 1 {
+		margin := tQuantile975(s.Count-1) * s.StdDev / math.Sqrt(float64(s.Count))
+		s.CILower -= margin
+		s.CIUpper += margin
+	}
+	return s
+}
+
+// Mean returns the arithmetic mean of samples
+func Mean(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range samples {
+		sum += v
+	}
+	return sum / float64(len(samples))
+}
+
+// WelchTTest runs Welch's two-sided t-test for a difference between the means
+// of a and b and returns its p-value. It needs at least two samples on each
+// side and returns 1 otherwise.
+func WelchTTest(a, b []float64) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return 1
+	}
+	t, df := WelchT(a, b)
+	if t == 0 {
+		if StdDev(a) == 0 && StdDev(b) == 0 && Mean(a) != Mean(b) {
+			return 0
+		}
+		return 1
+	}
+	return regIncBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// MannWhitneyU runs the two-sided Mann-Whitney U test for a shift between the
+// distributions of a and b and returns its p-value. It uses the normal
+// approximation with tie and continuity corrections, so it can't reach
+// p < 0.05 with fewer than four samples on each side. It needs at least two
+// samples on each side and returns 1 otherwise.
+func MannWhitneyU(a, b []float64) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return 1
+	}
+
+	type sample struct {
+		value float64
+		fromA bool
+	}
+	all := make([]sample, 0, len(a)+len(b))
+	for _, v := range a {
+		all = append(all, sample{v, true})
+	}
+	for _, v := range b {
+		all = append(all, sample{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].value < all[j].value })
+
+	// Rank with ties sharing their average rank
+	var rankSumA, tieTerm float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].value == all[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankSumA += rank
+			}
+		}
+		ties := float64(j - i)
+		tieTerm += ties*ties*ties - ties
+		i = j
+	}
+
+	na, nb := float64(len(a)), float64(len(b))
+	n := na + nb
+	u := rankSumA - na*(na+1)/2
+	mu := na * nb / 2
+	sigma := math.Sqrt(na * nb / 12 * ((n + 1) - tieTerm/(n*(n-1))))
+	if sigma == 0 {
+		return 1
+	}
+	z := (math.Abs(u-mu) - 0.5) / sigma
+	if z < 0 {
+		return 1
+	}
+	return math.Erfc(z / math.Sqrt2)
+}
+
+// GeometricMean returns the geometric mean of samples, which must all be
+// positive
+func GeometricMean(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range samples {
+		sum += math.Log(v)
+	}
+	return math.Exp(sum / float64(len(samples)))
+}
+
+// StdDev returns the sample standard deviation of samples
+func StdDev(samples []float64) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+	mean := Mean(samples)
+	var sum float64
+	for _, v := range samples {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(len(samples)-1))
+}
+
+// WelchT returns Welch's t statistic for the difference between the means
+// of a and b, and its Welch-Satterthwaite degrees of freedom. Both are zero
+// unless each side has at least two samples.
+func WelchT(a, b []float64) (t, df float64) {
+	if len(a) < 2 || len(b) < 2 {
+		return 0, 0
+	}
+	na, nb := float64(len(a)), float64(len(b))
+	sa, sb := StdDev(a), StdDev(b)
+	va, vb := sa*sa/na, sb*sb/nb
+	if va+vb == 0 {
+		return 0, na + nb - 2
+	}
+	t = (Mean(a) - Mean(b)) / math.Sqrt(va+vb)
+	df = (va + vb) * (va + vb) / (va*va/(na-1) + vb*vb/(nb-1))
+	return t, df
+}
+
+// SignificantlyDifferent reports whether the means of a and b differ at
+// ConfidenceLevel according to Welch's two-sided t-test. Samples without any
+// variance differ whenever their means do.
+func SignificantlyDifferent(a, b []float64) bool {
+	if len(a) < 2 || len(b) < 2 {
+		return false
+	}
+	t, df := WelchT(a, b)
+	if t == 0 {
+		return StdDev(a) == 0 && StdDev(b) == 0 && Mean(a) != Mean(b)
+	}
+	return math.Abs(t) > tQuantile975(int(df))
+}
+
+// Percentile returns the p-th percentile (0-100) of samples, interpolating
+// linearly between the closest ranks
+func Percentile(samples []float64, p float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	return percentileSorted(sorted, p)
+}
+
+func percentileSorted(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower < 0 {
+		return sorted[0]
+	}
+	if upper >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[upper]-sorted[lower])
+}
+
+// tTable975 holds the 0.975 quantile of Student's t distribution for 1 to
+// 30 degrees of freedom
+var tTable975 = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// tQuantile975 returns the two-sided 95% critical value of Student's t
+// distribution. Past the table it uses the Cornish-Fisher expansion around
+// the normal quantile, which is accurate to three decimals there.
+func tQuantile975(df int) float64 {
+	if df <= 0 {
+		return 0
+	}
+	if df <= len(tTable975) {
+		return tTable975[df-1]
+	}
+	const z = 1.959964
+	v := float64(df)
+	return z + (z*z*z+z)/(4*v) + (5*math.Pow(z, 5)+16*z*z*z+3*z)/(96*v*v)
+}
+
+// regIncBeta returns the regularized incomplete beta function I_x(a, b)
+func regIncBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lgab, _ := math.Lgamma(a + b)
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+	// The continued fraction converges quickly only on this side of the mean
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+// betaContinuedFraction evaluates the continued fraction of the incomplete
+// beta function with the modified Lentz method
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 300
+		epsilon       = 3e-14
+		tiny          = 1e-300
+	)
+	clamp := func(v float64) float64 {
+		if math.Abs(v) < tiny {
+			return tiny
+		}
+		return v
+	}
+
+	c := 1.0
+	d := 1 / clamp(1-(a+b)*x/(a+1))
+	h := d
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+
+		num := fm * (b - fm) * x / ((a - 1 + m2) * (a + m2))
+		d = 1 / clamp(1+num*d)
+		c = clamp(1 + num/c)
+		h *= d * c
+
+		num = -(a + fm) * (a + b + fm) * x / ((a + m2) * (a + 1 + m2))
+		d = 1 / clamp(1+num*d)
+		c = clamp(1 + num/c)
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,241 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+// R's sleep data set, split by group
+var (
+	sleep1 = []float64{0.7, -1.6, -0.2, -1.2, -0.1, 3.4, 3.7, 0.8, 0.0, 2.0}
+	sleep2 = []float64{1.9, 0.8, 1.1, 0.1, -0.1, 4.4, 5.5, 1.6, 4.6, 3.4}
+)
+
+// R's mtcars mpg, split into automatic and manual transmissions
+var (
+	mpgAutomatic = []float64{21.4, 18.7, 18.1, 14.3, 24.4, 22.8, 19.2, 17.8, 16.4, 17.3, 15.2, 10.4, 10.4, 14.7, 21.5, 15.5, 15.2, 13.3, 19.2}
+	mpgManual    = []float64{21.0, 21.0, 22.8, 32.4, 30.4, 33.9, 27.3, 26.0, 30.4, 15.8, 19.7, 15.0, 21.4}
+)
+
+func TestWelchTTest(t *testing.T) {
+	// Expected p-values are R's t.test(a, b, var.equal = FALSE)$p.value
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{"sleep", sleep1, sleep2, 0.07939414},
+		{"mtcars", mpgAutomatic, mpgManual, 0.001373638},
+		{"significant", []float64{10.2, 11.1, 9.8, 10.5, 10.9}, []float64{12.4, 11.8, 13.1, 12.2, 12.9, 11.5}, 0.000513086},
+		{"two samples each", []float64{1, 2}, []float64{3, 5}, 0.1987274},
+		{"zero variance on one side", []float64{1, 1, 1}, []float64{1, 2, 3}, 0.2254033},
+		{"identical", []float64{1, 2, 3}, []float64{1, 2, 3}, 1},
+		// R refuses these as essentially constant data
+		{"zero variance on both sides", []float64{1, 1, 1}, []float64{2, 2, 2}, 0},
+		{"zero variance and equal means", []float64{2, 2}, []float64{2, 2, 2}, 1},
+		{"one sample", []float64{1}, []float64{3, 5}, 1},
+		{"no samples", nil, nil, 1},
+	}
+	for _, tt := range tests {
+		if got := WelchTTest(tt.a, tt.b); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: WelchTTest = %.9f, want %.9f", tt.name, got, tt.want)
+		}
+		if got := WelchTTest(tt.b, tt.a); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: WelchTTest reversed = %.9f, want %.9f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWelchT(t *testing.T) {
+	// R: t = -1.8608, df = 17.776
+	statistic, df := WelchT(sleep1, sleep2)
+	if math.Abs(statistic+1.860813) > 1e-6 || math.Abs(df-17.776474) > 1e-6 {
+		t.Errorf("WelchT = %f, %f, want -1.860813, 17.776474", statistic, df)
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	// Expected p-values are R's wilcox.test(a, b, exact = FALSE)$p.value,
+	// which applies the same tie and continuity corrections
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{"sleep with ties", sleep1, sleep2, 0.06932758},
+		{"mtcars with ties", mpgAutomatic, mpgManual, 0.001871391},
+		{"ties across samples", []float64{1, 2, 2, 3}, []float64{2, 3, 3, 4}, 0.1720337},
+		{"separated", []float64{1, 2, 3, 4}, []float64{5, 6, 7, 8}, 0.03038282},
+		{"two samples each", []float64{1, 2}, []float64{3, 4}, 0.2452781},
+		{"identical", []float64{1, 2, 3}, []float64{1, 2, 3}, 1},
+		// R returns NaN when every value is tied
+		{"all tied", []float64{1, 1}, []float64{1, 1, 1}, 1},
+		{"one sample", []float64{1}, []float64{3, 4}, 1},
+		{"no samples", nil, nil, 1},
+	}
+	for _, tt := range tests {
+		if got := MannWhitneyU(tt.a, tt.b); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: MannWhitneyU = %.9f, want %.9f", tt.name, got, tt.want)
+		}
+		if got := MannWhitneyU(tt.b, tt.a); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: MannWhitneyU reversed = %.9f, want %.9f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRegIncBeta(t *testing.T) {
+	tests := []struct {
+		a, b, x float64
+		want    float64
+	}{
+		{2, 3, 0, 0},
+		{2, 3, 1, 1},
+		{1, 1, 0.3, 0.3},                  // uniform
+		{3, 1, 0.6, 0.216},                // x^a
+		{1, 4, 0.2, 1 - math.Pow(0.8, 4)}, // 1 - (1-x)^b
+		{5, 5, 0.5, 0.5},                  // symmetric
+		{0.5, 0.5, 0.25, 2 / math.Pi * math.Asin(0.5)}, // arcsine
+		{0.5, 0.5, 0.9, 2 / math.Pi * math.Asin(math.Sqrt(0.9))},
+		{2, 3, 0.4, 0.5248},        // pbeta(0.4, 2, 3)
+		{50, 0.5, 0.99, 0.3173044}, // pbeta(0.99, 50, 0.5)
+	}
+	for _, tt := range tests {
+		if got := regIncBeta(tt.a, tt.b, tt.x); math.Abs(got-tt.want) > 1e-7 {
+			t.Errorf("regIncBeta(%g, %g, %g) = %.9f, want %.9f", tt.a, tt.b, tt.x, got, tt.want)
+		}
+		// I_x(a, b) = 1 - I_(1-x)(b, a) exercises the other side of the
+		// continued fraction
+		if got := 1 - regIncBeta(tt.b, tt.a, 1-tt.x); math.Abs(got-tt.want) > 1e-7 {
+			t.Errorf("1 - regIncBeta(%g, %g, %g) = %.9f, want %.9f", tt.b, tt.a, 1-tt.x, got, tt.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []float64
+		want    Summary
+	}{
+		{"no samples", nil, Summary{}},
+		{
+			name:    "single sample",
+			samples: []float64{5},
+			want:    Summary{Count: 1, Mean: 5, Min: 5, Max: 5, Median: 5, P90: 5, P95: 5, P99: 5, CILower: 5, CIUpper: 5},
+		},
+		{
+			// R: t.test(c(3, 1))$conf.int is -10.70620 14.70620
+			name:    "two samples",
+			samples: []float64{3, 1},
+			want: Summary{
+				Count: 2, Mean: 2, Min: 1, Max: 3, Median: 2, P90: 2.8, P95: 2.9, P99: 2.98,
+				StdDev: math.Sqrt2, CV: math.Sqrt2 / 2, CILower: -10.706, CIUpper: 14.706,
+			},
+		},
+		{
+			// R: t.test(c(1, 2, 3, 4, 10))$conf.int is -0.3899 8.3899
+			name:    "skewed",
+			samples: []float64{10, 2, 4, 1, 3},
+			want: Summary{
+				Count: 5, Mean: 4, Min: 1, Max: 10, Median: 3, P90: 7.6, P95: 8.8, P99: 9.76,
+				StdDev: 3.535534, CV: 0.883883, CILower: -0.389, CIUpper: 8.389,
+			},
+		},
+		{
+			name:    "constant",
+			samples: []float64{7, 7, 7},
+			want:    Summary{Count: 3, Mean: 7, Min: 7, Max: 7, Median: 7, P90: 7, P95: 7, P99: 7, CILower: 7, CIUpper: 7},
+		},
+	}
+	for _, tt := range tests {
+		got := Summarize(tt.samples)
+		fields := []struct {
+			name      string
+			got, want float64
+		}{
+			{"count", float64(got.Count), float64(tt.want.Count)},
+			{"mean", got.Mean, tt.want.Mean},
+			{"min", got.Min, tt.want.Min},
+			{"max", got.Max, tt.want.Max},
+			{"median", got.Median, tt.want.Median},
+			{"p90", got.P90, tt.want.P90},
+			{"p95", got.P95, tt.want.P95},
+			{"p99", got.P99, tt.want.P99},
+			{"stddev", got.StdDev, tt.want.StdDev},
+			{"cv", got.CV, tt.want.CV},
+			{"ci lower", got.CILower, tt.want.CILower},
+			{"ci upper", got.CIUpper, tt.want.CIUpper},
+		}
+		for _, f := range fields {
+			if math.Abs(f.got-f.want) > 1e-3 {
+				t.Errorf("%s: %s = %f, want %f", tt.name, f.name, f.got, f.want)
+			}
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	samples := []float64{4, 1, 3, 2}
+	tests := []struct {
+		samples []float64
+		p       float64
+		want    float64
+	}{
+		{samples, 0, 1},
+		{samples, 100, 4},
+		{samples, 50, 2.5},
+		{samples, 25, 1.75}, // R: quantile(c(1, 2, 3, 4), 0.25)
+		{samples, -10, 1},
+		{samples, 110, 4},
+		{[]float64{1, 3}, 0, 1},
+		{[]float64{1, 3}, 50, 2},
+		{[]float64{1, 3}, 100, 3},
+		{[]float64{9}, 0, 9},
+		{[]float64{9}, 100, 9},
+		{nil, 50, 0},
+	}
+	for _, tt := range tests {
+		if got := Percentile(tt.samples, tt.p); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Percentile(%v, %g) = %g, want %g", tt.samples, tt.p, got, tt.want)
+		}
+	}
+	if samples[0] != 4 {
+		t.Errorf("Percentile sorted its input: %v", samples)
+	}
+}
+
+func TestTQuantile975(t *testing.T) {
+	// Expected values are R's qt(0.975, df)
+	tests := []struct {
+		df   int
+		want float64
+	}{
+		{-1, 0},
+		{0, 0},
+		{1, 12.706205},
+		{2, 4.302653},
+		{29, 2.045230},
+		{30, 2.042272},
+		{31, 2.039513},
+		{32, 2.036933},
+		{40, 2.021075},
+		{100, 1.983972},
+		{1000, 1.962339},
+	}
+	for _, tt := range tests {
+		if got := tQuantile975(tt.df); math.Abs(got-tt.want) > 1e-3 {
+			t.Errorf("tQuantile975(%d) = %f, want %f", tt.df, got, tt.want)
+		}
+	}
+
+	// The quantile shrinks towards the normal one, including across the
+	// end of the table
+	for df := 2; df <= 1000; df++ {
+		if tQuantile975(df) >= tQuantile975(df-1) {
+			t.Fatalf("tQuantile975(%d) = %f isn't below tQuantile975(%d) = %f", df, tQuantile975(df), df-1, tQuantile975(df-1))
+		}
+	}
+	if got := tQuantile975(1 << 20); got < 1.959964 {
+		t.Errorf("tQuantile975 for large df = %f, below the normal quantile", got)
+	}
+}
@@ -2,17 +2,20 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 // Config holds the application configuration
 type Config struct {
-	Server    ServerConfig
-	Trino     TrinoConfig
-	Presto    PrestoConfig
-	StarRocks StarRocksConfig
-	Logger    *logrus.Logger
+	Server  ServerConfig
+	Query   QueryConfig
+	Health  HealthConfig
+	Engines []EngineConfig
+	Logger  *logrus.Logger
 }
 
 // ServerConfig holds server configuration
@@ -21,33 +24,104 @@ type ServerConfig struct {
 	Mode string
 }
 
-// TrinoConfig holds Trino connection details
-type TrinoConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	Catalog  string
-	Schema   string
+// QueryConfig holds query execution settings
+type QueryConfig struct {
+	// DefaultTimeout applies to queries that don't set their own timeout
+	DefaultTimeout time.Duration
+	// CollectStats fetches the engine's runtime statistics after every
+	// completed query
+	CollectStats bool
 }
 
-// PrestoConfig holds Presto connection details
-type PrestoConfig struct {
+// HealthConfig controls the background engine health checks
+type HealthConfig struct {
+	Interval time.Duration
+	// Timeout bounds a single check of one engine
+	Timeout time.Duration
+}
+
+// EngineConfig holds connection details for a query engine
+type EngineConfig struct {
+	Name     string
+	Type     string // "trino", "presto" or "starrocks"
 	Host     string
 	Port     string
 	User     string
 	Password string
 	Catalog  string
 	Schema   string
+	// Tables are the benchmark tables an engine test checks are readable,
+	// qualified as needed to resolve from Catalog and Schema
+	Tables []string
+	// Catalogs maps the catalog names queries use, such as "hive", to the
+	// engine's own names for them where they differ. Only StarRocks
+	// translates catalog names.
+	Catalogs map[string]string
+	// ExternalCatalogs are created on the engine when it connects, unless
+	// it already has catalogs by those names. Only StarRocks creates
+	// catalogs.
+	ExternalCatalogs []ExternalCatalog
 }
 
-// StarRocksConfig holds StarRocks connection details
-type StarRocksConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	Database string
+// ExternalCatalog describes a catalog over the Hive metastore and the
+// object store holding the benchmark tables
+type ExternalCatalog struct {
+	Name         string
+	Type         string // "hive" or "iceberg"
+	MetastoreURI string
+	S3Endpoint   string
+	S3AccessKey  string
+	S3SecretKey  string
+}
+
+// engineDefaults are the connection defaults for the engines shipped in
+// docker-compose, keyed by engine name
+var engineDefaults = map[string]EngineConfig{
+	"trino": {
+		Type:    "trino",
+		Host:    "trino",
+		Port:    "8080",
+		User:    "admin",
+		Catalog: "hive",
+		Schema:  "default",
+		Tables: []string{
+			"hive.default.customer_hive", "hive.default.orders_hive", "hive.default.lineitem_hive",
+			"iceberg.default.customer_iceberg", "iceberg.default.orders_iceberg", "iceberg.default.lineitem_iceberg",
+		},
+	},
+	"presto": {
+		Type:    "presto",
+		Host:    "presto",
+		Port:    "8080",
+		User:    "admin",
+		Catalog: "hive",
+		Schema:  "default",
+		Tables:  []string{"hive.default.customer_hive", "hive.default.orders_hive", "hive.default.lineitem_hive"},
+	},
+	"starrocks": {
+		Type:    "starrocks",
+		Host:    "starrocks-fe",
+		Port:    "9030",
+		User:    "root",
+		Catalog: "hive",
+		Schema:  "default",
+		Tables: []string{
+			"hive.default.customer_hive", "hive.default.orders_hive", "hive.default.lineitem_hive",
+			"iceberg.default.customer_iceberg", "iceberg.default.orders_iceberg", "iceberg.default.lineitem_iceberg",
+		},
+		ExternalCatalogs: []ExternalCatalog{
+			{Name: "hive", Type: "hive"},
+			{Name: "iceberg", Type: "iceberg"},
+		},
+	},
+}
+
+// externalCatalogDefaults are where the external catalogs of every engine
+// find the metastore and object store shipped in docker-compose. The object
+// store's keys have no default and are only read from the environment.
+var externalCatalogDefaults = ExternalCatalog{
+	MetastoreURI: "thrift://hive-metastore:9083",
+	S3Endpoint:   "http://minio:9000",
 }
 
 // Load creates a new configuration object
@@ -57,28 +131,16 @@ func Load() (*Config, error) {
 			Port: getEnv("QUERY_SERVICE_PORT", "8080"),
 			Mode: getEnv("GIN_MODE", "release"),
 		},
-		Trino: TrinoConfig{
-			Host:    getEnv("TRINO_HOST", "trino"),
-			Port:    getEnv("TRINO_PORT", "8080"),
-			User:    getEnv("TRINO_USER", "admin"),
-			Catalog: getEnv("TRINO_CATALOG", "hive"),
-			Schema:  getEnv("TRINO_SCHEMA", "default"),
+		Query: QueryConfig{
+			DefaultTimeout: getEnvDuration("QUERY_DEFAULT_TIMEOUT", 30*time.Minute),
+			CollectStats:   getEnvBool("QUERY_COLLECT_STATS", true),
 		},
-		Presto: PrestoConfig{
-			Host:    getEnv("PRESTO_HOST", "presto"),
-			Port:    getEnv("PRESTO_PORT", "8080"),
-			User:    getEnv("PRESTO_USER", "admin"),
-			Catalog: getEnv("PRESTO_CATALOG", "hive"),
-			Schema:  getEnv("PRESTO_SCHEMA", "default"),
+		Health: HealthConfig{
+			Interval: getEnvDuration("HEALTH_CHECK_INTERVAL", 30*time.Second),
+			Timeout:  getEnvDuration("HEALTH_CHECK_TIMEOUT", 10*time.Second),
 		},
-		StarRocks: StarRocksConfig{
-			Host:     getEnv("STARROCKS_HOST", "starrocks-fe"),
-			Port:     getEnv("STARROCKS_PORT", "9030"),
-			User:     getEnv("STARROCKS_USER", "root"),
-			Password: getEnv("STARROCKS_PASSWORD", ""),
-			Database: getEnv("STARROCKS_DATABASE", "default_catalog"),
-		},
-		Logger: logrus.New(),
+		Engines: loadEngines(getEnv("ENGINES", "trino,presto")),
+		Logger:  logrus.New(),
 	}
 
 	// Configure logger
@@ -89,9 +151,128 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// loadEngines reads the configuration of every engine in the comma
+// separated list. Each engine is configured through variables prefixed
+// with its upper-cased name, e.g. TRINO_HOST, so a second Trino cluster
+// can be added with ENGINES=trino,trino_next and TRINO_NEXT_TYPE=trino.
+// External catalogs are listed as name:type pairs, e.g.
+// STARROCKS_EXTERNAL_CATALOGS=hive:hive,lake:iceberg, and share one
+// metastore and object store.
+func loadEngines(names string) []EngineConfig {
+	var engines []EngineConfig
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		defaults := engineDefaults[name]
+		prefix := strings.ToUpper(name) + "_"
+		engines = append(engines, EngineConfig{
+			Name:             name,
+			Type:             getEnv(prefix+"TYPE", firstNonEmpty(defaults.Type, name)),
+			Host:             getEnv(prefix+"HOST", defaults.Host),
+			Port:             getEnv(prefix+"PORT", defaults.Port),
+			User:             getEnv(prefix+"USER", defaults.User),
+			Password:         getEnv(prefix+"PASSWORD", defaults.Password),
+			Catalog:          getEnv(prefix+"CATALOG", defaults.Catalog),
+			Schema:           getEnv(prefix+"SCHEMA", getEnv(prefix+"DATABASE", defaults.Schema)),
+			Tables:           getEnvList(prefix+"TABLES", defaults.Tables),
+			Catalogs:         getEnvMap(prefix+"CATALOGS", defaults.Catalogs),
+			ExternalCatalogs: loadExternalCatalogs(prefix, defaults.ExternalCatalogs),
+		})
+	}
+	return engines
+}
+
+// loadExternalCatalogs reads the external catalogs of the engine whose
+// variables start with prefix. A catalog without a type takes its name as
+// the type.
+func loadExternalCatalogs(prefix string, defaults []ExternalCatalog) []ExternalCatalog {
+	catalogs := defaults
+	if os.Getenv(prefix+"EXTERNAL_CATALOGS") != "" {
+		catalogs = nil
+		for _, entry := range getEnvList(prefix+"EXTERNAL_CATALOGS", nil) {
+			name, catalogType, _ := strings.Cut(entry, ":")
+			catalogs = append(catalogs, ExternalCatalog{
+				Name: strings.TrimSpace(name),
+				Type: firstNonEmpty(strings.TrimSpace(catalogType), strings.TrimSpace(name)),
+			})
+		}
+	}
+
+	loaded := make([]ExternalCatalog, 0, len(catalogs))
+	for _, catalog := range catalogs {
+		catalog.MetastoreURI = getEnv(prefix+"METASTORE_URI", externalCatalogDefaults.MetastoreURI)
+		catalog.S3Endpoint = getEnv(prefix+"S3_ENDPOINT", externalCatalogDefaults.S3Endpoint)
+		catalog.S3AccessKey = os.Getenv(prefix + "S3_ACCESS_KEY")
+		catalog.S3SecretKey = os.Getenv(prefix + "S3_SECRET_KEY")
+		loaded = append(loaded, catalog)
+	}
+	return loaded
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+// getEnvList reads a comma separated list, skipping empty entries
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvMap reads a comma separated list of key=value pairs, skipping
+// entries without a value
+func getEnvMap(key string, defaultValue map[string]string) map[string]string {
+	if os.Getenv(key) == "" {
+		return defaultValue
+	}
+	m := make(map[string]string)
+	for _, item := range getEnvList(key, nil) {
+		k, v, ok := strings.Cut(item, "=")
+		if k, v = strings.TrimSpace(k), strings.TrimSpace(v); ok && k != "" && v != "" {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
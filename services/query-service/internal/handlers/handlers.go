@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"query-service/internal/services"
@@ -10,52 +15,378 @@ import (
 
 type QueryHandler struct {
 	executor *services.QueryExecutor
+	health   *services.HealthProber
 	logger   *logger.Logger
 }
 
 // NewQueryHandler creates a new QueryHandler
-func NewQueryHandler(executor *services.QueryExecutor, logger *logger.Logger) *QueryHandler {
+func NewQueryHandler(executor *services.QueryExecutor, health *services.HealthProber, logger *logger.Logger) *QueryHandler {
 	return &QueryHandler{
 		executor: executor,
+		health:   health,
 		logger:   logger,
 	}
 }
 
+// defaultMaxRows caps the rows of a plain JSON response, which is buffered
+const defaultMaxRows = 1000
+
+// ExecuteQueryRequest is the body of POST /api/v1/execute. Catalog, schema
+// and session properties override the engine's defaults for this query
+// only. MaxRows limits the rows sent back, not the rows the query reads:
+// 0 returns timings only, and when omitted plain JSON responses are capped
+// at defaultMaxRows while streamed responses are unlimited. A negative
+// MaxRows lifts the limit of streamed responses only. Timeout falls back to
+// QUERY_DEFAULT_TIMEOUT.
+type ExecuteQueryRequest struct {
+	// Handle lets the client cancel the query with DELETE
+	// /api/v1/queries/:handle before the response arrives. One is
+	// generated if it's empty.
+	Handle            string            `json:"handle"`
+	Engine            string            `json:"engine" binding:"required"`
+	SQL               string            `json:"sql" binding:"required"`
+	Catalog           string            `json:"catalog"`
+	Schema            string            `json:"schema"`
+	SessionProperties map[string]string `json:"session_properties"`
+	Timeout           Duration          `json:"timeout"`
+	MaxRows           *int64            `json:"max_rows"`
+	// Checksum asks for an order-insensitive checksum of the whole result
+	// set, normalized with these options
+	Checksum *services.ChecksumOptions `json:"checksum"`
+	// Format is json, ndjson or arrow. When empty it's taken from the
+	// Accept header.
+	Format string `json:"format"`
+}
+
+// ExplainQueryRequest is the body of POST /api/v1/explain. Catalog, schema
+// and session properties apply as for ExecuteQueryRequest.
+type ExplainQueryRequest struct {
+	Handle            string            `json:"handle"`
+	Engine            string            `json:"engine" binding:"required"`
+	SQL               string            `json:"sql" binding:"required"`
+	Catalog           string            `json:"catalog"`
+	Schema            string            `json:"schema"`
+	SessionProperties map[string]string `json:"session_properties"`
+	// Analyze runs the query and returns EXPLAIN ANALYZE output
+	Analyze bool     `json:"analyze"`
+	Timeout Duration `json:"timeout"`
+}
+
+// Duration is a timeout given either as a Go duration string like "90s" or
+// as a number of seconds
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case nil:
+		*d = 0
+	case float64:
+		*d = Duration(v * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	if *d < 0 {
+		return fmt.Errorf("duration must not be negative")
+	}
+	return nil
+}
+
+// ExecuteQuery runs a query and returns its timings and rows. Plain JSON
+// buffers the rows; ndjson streams a columns line, one line per row and a
+// final result line; arrow streams an Arrow IPC stream with the timings in
+// HTTP trailers.
 func (h *QueryHandler) ExecuteQuery(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "Not implemented yet"})
+	var req ExecuteQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format, err := responseFormat(req.Format, c.GetHeader("Accept"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	maxRows := int64(-1)
+	if req.MaxRows != nil {
+		maxRows = *req.MaxRows
+	} else if format == formatJSON {
+		maxRows = defaultMaxRows
+	}
+	if maxRows < 0 && format == formatJSON {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_rows must not be negative for json responses, which are buffered; use ndjson or arrow for unbounded results"})
+		return
+	}
+
+	var sink resultSink
+	buffer := &bufferSink{rows: [][]interface{}{}}
+	switch format {
+	case formatNDJSON:
+		sink = newNDJSONSink(c)
+	case formatArrow:
+		sink = newArrowSink(c)
+	}
+
+	execution := services.Execution{
+		Handle: req.Handle,
+		Engine: req.Engine,
+		SQL:    req.SQL,
+		Options: services.ExecOptions{
+			Catalog:           req.Catalog,
+			Schema:            req.Schema,
+			SessionProperties: req.SessionProperties,
+		},
+		Timeout:  time.Duration(req.Timeout),
+		MaxRows:  maxRows,
+		Checksum: req.Checksum,
+	}
+	if sink != nil {
+		execution.Sink = sink
+	} else if maxRows != 0 {
+		execution.Sink = buffer
+	}
+
+	result, err := h.executor.Execute(c.Request.Context(), execution)
+	var queryErr *services.QueryError
+	if err != nil && !errors.As(err, &queryErr) {
+		switch {
+		case errors.Is(err, services.ErrEngineNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrEngineUnavailable):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrHandleInUse):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if sink != nil && (sink.started() || format == formatNDJSON) {
+		if err := sink.finish(result); err != nil {
+			h.logger.WithError(err).Warn("Failed to finish result stream")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, struct {
+		*services.QueryResult
+		Columns []services.Column `json:"columns,omitempty"`
+		Rows    [][]interface{}   `json:"rows"`
+	}{result, buffer.columns, buffer.rows})
+}
+
+// responseFormat picks the format of an execute response from the request
+// body, falling back to the Accept header
+func responseFormat(format, accept string) (string, error) {
+	switch strings.ToLower(format) {
+	case formatJSON, formatNDJSON, formatArrow:
+		return strings.ToLower(format), nil
+	case "":
+	default:
+		return "", fmt.Errorf("unsupported format %q", format)
+	}
+
+	switch {
+	case strings.Contains(accept, contentTypeNDJSON):
+		return formatNDJSON, nil
+	case strings.Contains(accept, contentTypeArrow):
+		return formatArrow, nil
+	default:
+		return formatJSON, nil
+	}
+}
+
+// ExplainQuery returns the plan of a query, as JSON on Trino and Presto or
+// as text for EXPLAIN ANALYZE and StarRocks
+func (h *QueryHandler) ExplainQuery(c *gin.Context) {
+	var req ExplainQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.executor.Explain(c.Request.Context(), services.Explanation{
+		Handle: req.Handle,
+		Engine: req.Engine,
+		SQL:    req.SQL,
+		Options: services.ExecOptions{
+			Catalog:           req.Catalog,
+			Schema:            req.Schema,
+			SessionProperties: req.SessionProperties,
+		},
+		Analyze: req.Analyze,
+		Timeout: time.Duration(req.Timeout),
+	})
+	var queryErr *services.QueryError
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, result)
+	case errors.Is(err, services.ErrEngineNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrEngineUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrExplainUnsupported):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrHandleInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.As(err, &queryErr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "error_type": queryErr.Category})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// CancelQuery stops a running query by the handle it was started with. A
+// query started elsewhere can be killed by its engine query ID by passing
+// the engine as ?engine=.
+func (h *QueryHandler) CancelQuery(c *gin.Context) {
+	queryID := c.Param("query_id")
+	err := h.executor.Cancel(c.Request.Context(), queryID, c.Query("engine"))
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"query_id": queryID, "status": services.StatusCancelled})
+	case errors.Is(err, services.ErrQueryNotFound), errors.Is(err, services.ErrEngineNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrEngineUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	}
+}
+
+// ListRunningQueries lists the queries currently running through the
+// service
+func (h *QueryHandler) ListRunningQueries(c *gin.Context) {
+	c.JSON(http.StatusOK, h.executor.Running())
 }
 
+// ListEngines lists the registered engines with the status of their latest
+// health check. Engines that haven't connected yet are listed as
+// unavailable along with why and when they're retried.
 func (h *QueryHandler) ListEngines(c *gin.Context) {
-	engines := []map[string]interface{}{
-		{"name": "trino", "status": "active"},
-		{"name": "presto", "status": "active"},
-		{"name": "starrocks", "status": "active"},
+	engines := []gin.H{}
+	for _, connection := range h.executor.Engines().Connections() {
+		health := h.health.Health(connection)
+		engine := gin.H{
+			"name":   connection.Name,
+			"type":   connection.Type,
+			"status": health.Status,
+			"health": health,
+		}
+		if connection.Engine != nil {
+			engine["capabilities"] = connection.Engine.Capabilities()
+		} else {
+			engine["status"] = services.HealthUnavailable
+			engine["connection"] = gin.H{
+				"error":        connection.Error,
+				"attempts":     connection.Attempts,
+				"next_attempt": connection.NextAttempt,
+			}
+		}
+		engines = append(engines, engine)
 	}
 	c.JSON(http.StatusOK, engines)
 }
 
+// GetEngineStatus returns the latest health check of an engine, or checks
+// it now with ?refresh=true. An engine that hasn't connected yet answers
+// 503 along with its status.
 func (h *QueryHandler) GetEngineStatus(c *gin.Context) {
-	engine := c.Param("engine")
-	c.JSON(http.StatusOK, gin.H{"engine": engine, "status": "healthy"})
+	connection, ok := h.executor.Engines().Connection(c.Param("engine"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s: %s", services.ErrEngineNotFound, c.Param("engine"))})
+		return
+	}
+	if connection.Engine == nil {
+		health := h.health.Health(connection)
+		health.Status = services.HealthUnavailable
+		health.Error = connection.Error
+		c.JSON(http.StatusServiceUnavailable, health)
+		return
+	}
+	if c.Query("refresh") == "true" {
+		c.JSON(http.StatusOK, h.health.Check(c.Request.Context(), connection.Engine))
+		return
+	}
+	c.JSON(http.StatusOK, h.health.Health(connection))
 }
 
+// TestEngine opens a new connection to an engine and reports step by step
+// whether it can run queries and read the benchmark tables. The body is
+// optional and overrides the engine's configured connection settings and
+// tables for this test only. A failed check is part of the report, not an
+// error.
 func (h *QueryHandler) TestEngine(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"status": "test successful"})
+	var opts services.EngineTestOptions
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&opts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	report, err := h.executor.TestEngine(c.Request.Context(), c.Param("engine"), opts)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, report)
+	case errors.Is(err, services.ErrEngineNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidTest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
 
 type HealthHandler struct {
-	logger *logger.Logger
+	registry *services.Registry
+	logger   *logger.Logger
 }
 
 // NewHealthHandler creates a new HealthHandler
-func NewHealthHandler(logger *logger.Logger) *HealthHandler {
-	return &HealthHandler{logger: logger}
+func NewHealthHandler(registry *services.Registry, logger *logger.Logger) *HealthHandler {
+	return &HealthHandler{registry: registry, logger: logger}
 }
 
 func (h *HealthHandler) HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "healthy", "service": "query-service"})
 }
 
+// ReadinessCheck reports which engines are connected. The service is ready
+// when every engine is, degraded but still ready when only some are, and
+// not ready when none are.
 func (h *HealthHandler) ReadinessCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"status": "ready", "service": "query-service"})
+	engines := gin.H{}
+	available := 0
+	connections := h.registry.Connections()
+	for _, connection := range connections {
+		if connection.Engine != nil {
+			engines[connection.Name] = "available"
+			available++
+		} else {
+			engines[connection.Name] = services.HealthUnavailable
+		}
+	}
+
+	switch {
+	case available == len(connections):
+		c.JSON(http.StatusOK, gin.H{"status": "ready", "service": "query-service", "engines": engines})
+	case available > 0:
+		c.JSON(http.StatusOK, gin.H{"status": "degraded", "service": "query-service", "engines": engines})
+	default:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "service": "query-service", "engines": engines})
+	}
 }
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/ipc"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/gin-gonic/gin"
+	"query-service/internal/services"
+)
+
+const (
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+	formatArrow  = "arrow"
+
+	contentTypeNDJSON = "application/x-ndjson"
+	contentTypeArrow  = "application/vnd.apache.arrow.stream"
+
+	// flushEvery is how many rows are written between flushes of a stream
+	flushEvery = 500
+	// arrowBatchSize is the number of rows in each Arrow record batch
+	arrowBatchSize = 1024
+)
+
+// resultSink is a RowSink that also writes the final QueryResult. started
+// reports whether anything has been written to the response yet.
+type resultSink interface {
+	services.RowSink
+	started() bool
+	finish(result *services.QueryResult) error
+}
+
+// bufferSink collects rows in memory for a plain JSON response
+type bufferSink struct {
+	columns []services.Column
+	rows    [][]interface{}
+}
+
+func (s *bufferSink) Columns(columns []services.Column) error {
+	s.columns = columns
+	return nil
+}
+
+func (s *bufferSink) Row(values []interface{}) error {
+	s.rows = append(s.rows, append([]interface{}(nil), values...))
+	return nil
+}
+
+// ndjsonSink writes one JSON object per line: the columns, then each row,
+// then the QueryResult
+type ndjsonSink struct {
+	c       *gin.Context
+	encoder *json.Encoder
+	rows    int
+}
+
+func newNDJSONSink(c *gin.Context) *ndjsonSink {
+	return &ndjsonSink{c: c, encoder: json.NewEncoder(c.Writer)}
+}
+
+func (s *ndjsonSink) started() bool {
+	return s.c.Writer.Written()
+}
+
+func (s *ndjsonSink) Columns(columns []services.Column) error {
+	s.c.Header("Content-Type", contentTypeNDJSON)
+	if err := s.encoder.Encode(gin.H{"type": "columns", "columns": columns}); err != nil {
+		return err
+	}
+	s.c.Writer.Flush()
+	return nil
+}
+
+func (s *ndjsonSink) Row(values []interface{}) error {
+	if err := s.encoder.Encode(gin.H{"type": "row", "values": values}); err != nil {
+		return err
+	}
+	s.rows++
+	if s.rows%flushEvery == 0 {
+		s.c.Writer.Flush()
+	}
+	return nil
+}
+
+func (s *ndjsonSink) finish(result *services.QueryResult) error {
+	s.c.Header("Content-Type", contentTypeNDJSON)
+	err := s.encoder.Encode(struct {
+		Type string `json:"type"`
+		*services.QueryResult
+	}{"result", result})
+	s.c.Writer.Flush()
+	return err
+}
+
+// arrowTrailers carry the QueryResult of an Arrow stream, since it's only
+// known once the last batch has been written
+var arrowTrailers = []string{
+	"X-Query-Id",
+	"X-Query-Status",
+	"X-Execution-Time-Ms",
+	"X-Time-To-First-Row-Ms",
+	"X-Rows-Returned",
+	"X-Truncated",
+	"X-Checksum",
+	"X-Error",
+	"X-Error-Type",
+	"X-Query-Stats",
+}
+
+// arrowSink writes rows as an Arrow IPC stream in batches of arrowBatchSize.
+// Integer, floating point and boolean columns keep their type and every
+// other column is sent as UTF-8.
+type arrowSink struct {
+	c       *gin.Context
+	mem     memory.Allocator
+	schema  *arrow.Schema
+	builder *array.RecordBuilder
+	writer  *ipc.Writer
+	pending int
+}
+
+func newArrowSink(c *gin.Context) *arrowSink {
+	return &arrowSink{c: c, mem: memory.NewGoAllocator()}
+}
+
+func (s *arrowSink) started() bool {
+	return s.writer != nil
+}
+
+func (s *arrowSink) Columns(columns []services.Column) error {
+	fields := make([]arrow.Field, len(columns))
+	for i, column := range columns {
+		fields[i] = arrow.Field{Name: column.Name, Type: arrowType(column.Type), Nullable: true}
+	}
+	s.schema = arrow.NewSchema(fields, nil)
+	s.builder = array.NewRecordBuilder(s.mem, s.schema)
+
+	s.c.Header("Content-Type", contentTypeArrow)
+	s.c.Header("Trailer", strings.Join(arrowTrailers, ", "))
+	s.c.Status(http.StatusOK)
+	s.writer = ipc.NewWriter(s.c.Writer, ipc.WithSchema(s.schema), ipc.WithAllocator(s.mem))
+	return nil
+}
+
+func (s *arrowSink) Row(values []interface{}) error {
+	for i, value := range values {
+		if err := appendArrowValue(s.builder.Field(i), value); err != nil {
+			return fmt.Errorf("column %s: %w", s.schema.Field(i).Name, err)
+		}
+	}
+	s.pending++
+	if s.pending >= arrowBatchSize {
+		return s.flush()
+	}
+	return nil
+}
+
+func (s *arrowSink) flush() error {
+	if s.pending == 0 {
+		return nil
+	}
+	record := s.builder.NewRecord()
+	defer record.Release()
+	s.pending = 0
+
+	if err := s.writer.Write(record); err != nil {
+		return err
+	}
+	s.c.Writer.Flush()
+	return nil
+}
+
+func (s *arrowSink) finish(result *services.QueryResult) error {
+	defer s.builder.Release()
+
+	err := s.flush()
+	if closeErr := s.writer.Close(); err == nil {
+		err = closeErr
+	}
+
+	header := s.c.Writer.Header()
+	header.Set("X-Query-Id", result.QueryID)
+	header.Set("X-Query-Status", result.Status)
+	header.Set("X-Execution-Time-Ms", strconv.FormatInt(result.ExecutionTime, 10))
+	header.Set("X-Time-To-First-Row-Ms", strconv.FormatInt(result.TimeToFirstRowMs, 10))
+	header.Set("X-Rows-Returned", strconv.FormatInt(result.RowsReturned, 10))
+	header.Set("X-Truncated", strconv.FormatBool(result.Truncated))
+	header.Set("X-Checksum", result.Checksum)
+	header.Set("X-Error", result.Error)
+	header.Set("X-Error-Type", result.ErrorType)
+	if result.Stats != nil {
+		if stats, err := json.Marshal(result.Stats); err == nil {
+			header.Set("X-Query-Stats", string(stats))
+		}
+	}
+	return err
+}
+
+// arrowType maps an engine column type to the Arrow type it's sent as
+func arrowType(databaseType string) arrow.DataType {
+	name := strings.ToLower(databaseType)
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(name), " unsigned"))
+
+	switch name {
+	case "tinyint", "smallint", "int", "integer", "bigint":
+		return arrow.PrimitiveTypes.Int64
+	case "real", "float", "double":
+		return arrow.PrimitiveTypes.Float64
+	case "boolean", "bool":
+		return arrow.FixedWidthTypes.Boolean
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
+
+func appendArrowValue(builder array.Builder, value interface{}) error {
+	if value == nil {
+		builder.AppendNull()
+		return nil
+	}
+
+	switch b := builder.(type) {
+	case *array.Int64Builder:
+		switch v := value.(type) {
+		case int64:
+			b.Append(v)
+		case int32:
+			b.Append(int64(v))
+		case int:
+			b.Append(int64(v))
+		default:
+			n, err := strconv.ParseInt(fmt.Sprint(v), 10, 64)
+			if err != nil {
+				return err
+			}
+			b.Append(n)
+		}
+	case *array.Float64Builder:
+		switch v := value.(type) {
+		case float64:
+			b.Append(v)
+		case float32:
+			b.Append(float64(v))
+		default:
+			f, err := strconv.ParseFloat(fmt.Sprint(v), 64)
+			if err != nil {
+				return err
+			}
+			b.Append(f)
+		}
+	case *array.BooleanBuilder:
+		switch v := value.(type) {
+		case bool:
+			b.Append(v)
+		default:
+			parsed, err := strconv.ParseBool(fmt.Sprint(v))
+			if err != nil {
+				return err
+			}
+			b.Append(parsed)
+		}
+	case *array.StringBuilder:
+		b.Append(formatValue(value))
+	default:
+		return fmt.Errorf("unsupported builder %T", builder)
+	}
+	return nil
+}
+
+// formatValue renders a value of a column sent as UTF-8
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []interface{}, map[string]interface{}:
+		b, err := json.Marshal(v)
+		if err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(value)
+}
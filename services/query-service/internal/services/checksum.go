@@ -0,0 +1,191 @@
+package services
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChecksumOptions control how values are normalized before they're hashed,
+// so that engines returning the same data in different representations
+// produce the same checksum
+type ChecksumOptions struct {
+	// FloatPrecision rounds floating point and decimal values to this many
+	// decimal places; negative compares them exactly
+	FloatPrecision int `json:"float_precision"`
+	// EmptyStringAsNull treats empty strings as NULL
+	EmptyStringAsNull bool `json:"empty_string_as_null"`
+	// NaNAsNull treats floating point NaN as NULL
+	NaNAsNull bool `json:"nan_as_null"`
+}
+
+// valueKind is how a column's values are normalized
+type valueKind int
+
+const (
+	kindOther valueKind = iota
+	kindNumber
+	kindBool
+	kindDate
+	kindTimestamp
+)
+
+// nullToken stands in for NULL so it can't collide with any string value
+const nullToken = "\x00"
+
+const timestampLayout = "2006-01-02 15:04:05.999999999"
+
+// checksummer computes an order-insensitive checksum of a result set: every
+// row is normalized and hashed, and the row hashes are summed, so the same
+// multiset of rows gives the same checksum in any order
+type checksummer struct {
+	options ChecksumOptions
+	kinds   []valueKind
+	rows    int64
+	sum     uint64
+	buf     []byte
+}
+
+func newChecksummer(options ChecksumOptions, columns []Column) *checksummer {
+	kinds := make([]valueKind, len(columns))
+	for i, column := range columns {
+		kinds[i] = kindOf(column.Type)
+	}
+	return &checksummer{options: options, kinds: kinds}
+}
+
+// kindOf classifies an engine column type, e.g. "decimal(10,2)", "BIGINT"
+// or "UNSIGNED INT"
+func kindOf(databaseType string) valueKind {
+	name := strings.ToLower(strings.TrimSpace(databaseType))
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.TrimPrefix(name, "unsigned ")
+
+	switch {
+	case name == "tinyint", name == "smallint", name == "int", name == "integer",
+		name == "bigint", name == "largeint", name == "real", name == "double",
+		name == "float", name == "numeric", strings.HasPrefix(name, "decimal"):
+		return kindNumber
+	case name == "boolean", name == "bool":
+		return kindBool
+	case name == "date":
+		return kindDate
+	case strings.HasPrefix(name, "timestamp"), name == "datetime":
+		return kindTimestamp
+	default:
+		return kindOther
+	}
+}
+
+func (c *checksummer) add(values []interface{}) {
+	c.buf = c.buf[:0]
+	for i, value := range values {
+		kind := kindOther
+		if i < len(c.kinds) {
+			kind = c.kinds[i]
+		}
+		c.buf = append(c.buf, c.normalize(kind, value)...)
+		c.buf = append(c.buf, 0x1f)
+	}
+	h := fnv.New64a()
+	h.Write(c.buf)
+	c.sum += h.Sum64()
+	c.rows++
+}
+
+// String formats the checksum as "<rows>:<hash>"
+func (c *checksummer) String() string {
+	return fmt.Sprintf("%d:%016x", c.rows, c.sum)
+}
+
+func (c *checksummer) normalize(kind valueKind, value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return nullToken
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case float64:
+		return c.formatFloat(v)
+	case float32:
+		return c.formatFloat(float64(v))
+	case time.Time:
+		if kind == kindDate {
+			return v.Format("2006-01-02")
+		}
+		return v.Format(timestampLayout)
+	case string:
+		return c.normalizeString(kind, v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// normalizeString handles drivers that return typed values as text, like the
+// MySQL protocol StarRocks speaks
+func (c *checksummer) normalizeString(kind valueKind, v string) string {
+	switch kind {
+	case kindNumber:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return strconv.FormatInt(i, 10)
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return c.formatFloat(f)
+		}
+	case kindBool:
+		switch strings.ToLower(v) {
+		case "1", "true":
+			return "1"
+		case "0", "false":
+			return "0"
+		}
+	case kindDate:
+		if t, err := time.Parse("2006-01-02", v); err == nil {
+			return t.Format("2006-01-02")
+		}
+	case kindTimestamp:
+		if t, err := time.Parse(timestampLayout, strings.Replace(v, "T", " ", 1)); err == nil {
+			return t.Format(timestampLayout)
+		}
+	default:
+		if v == "" && c.options.EmptyStringAsNull {
+			return nullToken
+		}
+	}
+	return v
+}
+
+func (c *checksummer) formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		if c.options.NaNAsNull {
+			return nullToken
+		}
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	// Past 1e15 the scaled value no longer fits a float64's mantissa, and
+	// such values have no fractional digits left to round anyway
+	if p := c.options.FloatPrecision; p >= 0 && math.Abs(f) < 1e15 {
+		scale := math.Pow(10, float64(p))
+		f = math.Round(f*scale) / scale
+	}
+	if f == 0 {
+		return "0" // also covers -0
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
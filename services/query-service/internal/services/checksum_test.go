@@ -0,0 +1,240 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// checksumOf checksums rows read as columns
+func checksumOf(options ChecksumOptions, columns []Column, rows [][]interface{}) string {
+	c := newChecksummer(options, columns)
+	for _, row := range rows {
+		c.add(row)
+	}
+	return c.String()
+}
+
+func TestChecksum(t *testing.T) {
+	exact := ChecksumOptions{FloatPrecision: -1}
+	columns := []Column{{Name: "id", Type: "bigint"}, {Name: "name", Type: "varchar"}, {Name: "price", Type: "decimal(10,2)"}}
+	nameColumn := []Column{{Name: "name", Type: "varchar"}}
+	doubleColumn := []Column{{Name: "value", Type: "double"}}
+	decimalColumn := []Column{{Name: "price", Type: "DECIMAL(10,2)"}}
+
+	tests := []struct {
+		name    string
+		options ChecksumOptions
+		columns []Column
+		a, b    [][]interface{}
+		equal   bool
+	}{
+		{
+			name:    "same rows in another order",
+			options: exact,
+			columns: columns,
+			a:       [][]interface{}{{int64(1), "a", 1.5}, {int64(2), "b", 2.25}, {int64(3), "c", nil}},
+			b:       [][]interface{}{{int64(3), "c", nil}, {int64(1), "a", 1.5}, {int64(2), "b", 2.25}},
+			equal:   true,
+		},
+		{
+			name:    "different rows",
+			options: exact,
+			columns: columns,
+			a:       [][]interface{}{{int64(1), "a", 1.5}},
+			b:       [][]interface{}{{int64(1), "b", 1.5}},
+		},
+		{
+			name:    "same rows with different multiplicity",
+			options: exact,
+			columns: columns,
+			a:       [][]interface{}{{int64(1), "a", 1.5}, {int64(1), "a", 1.5}, {int64(2), "b", 2.25}},
+			b:       [][]interface{}{{int64(1), "a", 1.5}, {int64(2), "b", 2.25}, {int64(2), "b", 2.25}},
+		},
+		{
+			name:    "values swapped between columns",
+			options: exact,
+			columns: []Column{{Name: "a", Type: "varchar"}, {Name: "b", Type: "varchar"}},
+			a:       [][]interface{}{{"x", "y"}},
+			b:       [][]interface{}{{"y", "x"}},
+		},
+		{
+			name:    "integers returned as text",
+			options: exact,
+			columns: columns,
+			a:       [][]interface{}{{int64(42), "a", 1.5}},
+			b:       [][]interface{}{{"42", "a", "1.5"}},
+			equal:   true,
+		},
+		{
+			name:    "empty string and NULL with EmptyStringAsNull",
+			options: ChecksumOptions{FloatPrecision: -1, EmptyStringAsNull: true},
+			columns: nameColumn,
+			a:       [][]interface{}{{""}},
+			b:       [][]interface{}{{nil}},
+			equal:   true,
+		},
+		{
+			name:    "empty string and NULL without EmptyStringAsNull",
+			options: exact,
+			columns: nameColumn,
+			a:       [][]interface{}{{""}},
+			b:       [][]interface{}{{nil}},
+		},
+		{
+			name:    "NaN and NULL with NaNAsNull",
+			options: ChecksumOptions{FloatPrecision: -1, NaNAsNull: true},
+			columns: doubleColumn,
+			a:       [][]interface{}{{math.NaN()}},
+			b:       [][]interface{}{{nil}},
+			equal:   true,
+		},
+		{
+			name:    "NaN and NULL without NaNAsNull",
+			options: exact,
+			columns: doubleColumn,
+			a:       [][]interface{}{{math.NaN()}},
+			b:       [][]interface{}{{nil}},
+		},
+		{
+			name:    "NaN as float and as text",
+			options: exact,
+			columns: doubleColumn,
+			a:       [][]interface{}{{math.NaN()}},
+			b:       [][]interface{}{{"NaN"}},
+			equal:   true,
+		},
+		{
+			name:    "decimal text differing only in formatting",
+			options: exact,
+			columns: decimalColumn,
+			a:       [][]interface{}{{"1.50"}, {"100"}},
+			b:       [][]interface{}{{"1.5"}, {"100.00"}},
+			equal:   true,
+		},
+		{
+			name:    "decimal text and float",
+			options: ChecksumOptions{FloatPrecision: 2},
+			columns: decimalColumn,
+			a:       [][]interface{}{{"1.50"}},
+			b:       [][]interface{}{{1.5}},
+			equal:   true,
+		},
+		{
+			name:    "floats equal at FloatPrecision",
+			options: ChecksumOptions{FloatPrecision: 2},
+			columns: doubleColumn,
+			a:       [][]interface{}{{0.30000000000000004}, {2.004}},
+			b:       [][]interface{}{{0.3}, {"2.0"}},
+			equal:   true,
+		},
+		{
+			name:    "floats compared exactly",
+			options: exact,
+			columns: doubleColumn,
+			a:       [][]interface{}{{0.30000000000000004}},
+			b:       [][]interface{}{{0.3}},
+		},
+		{
+			name:    "floats beyond FloatPrecision",
+			options: ChecksumOptions{FloatPrecision: 2},
+			columns: doubleColumn,
+			a:       [][]interface{}{{1.5}},
+			b:       [][]interface{}{{1.51}},
+		},
+		{
+			name:    "negative zero",
+			options: ChecksumOptions{FloatPrecision: 2},
+			columns: doubleColumn,
+			a:       [][]interface{}{{math.Copysign(0, -1)}, {-0.001}},
+			b:       [][]interface{}{{0.0}, {0.0}},
+			equal:   true,
+		},
+		{
+			name:    "dates and timestamps as time and text",
+			options: exact,
+			columns: []Column{{Name: "d", Type: "date"}, {Name: "ts", Type: "timestamp(3)"}},
+			a:       [][]interface{}{{time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 12, 30, 0, 500e6, time.UTC)}},
+			b:       [][]interface{}{{"2024-03-01", "2024-03-01T12:30:00.5"}},
+			equal:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := checksumOf(tt.options, tt.columns, tt.a)
+			b := checksumOf(tt.options, tt.columns, tt.b)
+			if (a == b) != tt.equal {
+				t.Errorf("checksums %s and %s, want equal = %v", a, b, tt.equal)
+			}
+		})
+	}
+}
+
+func TestChecksumString(t *testing.T) {
+	if got := checksumOf(ChecksumOptions{}, nil, nil); got != "0:0000000000000000" {
+		t.Errorf("empty result checksum = %s", got)
+	}
+	rows := [][]interface{}{{int64(1)}, {int64(2)}, {int64(3)}}
+	if got := checksumOf(ChecksumOptions{}, []Column{{Name: "id", Type: "INT"}}, rows); got[:2] != "3:" {
+		t.Errorf("checksum %s doesn't start with the row count", got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ChecksumOptions
+		kind    valueKind
+		value   interface{}
+		want    string
+	}{
+		{"null", ChecksumOptions{}, kindOther, nil, nullToken},
+		{"bool", ChecksumOptions{}, kindBool, true, "1"},
+		{"bool text", ChecksumOptions{}, kindBool, "false", "0"},
+		{"int32", ChecksumOptions{}, kindNumber, int32(-7), "-7"},
+		{"float32", ChecksumOptions{FloatPrecision: 2}, kindNumber, float32(2.5), "2.5"},
+		{"decimal text", ChecksumOptions{FloatPrecision: -1}, kindNumber, "1.50", "1.5"},
+		{"decimal text rounded", ChecksumOptions{FloatPrecision: 1}, kindNumber, "1.25", "1.3"},
+		{"integer text", ChecksumOptions{FloatPrecision: 2}, kindNumber, "0042", "42"},
+		{"number without a number", ChecksumOptions{}, kindNumber, "n/a", "n/a"},
+		{"scientific text", ChecksumOptions{FloatPrecision: -1}, kindNumber, "1.5e3", "1500"},
+		{"large float keeps its digits", ChecksumOptions{FloatPrecision: 2}, kindNumber, 1e16, "10000000000000000"},
+		{"positive infinity", ChecksumOptions{}, kindNumber, math.Inf(1), "+Inf"},
+		{"negative infinity", ChecksumOptions{}, kindNumber, math.Inf(-1), "-Inf"},
+		{"NaN", ChecksumOptions{}, kindNumber, math.NaN(), "NaN"},
+		{"NaN as NULL", ChecksumOptions{NaNAsNull: true}, kindNumber, math.NaN(), nullToken},
+		{"empty string", ChecksumOptions{}, kindOther, "", ""},
+		{"empty string as NULL", ChecksumOptions{EmptyStringAsNull: true}, kindOther, "", nullToken},
+		{"string", ChecksumOptions{EmptyStringAsNull: true}, kindOther, "abc", "abc"},
+		{"date text", ChecksumOptions{}, kindDate, "2024-03-01", "2024-03-01"},
+		{"timestamp text", ChecksumOptions{}, kindTimestamp, "2024-03-01 12:30:00.000", "2024-03-01 12:30:00"},
+	}
+	for _, tt := range tests {
+		c := newChecksummer(tt.options, nil)
+		if got := c.normalize(tt.kind, tt.value); got != tt.want {
+			t.Errorf("%s: normalize(%v) = %q, want %q", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	tests := map[string]valueKind{
+		"BIGINT":         kindNumber,
+		"decimal(10,2)":  kindNumber,
+		"DECIMAL64(9,2)": kindNumber,
+		"UNSIGNED INT":   kindNumber,
+		"double":         kindNumber,
+		"boolean":        kindBool,
+		"date":           kindDate,
+		"timestamp(3)":   kindTimestamp,
+		"DATETIME":       kindTimestamp,
+		"varchar(25)":    kindOther,
+		"":               kindOther,
+	}
+	for databaseType, want := range tests {
+		if got := kindOf(databaseType); got != want {
+			t.Errorf("kindOf(%q) = %d, want %d", databaseType, got, want)
+		}
+	}
+}
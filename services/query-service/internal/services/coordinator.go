@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// coordinatorClient talks to the REST API of a Trino or Presto coordinator.
+// Both expose the same /v1 endpoints and differ only in header prefix.
+type coordinatorClient struct {
+	baseURL      string
+	user         string
+	headerPrefix string
+	httpClient   *http.Client
+}
+
+func newCoordinatorClient(host, port, user, headerPrefix string) *coordinatorClient {
+	return &coordinatorClient{
+		baseURL:      fmt.Sprintf("http://%s:%s", host, port),
+		user:         user,
+		headerPrefix: headerPrefix,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type basicQueryInfo struct {
+	QueryID string `json:"queryId"`
+	State   string `json:"state"`
+	Session struct {
+		ClientInfo string `json:"clientInfo"`
+	} `json:"session"`
+}
+
+type serverInfo struct {
+	NodeVersion struct {
+		Version string `json:"version"`
+	} `json:"nodeVersion"`
+	Environment string `json:"environment"`
+	Coordinator bool   `json:"coordinator"`
+	Starting    bool   `json:"starting"`
+	Uptime      string `json:"uptime"`
+}
+
+// info returns the coordinator's /v1/info document
+func (c *coordinatorClient) info(ctx context.Context) (*serverInfo, error) {
+	var info serverInfo
+	if err := c.get(ctx, "/v1/info", &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// coordinatorStatus reports on a Trino or Presto cluster: its version and
+// uptime from the coordinator's /v1/info, and its active workers from
+// system.runtime.nodes. A coordinator that also schedules work on itself
+// isn't counted as a worker.
+func coordinatorStatus(ctx context.Context, coordinator *coordinatorClient, db *sql.DB) (*EngineStatus, error) {
+	info, err := coordinator.info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if info.Starting {
+		return nil, errors.New("coordinator is still starting")
+	}
+
+	status := &EngineStatus{Version: info.NodeVersion.Version}
+	if ms, ok := parseEngineDuration(info.Uptime); ok {
+		seconds := ms / 1000
+		status.UptimeSeconds = &seconds
+	}
+
+	var workers int
+	err = db.QueryRowContext(ctx, "SELECT count(*) FROM system.runtime.nodes WHERE NOT coordinator AND state = 'active'").Scan(&workers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count workers: %w", err)
+	}
+	status.Workers = &workers
+	return status, nil
+}
+
+// queryInfo is the part of the coordinator's /v1/query/{queryId} document
+// the service reads. Presto versions without physical input report it as raw
+// input.
+type queryInfo struct {
+	QueryID    string `json:"queryId"`
+	State      string `json:"state"`
+	QueryStats struct {
+		QueuedTime                engineDuration `json:"queuedTime"`
+		PlanningTime              engineDuration `json:"planningTime"`
+		TotalCPUTime              engineDuration `json:"totalCpuTime"`
+		PeakUserMemoryReservation engineSize     `json:"peakUserMemoryReservation"`
+		PhysicalInputDataSize     engineSize     `json:"physicalInputDataSize"`
+		PhysicalInputPositions    *int64         `json:"physicalInputPositions"`
+		RawInputDataSize          engineSize     `json:"rawInputDataSize"`
+		RawInputPositions         *int64         `json:"rawInputPositions"`
+		ProcessedInputDataSize    engineSize     `json:"processedInputDataSize"`
+		ProcessedInputPositions   *int64         `json:"processedInputPositions"`
+	} `json:"queryStats"`
+}
+
+// queryStats returns the runtime statistics of a query. The coordinator
+// only finalizes them once the query reaches a final state, which can lag
+// behind the client reading the last row, so it's polled until then and
+// the latest statistics are returned either way.
+func (c *coordinatorClient) queryStats(ctx context.Context, queryID string) (*QueryStats, error) {
+	var info queryInfo
+	for attempt := 1; ; attempt++ {
+		info = queryInfo{}
+		if err := c.get(ctx, "/v1/query/"+url.PathEscape(queryID), &info); err != nil {
+			return nil, err
+		}
+		if info.State == "FINISHED" || info.State == "FAILED" || attempt == statsAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(statsRetryInterval):
+		}
+	}
+
+	queryStats := info.QueryStats
+	stats := &QueryStats{
+		CPUTimeMs:           queryStats.TotalCPUTime.ptr(),
+		PeakMemoryBytes:     queryStats.PeakUserMemoryReservation.ptr(),
+		PhysicalInputBytes:  queryStats.PhysicalInputDataSize.ptr(),
+		PhysicalInputRows:   queryStats.PhysicalInputPositions,
+		ProcessedInputBytes: queryStats.ProcessedInputDataSize.ptr(),
+		ProcessedRows:       queryStats.ProcessedInputPositions,
+		QueuedTimeMs:        queryStats.QueuedTime.ptr(),
+		PlanningTimeMs:      queryStats.PlanningTime.ptr(),
+	}
+	if stats.PhysicalInputBytes == nil {
+		stats.PhysicalInputBytes = queryStats.RawInputDataSize.ptr()
+	}
+	if stats.PhysicalInputRows == nil {
+		stats.PhysicalInputRows = queryStats.RawInputPositions
+	}
+	return stats, nil
+}
+
+// cancel kills a query. The coordinator answers 204 whether or not the
+// query was still running.
+func (c *coordinatorClient) cancel(ctx context.Context, queryID string) error {
+	return c.do(ctx, http.MethodDelete, "/v1/query/"+url.PathEscape(queryID), nil)
+}
+
+// findQueryID looks up the ID of the query submitted with the given client
+// info tag among the coordinator's queries, optionally only those in state.
+// An empty ID is returned if there's no such query.
+func (c *coordinatorClient) findQueryID(ctx context.Context, clientInfo, state string) (string, error) {
+	path := "/v1/query"
+	if state != "" {
+		path += "?state=" + url.QueryEscape(state)
+	}
+	var queries []basicQueryInfo
+	if err := c.get(ctx, path, &queries); err != nil {
+		return "", err
+	}
+	for _, query := range queries {
+		// Presto's driver sends header values as SQL literals, quotes included
+		if strings.Trim(query.Session.ClientInfo, "'") == clientInfo {
+			return query.QueryID, nil
+		}
+	}
+	return "", nil
+}
+
+// lookupRunningQueryID starts looking up the ID of the query submitted with
+// the given client info tag and returns a function that waits for it. The
+// drivers return once the query runs, and only the running queries are
+// listed, rather than the coordinator's whole history of finished ones. A
+// query that already finished isn't found, and has an empty ID, so its
+// statistics aren't collected. The lookup runs alongside the query so it
+// doesn't add to its time.
+func (c *coordinatorClient) lookupRunningQueryID(ctx context.Context, clientInfo string) func() (string, error) {
+	done := make(chan struct{})
+	var queryID string
+	var err error
+	go func() {
+		defer close(done)
+		queryID, err = c.findQueryID(ctx, clientInfo, "RUNNING")
+	}()
+	return func() (string, error) {
+		<-done
+		return queryID, err
+	}
+}
+
+// cancelTagged kills the query submitted with the given client info tag.
+// It runs once the query's own context is done, so it uses a fresh one.
+func (c *coordinatorClient) cancelTagged(clientInfo string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.httpClient.Timeout)
+	defer cancel()
+
+	queryID, err := c.findQueryID(ctx, clientInfo, "")
+	if err != nil {
+		return err
+	}
+	if queryID == "" {
+		return fmt.Errorf("no query tagged %q found on coordinator", clientInfo)
+	}
+	return c.cancel(ctx, queryID)
+}
+
+func (c *coordinatorClient) get(ctx context.Context, path string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, path, out)
+}
+
+func (c *coordinatorClient) do(ctx context.Context, method, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set(c.headerPrefix+"User", c.user)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call coordinator: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("coordinator returned %s for %s %s", resp.Status, method, path)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode coordinator response: %w", err)
+	}
+	return nil
+}
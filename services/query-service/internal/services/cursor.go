@@ -0,0 +1,55 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+)
+
+// Cursor is an open result set together with the engine's identifier for
+// the query that produced it. Some engines only reveal the identifier once
+// the result set is closed, so QueryID is valid after Close.
+type Cursor struct {
+	*sql.Rows
+
+	resolveID func() (string, error)
+	release   func()
+
+	queryID string
+	idErr   error
+	closed  bool
+}
+
+// Close closes the result set, resolves the query ID and releases any
+// connection held for the cursor
+func (c *Cursor) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	err := c.Rows.Close()
+	if c.resolveID != nil {
+		c.queryID, c.idErr = c.resolveID()
+	}
+	if c.release != nil {
+		c.release()
+	}
+	return err
+}
+
+// QueryID returns the engine-assigned query ID, or an error if the engine
+// couldn't be asked for it
+func (c *Cursor) QueryID() (string, error) {
+	return c.queryID, c.idErr
+}
+
+// generateID returns a random identifier used to tag queries so they can be
+// found again on the engine
+func generateID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"query-service/internal/config"
+)
+
+var ErrInvalidTest = errors.New("invalid engine test")
+
+// Engine test step statuses
+const (
+	StepPassed  = "passed"
+	StepFailed  = "failed"
+	StepSkipped = "skipped"
+)
+
+// engineTestTimeout bounds a whole engine test
+const engineTestTimeout = 2 * time.Minute
+
+// identifierPattern and tableNamePattern accept the names spliced into the
+// test's statements, table names qualified by up to a catalog and a schema
+var (
+	identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	tableNamePattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*){0,2}$`)
+)
+
+// EngineTestOptions override an engine's configuration for a single test.
+// Empty fields keep the configured value, except that the configured user
+// and password are dropped when Host or Port points elsewhere.
+type EngineTestOptions struct {
+	Host     string   `json:"host"`
+	Port     string   `json:"port"`
+	User     string   `json:"user"`
+	Password string   `json:"password"`
+	Catalog  string   `json:"catalog"`
+	Schema   string   `json:"schema"`
+	Tables   []string `json:"tables"`
+}
+
+// EngineTestReport is the step by step outcome of testing an engine. Steps
+// after a failed connection or probe query are skipped; the others run
+// regardless so one report shows every problem.
+type EngineTestReport struct {
+	Engine     string           `json:"engine"`
+	Type       string           `json:"type"`
+	Host       string           `json:"host"`
+	Port       string           `json:"port"`
+	User       string           `json:"user"`
+	Catalog    string           `json:"catalog"`
+	Schema     string           `json:"schema"`
+	Success    bool             `json:"success"`
+	DurationMs int64            `json:"duration_ms"`
+	Steps      []EngineTestStep `json:"steps"`
+}
+
+// EngineTestStep is one check of an engine test. Error is the engine's
+// error as returned.
+type EngineTestStep struct {
+	Name       string   `json:"name"`
+	Status     string   `json:"status"`
+	DurationMs int64    `json:"duration_ms"`
+	Detail     string   `json:"detail,omitempty"`
+	Items      []string `json:"items,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// TestEngine opens a fresh connection to an engine with its configuration,
+// overridden by opts, and checks it step by step: connecting, running a
+// probe query, listing catalogs and the schemas of its catalog, and reading
+// a row of each benchmark table. The connection isn't shared with the
+// registered engine, so supplied credentials never affect other queries.
+func (q *QueryExecutor) TestEngine(ctx context.Context, name string, opts EngineTestOptions) (*EngineTestReport, error) {
+	cfg, ok := q.registry.Config(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrEngineNotFound, name)
+	}
+	cfg = opts.apply(cfg)
+	if cfg.Catalog != "" && !identifierPattern.MatchString(cfg.Catalog) {
+		return nil, fmt.Errorf("%w: invalid catalog name %q", ErrInvalidTest, cfg.Catalog)
+	}
+	for _, table := range cfg.Tables {
+		if !tableNamePattern.MatchString(table) {
+			return nil, fmt.Errorf("%w: invalid table name %q", ErrInvalidTest, table)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, engineTestTimeout)
+	defer cancel()
+
+	report := &EngineTestReport{
+		Engine:  cfg.Name,
+		Type:    cfg.Type,
+		Host:    cfg.Host,
+		Port:    cfg.Port,
+		User:    cfg.User,
+		Catalog: cfg.Catalog,
+		Schema:  cfg.Schema,
+	}
+	start := time.Now()
+	defer func() {
+		report.DurationMs = time.Since(start).Milliseconds()
+		report.Success = true
+		for _, step := range report.Steps {
+			if step.Status == StepFailed {
+				report.Success = false
+			}
+		}
+	}()
+
+	var engine Engine
+	connected := report.run("connect", func(step *EngineTestStep) error {
+		var err error
+		engine, err = openEngine(ctx, cfg, q.logger)
+		step.Detail = fmt.Sprintf("%s@%s:%s", cfg.User, cfg.Host, cfg.Port)
+		return err
+	})
+	if connected {
+		defer engine.Close()
+	}
+
+	probed := connected && report.run("probe", func(step *EngineTestStep) error {
+		values, err := queryColumn(ctx, engine, "SELECT 1")
+		if err == nil {
+			step.Detail = "SELECT 1 returned " + strings.Join(values, ", ")
+		}
+		return err
+	})
+	if !probed {
+		if !connected {
+			report.skip("probe")
+		}
+		report.skip("catalogs", "schemas")
+		for _, table := range cfg.Tables {
+			report.skip("read " + table)
+		}
+		return report, nil
+	}
+
+	report.run("catalogs", func(step *EngineTestStep) error {
+		catalogs, err := queryColumn(ctx, engine, "SHOW CATALOGS")
+		step.Items = catalogs
+		return err
+	})
+
+	if cfg.Catalog == "" {
+		report.skip("schemas")
+	} else {
+		report.run("schemas", func(step *EngineTestStep) error {
+			statement := "SHOW SCHEMAS FROM " + cfg.Catalog
+			if cfg.Type == "starrocks" {
+				statement = "SHOW DATABASES FROM " + cfg.Catalog
+			}
+			schemas, err := queryColumn(ctx, engine, statement)
+			if err != nil {
+				return err
+			}
+			step.Items = schemas
+			step.Detail = "catalog " + cfg.Catalog
+			if cfg.Schema != "" && !containsFold(schemas, cfg.Schema) {
+				return fmt.Errorf("schema %q not found in catalog %q", cfg.Schema, cfg.Catalog)
+			}
+			return nil
+		})
+	}
+
+	for _, table := range cfg.Tables {
+		report.run("read "+table, func(step *EngineTestStep) error {
+			values, err := queryColumn(ctx, engine, "SELECT * FROM "+table+" LIMIT 1")
+			if err == nil {
+				step.Detail = fmt.Sprintf("read %d row(s)", len(values))
+			}
+			return err
+		})
+	}
+	return report, nil
+}
+
+// apply overrides cfg with the options that are set. The configured
+// credentials are only kept for the configured host and port: another
+// server gets the user and password of the options, if any, and no
+// external catalogs, whose object store keys StarRocks would be sent, so a
+// test can't hand them to a server of the caller's choosing.
+func (o EngineTestOptions) apply(cfg config.EngineConfig) config.EngineConfig {
+	if (o.Host != "" && o.Host != cfg.Host) || (o.Port != "" && o.Port != cfg.Port) {
+		cfg.User, cfg.Password = "", ""
+		cfg.ExternalCatalogs = nil
+	}
+	cfg.Host = firstSet(o.Host, cfg.Host)
+	cfg.Port = firstSet(o.Port, cfg.Port)
+	cfg.User = firstSet(o.User, cfg.User)
+	cfg.Password = firstSet(o.Password, cfg.Password)
+	cfg.Catalog = firstSet(o.Catalog, cfg.Catalog)
+	cfg.Schema = firstSet(o.Schema, cfg.Schema)
+	if len(o.Tables) > 0 {
+		cfg.Tables = o.Tables
+	}
+	return cfg
+}
+
+// run times a step and records it as passed or failed by its error
+func (r *EngineTestReport) run(name string, check func(step *EngineTestStep) error) bool {
+	step := EngineTestStep{Name: name, Status: StepPassed}
+	start := time.Now()
+	err := check(&step)
+	step.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		step.Status = StepFailed
+		step.Error = err.Error()
+	}
+	r.Steps = append(r.Steps, step)
+	return err == nil
+}
+
+// skip records steps that couldn't run
+func (r *EngineTestReport) skip(names ...string) {
+	for _, name := range names {
+		r.Steps = append(r.Steps, EngineTestStep{Name: name, Status: StepSkipped})
+	}
+}
+
+// queryColumn runs a statement and returns the first column of every row
+func queryColumn(ctx context.Context, engine Engine, statement string) ([]string, error) {
+	cursor, err := engine.Execute(ctx, statement, ExecOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	columns, err := cursor.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := []string{}
+	for cursor.Next() {
+		row := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := cursor.Scan(dest...); err != nil {
+			return nil, err
+		}
+		if b, ok := row[0].([]byte); ok {
+			row[0] = string(b)
+		}
+		values = append(values, fmt.Sprint(row[0]))
+	}
+	return values, cursor.Err()
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstSet(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	"query-service/internal/config"
+)
+
+func TestEngineTestOptionsApply(t *testing.T) {
+	configured := config.EngineConfig{
+		Host:             "starrocks-fe",
+		Port:             "9030",
+		User:             "root",
+		Password:         "secret",
+		Catalog:          "hive",
+		ExternalCatalogs: []config.ExternalCatalog{{Name: "hive", Type: "hive", S3AccessKey: "key", S3SecretKey: "secret"}},
+	}
+
+	tests := []struct {
+		name     string
+		opts     EngineTestOptions
+		user     string
+		password string
+		catalogs int
+		host     string
+		port     string
+	}{
+		{"nothing overridden", EngineTestOptions{}, "root", "secret", 1, "starrocks-fe", "9030"},
+		{"credentials overridden", EngineTestOptions{User: "benchmark", Password: "other"}, "benchmark", "other", 1, "starrocks-fe", "9030"},
+		{"same host and port", EngineTestOptions{Host: "starrocks-fe", Port: "9030", Catalog: "iceberg"}, "root", "secret", 1, "starrocks-fe", "9030"},
+		{"other host", EngineTestOptions{Host: "attacker.example"}, "", "", 0, "attacker.example", "9030"},
+		{"other port", EngineTestOptions{Port: "3306"}, "", "", 0, "starrocks-fe", "3306"},
+		{"other host with credentials", EngineTestOptions{Host: "staging-fe", User: "benchmark"}, "benchmark", "", 0, "staging-fe", "9030"},
+	}
+	for _, tt := range tests {
+		cfg := tt.opts.apply(configured)
+		if cfg.User != tt.user || cfg.Password != tt.password {
+			t.Errorf("%s: credentials = %q, %q, want %q, %q", tt.name, cfg.User, cfg.Password, tt.user, tt.password)
+		}
+		if len(cfg.ExternalCatalogs) != tt.catalogs {
+			t.Errorf("%s: %d external catalogs, want %d", tt.name, len(cfg.ExternalCatalogs), tt.catalogs)
+		}
+		if cfg.Host != tt.host || cfg.Port != tt.port {
+			t.Errorf("%s: address = %s:%s, want %s:%s", tt.name, cfg.Host, cfg.Port, tt.host, tt.port)
+		}
+	}
+}
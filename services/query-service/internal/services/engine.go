@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+)
+
+// Engine is a query engine the service can run SQL on. TrinoService,
+// PrestoService and StarRocksService are the built-in implementations.
+type Engine interface {
+	// Name is the name the engine is registered under
+	Name() string
+	// Type is the kind of engine, e.g. "trino"
+	Type() string
+	// Execute starts a query and returns a cursor over its rows. If ctx
+	// ends before the cursor is closed the query is killed on the engine.
+	Execute(ctx context.Context, query string, opts ExecOptions) (*Cursor, error)
+	// Explain returns the plan of a query with opts applied, running it
+	// first if analyze is set
+	Explain(ctx context.Context, query string, analyze bool, opts ExecOptions) (string, error)
+	// Health reports whether the engine accepts connections
+	Health(ctx context.Context) error
+	// Version returns the engine's version string
+	Version(ctx context.Context) (string, error)
+	// Status reports the engine's version, uptime and workers as the
+	// engine itself sees them
+	Status(ctx context.Context) (*EngineStatus, error)
+	// Cancel kills a running query by its engine query ID
+	Cancel(ctx context.Context, queryID string) error
+	// QueryStats returns the engine's runtime statistics of a finished
+	// query by its engine query ID
+	QueryStats(ctx context.Context, queryID string) (*QueryStats, error)
+	// Capabilities describes the optional features the engine supports
+	Capabilities() Capabilities
+	// Close releases the engine's connections
+	Close() error
+}
+
+// ExecOptions are per-query settings applied on top of the engine's
+// configured defaults. Empty fields leave the default in place.
+type ExecOptions struct {
+	Catalog           string
+	Schema            string
+	SessionProperties map[string]string
+}
+
+// IsZero reports whether the options change nothing
+func (o ExecOptions) IsZero() bool {
+	return o.Catalog == "" && o.Schema == "" && len(o.SessionProperties) == 0
+}
+
+// sessionHeader formats session properties as the comma separated
+// name=value list used by the Trino and Presto session headers. Names are
+// sorted so equal property sets format the same way.
+func sessionHeader(properties map[string]string) string {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+"="+properties[name])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Capabilities describes the optional features of an engine
+type Capabilities struct {
+	ExplainJSON    bool `json:"explain_json"`
+	ExplainAnalyze bool `json:"explain_analyze"`
+	Cancel         bool `json:"cancel"`
+	QueryInfo      bool `json:"query_info"`
+}
+
+// coordinatorCapabilities are shared by Trino and Presto, which expose the
+// same coordinator REST API
+var coordinatorCapabilities = Capabilities{
+	ExplainJSON:    true,
+	ExplainAnalyze: true,
+	Cancel:         true,
+	QueryInfo:      true,
+}
+
+// explainStatement builds the EXPLAIN statement for Trino and Presto
+func explainStatement(query string, analyze bool) string {
+	if analyze {
+		return "EXPLAIN ANALYZE " + query
+	}
+	return "EXPLAIN (FORMAT JSON) " + query
+}
+
+// readText joins the single text column of every row, which is how engines
+// return EXPLAIN output
+func readText(rows *sql.Rows) (string, error) {
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// scanRowMaps reads every row into a map keyed by column name. It's meant
+// for SHOW statements whose columns vary between engine versions.
+func scanRowMaps(rows *sql.Rows) ([]map[string]string, error) {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []map[string]string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]string, len(columns))
+		for i, column := range columns {
+			row[column] = values[i].String
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
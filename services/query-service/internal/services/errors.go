@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/trinodb/trino-go-client/trino"
+)
+
+// Error categories reported for failed queries
+const (
+	ErrorTimeout           = "timeout"
+	ErrorCancelled         = "cancelled"
+	ErrorSyntax            = "syntax"
+	ErrorResourceExhausted = "resource_exhausted"
+	ErrorConnection        = "connection"
+	ErrorQuery             = "query"
+)
+
+// QueryError is a driver error tagged with the category it falls into
+type QueryError struct {
+	Category string
+	Err      error
+}
+
+func (e *QueryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// classifyError maps a driver error onto one of the error categories.
+// Trino errors carry a structured name; Presto and StarRocks only expose a
+// message, so those fall back to matching well-known fragments.
+func classifyError(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorTimeout
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorCancelled
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorTimeout
+	}
+
+	var trinoErr *trino.ErrTrino
+	if errors.As(err, &trinoErr) {
+		switch {
+		case trinoErr.ErrorName == "EXCEEDED_TIME_LIMIT":
+			return ErrorTimeout
+		case trinoErr.ErrorName == "USER_CANCELED":
+			return ErrorCancelled
+		case trinoErr.ErrorName == "SYNTAX_ERROR":
+			return ErrorSyntax
+		case trinoErr.ErrorType == "INSUFFICIENT_RESOURCES":
+			return ErrorResourceExhausted
+		}
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1064, 1149: // ER_PARSE_ERROR, ER_SYNTAX_ERROR
+			return ErrorSyntax
+		case 3024: // ER_QUERY_TIMEOUT
+			return ErrorTimeout
+		case 1317: // ER_QUERY_INTERRUPTED, raised by KILL QUERY
+			return ErrorCancelled
+		case 1037, 1038, 1041: // ER_OUTOFMEMORY, ER_OUT_OF_SORTMEMORY, ER_OUT_OF_RESOURCES
+			return ErrorResourceExhausted
+		}
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) {
+		return ErrorConnection
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return ErrorConnection
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case containsAny(msg, "exceeded_time_limit", "exceeded maximum time limit", "query timeout", "timed out", "timeout"):
+		return ErrorTimeout
+	case containsAny(msg, "user_canceled", "query was canceled", "query killed"):
+		return ErrorCancelled
+	case containsAny(msg, "syntax_error", "parsingexception", "mismatched input", "syntax error", "getting syntax error"):
+		return ErrorSyntax
+	case containsAny(msg, "exceeded_memory_limit", "exceeded_local_memory_limit", "insufficient_resources", "memory exceed", "exceed limit", "out of memory", "query_queue_full", "too many queued"):
+		return ErrorResourceExhausted
+	case containsAny(msg, "connection refused", "connection reset", "no such host", "broken pipe", "unexpected eof"):
+		return ErrorConnection
+	}
+
+	return ErrorQuery
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,89 +2,498 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/sirupsen/logrus"
+	"query-service/internal/config"
 	"query-service/pkg/logger"
+	"query-service/pkg/metrics"
+)
+
+var (
+	ErrQueryNotFound      = errors.New("query not found")
+	ErrHandleInUse        = errors.New("query handle already in use")
+	ErrExplainUnsupported = errors.New("engine does not support this kind of explain")
+)
+
+// Plan formats reported in a PlanResult
+const (
+	PlanFormatJSON = "json"
+	PlanFormatText = "text"
+)
+
+// Query statuses reported in a QueryResult
+const (
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+	StatusTimedOut  = "timed_out"
 )
 
 type QueryExecutor struct {
-	trinoService     *TrinoService
-	prestoService    *PrestoService
-	starrocksService *StarRocksService
-	logger           *logger.Logger
+	registry *Registry
+	config   config.QueryConfig
+	logger   *logger.Logger
+
+	mu      sync.Mutex
+	running map[string]*runningQuery
+}
+
+// runningQuery is a query in flight, tracked so it can be cancelled by its
+// handle
+type runningQuery struct {
+	info      RunningQuery
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// RunningQuery describes a query in flight
+type RunningQuery struct {
+	Handle    string    `json:"handle"`
+	Engine    string    `json:"engine"`
+	SQL       string    `json:"sql"`
+	StartedAt time.Time `json:"started_at"`
 }
 
 // NewQueryExecutor creates a new QueryExecutor
-func NewQueryExecutor(trino *TrinoService, presto *PrestoService, starrocks *StarRocksService, logger *logger.Logger) *QueryExecutor {
+func NewQueryExecutor(registry *Registry, config config.QueryConfig, logger *logger.Logger) *QueryExecutor {
 	return &QueryExecutor{
-		trinoService:     trino,
-		prestoService:    presto,
-		starrocksService: starrocks,
-		logger:           logger,
+		registry: registry,
+		config:   config,
+		logger:   logger,
+		running:  make(map[string]*runningQuery),
 	}
 }
 
-func (q *QueryExecutor) ExecuteTrinoQuery(ctx context.Context, query string) (*QueryResult, error) {
-	q.logger.WithFields(logrus.Fields{
-		"engine": "trino",
-		"query":  query,
-	}).Info("Executing Trino query")
-	
-	// TODO: Implement actual Trino query execution
-	return &QueryResult{
-		QueryID:     "trino-" + generateID(),
-		Status:      "completed",
-		Engine:      "trino",
-		ExecutionTime: 1500,
-		RowsReturned: 100,
-	}, nil
-}
-
-func (q *QueryExecutor) ExecutePrestoQuery(ctx context.Context, query string) (*QueryResult, error) {
-	q.logger.WithFields(logrus.Fields{
-		"engine": "presto",
-		"query":  query,
-	}).Info("Executing Presto query")
-	
-	// TODO: Implement actual Presto query execution
-	return &QueryResult{
-		QueryID:     "presto-" + generateID(),
-		Status:      "completed",
-		Engine:      "presto",
-		ExecutionTime: 1800,
-		RowsReturned: 100,
-	}, nil
-}
-
-func (q *QueryExecutor) ExecuteStarRocksQuery(ctx context.Context, query string) (*QueryResult, error) {
-	if q.starrocksService == nil {
-		return nil, fmt.Errorf("StarRocks service not available")
-	}
-	
-	q.logger.WithFields(logrus.Fields{
-		"engine": "starrocks",
+// Engines returns the registry of engines queries can run on
+func (q *QueryExecutor) Engines() *Registry {
+	return q.registry
+}
+
+// Execution describes a query to run
+type Execution struct {
+	// Handle identifies the query to Cancel while it runs. One is generated
+	// if it's empty.
+	Handle  string
+	Engine  string
+	SQL     string
+	Options ExecOptions
+	// Timeout bounds the whole execution, including reading the rows. Zero
+	// means the configured default timeout.
+	Timeout time.Duration
+	// Sink receives the columns and up to MaxRows rows as they are read. A
+	// negative MaxRows means no limit. Rows past the limit, or every row if
+	// Sink is nil, are still read from the engine and counted but not
+	// scanned.
+	Sink    RowSink
+	MaxRows int64
+	// Checksum, if set, makes the result carry a checksum of every row read,
+	// including the ones past MaxRows
+	Checksum *ChecksumOptions
+}
+
+// RowSink receives the rows of a query as they are read, so results can be
+// streamed to the client instead of buffered
+type RowSink interface {
+	Columns(columns []Column) error
+	Row(values []interface{}) error
+}
+
+// Column describes a column of a result set
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Execute runs a query on the named engine, drains every row and times it.
+// Wall-clock time is split into time to first row, which covers queueing,
+// planning and the first split, and total time until the last row is read.
+// Time spent in the sink and on the checksum is included, so callers that
+// only need timings should leave both nil. A failed query still returns its QueryResult
+// alongside a *QueryError.
+func (q *QueryExecutor) Execute(ctx context.Context, execution Execution) (*QueryResult, error) {
+	engine, err := q.registry.Get(execution.Engine)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := execution.Timeout
+	if timeout <= 0 {
+		timeout = q.config.DefaultTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	query := execution.SQL
+	ctx, running, err := q.track(ctx, execution.Handle, engine.Name(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer q.untrack(running)
+
+	log := q.logger.WithFields(logrus.Fields{
+		"engine": engine.Name(),
+		"handle": running.info.Handle,
 		"query":  query,
-	}).Info("Executing StarRocks query")
-	
-	// TODO: Implement actual StarRocks query execution
-	return &QueryResult{
-		QueryID:     "starrocks-" + generateID(),
-		Status:      "completed",
-		Engine:      "starrocks",
-		ExecutionTime: 1200,
-		RowsReturned: 100,
-	}, nil
+	})
+	log.Info("Executing query")
+
+	result := &QueryResult{Handle: running.info.Handle, Engine: engine.Name()}
+	start := time.Now()
+
+	cursor, err := engine.Execute(ctx, query, execution.Options)
+	if err != nil {
+		return q.finish(ctx, log, running, result, query, start, time.Now(), err)
+	}
+
+	// The query ends once its rows are read. Closing the cursor resolves
+	// the engine query ID, a round trip that's not part of the query.
+	err = q.drain(cursor, execution, result, start)
+	end := time.Now()
+	if closeErr := cursor.Close(); err == nil {
+		err = closeErr
+	}
+
+	queryID, idErr := cursor.QueryID()
+	if idErr != nil {
+		log.WithError(idErr).Warn("Failed to resolve engine query ID")
+	}
+	result.QueryID = queryID
+
+	result, err = q.finish(ctx, log, running, result, query, start, end, err)
+	if err == nil {
+		q.collectStats(ctx, log, engine, result)
+	}
+	return result, err
 }
 
-type QueryResult struct {
-	QueryID       string `json:"query_id"`
-	Status        string `json:"status"`
+// collectStats adds the engine's runtime statistics to a completed query's
+// result. It runs after the query was timed, and failing to get the
+// statistics is logged but doesn't fail the query.
+func (q *QueryExecutor) collectStats(ctx context.Context, log *logrus.Entry, engine Engine, result *QueryResult) {
+	if !q.config.CollectStats || !engine.Capabilities().QueryInfo || result.QueryID == "" {
+		return
+	}
+	// The query's own deadline may be nearly used up by now
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), statsTimeout)
+	defer cancel()
+
+	stats, err := engine.QueryStats(ctx, result.QueryID)
+	if err != nil {
+		log.WithError(err).WithField("query_id", result.QueryID).Warn("Failed to get query stats")
+		return
+	}
+	result.Stats = stats
+}
+
+// Explanation describes a query to explain
+type Explanation struct {
+	// Handle identifies the EXPLAIN to Cancel while it runs, which matters
+	// for EXPLAIN ANALYZE as it runs the query. One is generated if it's
+	// empty.
+	Handle  string
+	Engine  string
+	SQL     string
+	Options ExecOptions
+	// Analyze runs the query and reports the plan with its runtime
+	// statistics instead of only planning it
+	Analyze bool
+	// Timeout bounds the EXPLAIN. Zero means the configured default timeout.
+	Timeout time.Duration
+}
+
+// PlanResult is the plan of a query
+type PlanResult struct {
+	Handle        string `json:"handle"`
 	Engine        string `json:"engine"`
+	Analyze       bool   `json:"analyze"`
+	Format        string `json:"format"` // "json" or "text"
+	Plan          string `json:"plan"`
 	ExecutionTime int64  `json:"execution_time_ms"`
-	RowsReturned  int64  `json:"rows_returned"`
-	Error         string `json:"error,omitempty"`
 }
 
-func generateID() string {
-	return fmt.Sprintf("%d", 12345) // TODO: Use proper UUID
+// Explain returns the plan of a query on the named engine. Trino and
+// Presto plan in JSON while EXPLAIN ANALYZE and StarRocks return text.
+func (q *QueryExecutor) Explain(ctx context.Context, explanation Explanation) (*PlanResult, error) {
+	engine, err := q.registry.Get(explanation.Engine)
+	if err != nil {
+		return nil, err
+	}
+	capabilities := engine.Capabilities()
+	if explanation.Analyze && !capabilities.ExplainAnalyze {
+		return nil, fmt.Errorf("%w: %s has no EXPLAIN ANALYZE", ErrExplainUnsupported, engine.Name())
+	}
+
+	timeout := explanation.Timeout
+	if timeout <= 0 {
+		timeout = q.config.DefaultTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	ctx, running, err := q.track(ctx, explanation.Handle, engine.Name(), explanation.SQL)
+	if err != nil {
+		return nil, err
+	}
+	defer q.untrack(running)
+
+	result := &PlanResult{
+		Handle:  running.info.Handle,
+		Engine:  engine.Name(),
+		Analyze: explanation.Analyze,
+		Format:  PlanFormatText,
+	}
+	if capabilities.ExplainJSON && !explanation.Analyze {
+		result.Format = PlanFormatJSON
+	}
+
+	start := time.Now()
+	plan, err := engine.Explain(ctx, explanation.SQL, explanation.Analyze, explanation.Options)
+	result.ExecutionTime = time.Since(start).Milliseconds()
+	if err != nil {
+		q.logger.WithError(err).WithFields(logrus.Fields{
+			"engine":  engine.Name(),
+			"handle":  running.info.Handle,
+			"analyze": explanation.Analyze,
+		}).Warn("Explain failed")
+		return nil, &QueryError{Category: classifyError(err), Err: err}
+	}
+	result.Plan = plan
+	return result, nil
+}
+
+// Cancel stops the query with the given handle. Cancelling its context
+// makes the engine kill it, and its result is reported as cancelled. A
+// query the executor isn't tracking, e.g. one started outside the service,
+// can be killed by its engine query ID if engineName is given.
+func (q *QueryExecutor) Cancel(ctx context.Context, queryID, engineName string) error {
+	q.mu.Lock()
+	running, ok := q.running[queryID]
+	if ok {
+		running.cancelled = true
+		running.cancel()
+	}
+	q.mu.Unlock()
+	if ok {
+		q.logger.WithField("handle", queryID).Info("Cancelled query")
+		return nil
+	}
+
+	if engineName == "" {
+		return fmt.Errorf("%w: %s", ErrQueryNotFound, queryID)
+	}
+	engine, err := q.registry.Get(engineName)
+	if err != nil {
+		return err
+	}
+	return engine.Cancel(ctx, queryID)
+}
+
+// Running lists the queries in flight, oldest first
+func (q *QueryExecutor) Running() []RunningQuery {
+	q.mu.Lock()
+	queries := make([]RunningQuery, 0, len(q.running))
+	for _, running := range q.running {
+		queries = append(queries, running.info)
+	}
+	q.mu.Unlock()
+
+	sort.Slice(queries, func(i, j int) bool {
+		return queries[i].StartedAt.Before(queries[j].StartedAt)
+	})
+	return queries
+}
+
+// track registers a query under its handle and returns the context it
+// should run with
+func (q *QueryExecutor) track(ctx context.Context, handle, engine, query string) (context.Context, *runningQuery, error) {
+	if handle == "" {
+		handle = generateID()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	running := &runningQuery{
+		info: RunningQuery{
+			Handle:    handle,
+			Engine:    engine,
+			SQL:       query,
+			StartedAt: time.Now(),
+		},
+		cancel: cancel,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, exists := q.running[handle]; exists {
+		cancel()
+		return nil, nil, fmt.Errorf("%w: %s", ErrHandleInUse, handle)
+	}
+	q.running[handle] = running
+	return ctx, running, nil
+}
+
+func (q *QueryExecutor) untrack(running *runningQuery) {
+	q.mu.Lock()
+	delete(q.running, running.info.Handle)
+	q.mu.Unlock()
+	running.cancel()
+}
+
+func (q *QueryExecutor) wasCancelled(running *runningQuery) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return running.cancelled
+}
+
+// drain reads every row of cursor, handing up to MaxRows of them to the sink
+// and checksumming all of them if asked to
+func (q *QueryExecutor) drain(cursor *Cursor, execution Execution, result *QueryResult, start time.Time) error {
+	sink := execution.Sink
+	var checksum *checksummer
+	var values, dest []interface{}
+	if sink != nil || execution.Checksum != nil {
+		types, err := cursor.ColumnTypes()
+		if err != nil {
+			return err
+		}
+		columns := make([]Column, len(types))
+		for i, t := range types {
+			columns[i] = Column{Name: t.Name(), Type: t.DatabaseTypeName()}
+		}
+		if sink != nil {
+			if err := sink.Columns(columns); err != nil {
+				return err
+			}
+		}
+		if execution.Checksum != nil {
+			checksum = newChecksummer(*execution.Checksum, columns)
+		}
+
+		values = make([]interface{}, len(columns))
+		dest = make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+	}
+
+	for cursor.Next() {
+		if result.RowsReturned == 0 {
+			result.TimeToFirstRowMs = time.Since(start).Milliseconds()
+		}
+		result.RowsReturned++
+
+		toSink := sink != nil
+		if toSink && execution.MaxRows >= 0 && result.RowsReturned > execution.MaxRows {
+			result.Truncated = true
+			toSink = false
+		}
+		if !toSink && checksum == nil {
+			continue
+		}
+		if err := cursor.Scan(dest...); err != nil {
+			return err
+		}
+		for i, value := range values {
+			if b, ok := value.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		if checksum != nil {
+			checksum.add(values)
+		}
+		if toSink {
+			if err := sink.Row(values); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if checksum != nil {
+		result.Checksum = checksum.String()
+	}
+	return nil
+}
+
+// finish records the outcome of a query that ran from start to end
+func (q *QueryExecutor) finish(ctx context.Context, log *logrus.Entry, running *runningQuery, result *QueryResult, query string, start, end time.Time, err error) (*QueryResult, error) {
+	elapsed := end.Sub(start)
+	result.ExecutionTime = elapsed.Milliseconds()
+	metrics.RecordQueryExecution(result.Engine, statementType(query), elapsed.Seconds(), err == nil)
+
+	if err != nil {
+		// Once the context is done the driver's error says little about
+		// why, so the context decides between cancelled and timed out
+		result.ErrorType = classifyError(err)
+		switch {
+		case q.wasCancelled(running):
+			result.ErrorType = ErrorCancelled
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			result.ErrorType = ErrorTimeout
+		case errors.Is(ctx.Err(), context.Canceled):
+			result.ErrorType = ErrorCancelled
+		}
+
+		switch result.ErrorType {
+		case ErrorCancelled:
+			result.Status = StatusCancelled
+		case ErrorTimeout:
+			result.Status = StatusTimedOut
+		default:
+			result.Status = StatusFailed
+		}
+		result.Error = err.Error()
+		log.WithError(err).WithFields(logrus.Fields{
+			"status":     result.Status,
+			"error_type": result.ErrorType,
+		}).Warn("Query failed")
+		return result, &QueryError{Category: result.ErrorType, Err: err}
+	}
+
+	result.Status = StatusCompleted
+	log.WithFields(logrus.Fields{
+		"query_id":             result.QueryID,
+		"rows":                 result.RowsReturned,
+		"execution_time_ms":    result.ExecutionTime,
+		"time_to_first_row_ms": result.TimeToFirstRowMs,
+	}).Info("Query completed")
+	return result, nil
+}
+
+type QueryResult struct {
+	Handle           string `json:"handle"`
+	QueryID          string `json:"query_id"`
+	Status           string `json:"status"`
+	Engine           string `json:"engine"`
+	ExecutionTime    int64  `json:"execution_time_ms"`
+	TimeToFirstRowMs int64  `json:"time_to_first_row_ms"`
+	RowsReturned     int64  `json:"rows_returned"`
+	Truncated        bool   `json:"truncated,omitempty"`
+	Checksum         string `json:"checksum,omitempty"`
+	Error            string `json:"error,omitempty"`
+	ErrorType        string `json:"error_type,omitempty"`
+	// Stats are the engine's runtime statistics, when it reports them
+	Stats *QueryStats `json:"stats,omitempty"`
+}
+
+// statementType returns the lower-cased leading keyword of a query, which
+// is used to label metrics
+func statementType(query string) string {
+	fields := strings.Fields(strings.TrimLeft(query, "( \t\r\n"))
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	return strings.ToLower(fields[0])
 }
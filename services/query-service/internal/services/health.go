@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"query-service/internal/config"
+	"query-service/pkg/logger"
+	"query-service/pkg/metrics"
+)
+
+// Engine health statuses reported in an EngineHealth. An unavailable
+// engine hasn't connected yet, see Registry.
+const (
+	HealthUnknown     = "unknown"
+	HealthHealthy     = "healthy"
+	HealthUnhealthy   = "unhealthy"
+	HealthUnavailable = "unavailable"
+)
+
+// EngineStatus is what an engine reports about itself. Fields the engine
+// doesn't report are nil.
+type EngineStatus struct {
+	Version       string `json:"version"`
+	UptimeSeconds *int64 `json:"uptime_seconds,omitempty"`
+	Workers       *int   `json:"workers,omitempty"` // active worker nodes
+}
+
+// EngineHealth is the outcome of the latest health check of an engine.
+// LatencyMs is the round trip of the connection check alone.
+type EngineHealth struct {
+	Engine string `json:"engine"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	EngineStatus
+	LatencyMs *int64     `json:"latency_ms,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+}
+
+// HealthProber checks every registered engine in the background, keeping
+// the latest result of each and reporting it through
+// metrics.SetEngineHealth
+type HealthProber struct {
+	registry *Registry
+	config   config.HealthConfig
+	logger   *logger.Logger
+
+	mu      sync.RWMutex
+	results map[string]EngineHealth
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewHealthProber creates a HealthProber for the engines in registry
+func NewHealthProber(registry *Registry, config config.HealthConfig, logger *logger.Logger) *HealthProber {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &HealthProber{
+		registry: registry,
+		config:   config,
+		logger:   logger,
+		results:  make(map[string]EngineHealth),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start checks every engine right away and then every configured interval
+// until Stop is called
+func (p *HealthProber) Start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(p.config.Interval)
+		defer ticker.Stop()
+		for {
+			p.checkAll()
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends the background checks and waits for a check in progress
+func (p *HealthProber) Stop() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+// Health returns the latest health of a registered engine, with status
+// unknown if it hasn't been checked yet
+func (p *HealthProber) Health(connection EngineConnection) EngineHealth {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if health, ok := p.results[connection.Name]; ok {
+		return health
+	}
+	return EngineHealth{Engine: connection.Name, Type: connection.Type, Status: HealthUnknown}
+}
+
+// checkAll checks every connected engine at once and records the others as
+// unavailable
+func (p *HealthProber) checkAll() {
+	var wg sync.WaitGroup
+	for _, connection := range p.registry.Connections() {
+		if connection.Engine == nil {
+			p.unavailable(connection)
+			continue
+		}
+		wg.Add(1)
+		go func(engine Engine) {
+			defer wg.Done()
+			p.Check(p.ctx, engine)
+		}(connection.Engine)
+	}
+	wg.Wait()
+}
+
+// unavailable records an engine that hasn't connected yet. The registry
+// logs its connection attempts.
+func (p *HealthProber) unavailable(connection EngineConnection) {
+	now := time.Now()
+	metrics.SetEngineHealth(connection.Name, false)
+
+	p.mu.Lock()
+	p.results[connection.Name] = EngineHealth{
+		Engine:    connection.Name,
+		Type:      connection.Type,
+		Status:    HealthUnavailable,
+		Error:     connection.Error,
+		CheckedAt: &now,
+	}
+	p.mu.Unlock()
+}
+
+// Check checks an engine now and records the result. The engine is healthy
+// if it accepts connections and reports its status.
+func (p *HealthProber) Check(ctx context.Context, engine Engine) EngineHealth {
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	now := time.Now()
+	health := EngineHealth{
+		Engine:    engine.Name(),
+		Type:      engine.Type(),
+		Status:    HealthHealthy,
+		CheckedAt: &now,
+	}
+
+	err := engine.Health(ctx)
+	latencyMs := time.Since(now).Milliseconds()
+	health.LatencyMs = &latencyMs
+	if err == nil {
+		var status *EngineStatus
+		if status, err = engine.Status(ctx); err == nil {
+			health.EngineStatus = *status
+		}
+	}
+	if err != nil {
+		health.Status = HealthUnhealthy
+		health.Error = err.Error()
+	}
+	metrics.SetEngineHealth(engine.Name(), health.Status == HealthHealthy)
+
+	p.mu.Lock()
+	previous, checked := p.results[engine.Name()]
+	p.results[engine.Name()] = health
+	p.mu.Unlock()
+
+	log := p.logger.WithFields(logrus.Fields{
+		"engine":     engine.Name(),
+		"latency_ms": latencyMs,
+	})
+	switch {
+	case health.Status == HealthUnhealthy && (!checked || previous.Status != HealthUnhealthy):
+		log.WithError(err).Warn("Engine is unhealthy")
+	case health.Status == HealthHealthy && checked && previous.Status == HealthUnhealthy:
+		log.Info("Engine recovered")
+	}
+	return health
+}
@@ -1,50 +1,256 @@
 package services
 
 import (
+	"container/list"
 	"context"
 	"database/sql"
 	"fmt"
+	"net/url"
 	"query-service/internal/config"
 	"query-service/pkg/logger"
+	"sync"
 
 	_ "github.com/prestodb/presto-go-client/presto"
 )
 
 // PrestoService handles Presto-specific queries
 type PrestoService struct {
-	cfg    config.PrestoConfig
-	logger *logger.Logger
-	db     *sql.DB
+	cfg         config.EngineConfig
+	logger      *logger.Logger
+	db          *sql.DB
+	coordinator *coordinatorClient
+
+	// The Presto driver only takes catalog, schema and session properties
+	// from the DSN, so a pool is kept for each combination of options seen,
+	// up to maxPrestoSessions. lru holds the most recently used at the front.
+	mu       sync.Mutex
+	sessions map[string]*list.Element
+	lru      *list.List
 }
 
-// NewPrestoService creates a new PrestoService
-func NewPrestoService(cfg config.PrestoConfig, logger *logger.Logger) (*PrestoService, error) {
-	dsn := fmt.Sprintf("http://%s@%s:%s?catalog=%s&schema=%s",
-		cfg.User, cfg.Host, cfg.Port, cfg.Catalog, cfg.Schema)
+// maxPrestoSessions bounds the pools kept for options other than the
+// configured ones. Session properties come from clients, so beyond it the
+// least recently used pool is evicted, and closed once no query uses it.
+const maxPrestoSessions = 16
+
+// prestoSession is a pool opened for a combination of options. users counts
+// the queries holding it, guarded by PrestoService.mu.
+type prestoSession struct {
+	dsn     string
+	db      *sql.DB
+	users   int
+	evicted bool
+}
 
-	db, err := sql.Open("presto", dsn)
+// NewPrestoService creates a new PrestoService, giving up on connecting
+// when ctx ends
+func NewPrestoService(ctx context.Context, cfg config.EngineConfig, logger *logger.Logger) (*PrestoService, error) {
+	db, err := sql.Open("presto", prestoDSN(cfg, ExecOptions{}))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Presto: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping Presto: %w", err)
 	}
 
 	return &PrestoService{
-		cfg:    cfg,
-		logger: logger,
-		db:     db,
+		cfg:         cfg,
+		logger:      logger,
+		db:          db,
+		coordinator: newCoordinatorClient(cfg.Host, cfg.Port, cfg.User, "X-Presto-"),
+		sessions:    make(map[string]*list.Element),
+		lru:         list.New(),
+	}, nil
+}
+
+// prestoDSN builds the DSN for cfg with opts applied
+func prestoDSN(cfg config.EngineConfig, opts ExecOptions) string {
+	catalog, schema := cfg.Catalog, cfg.Schema
+	if opts.Catalog != "" {
+		catalog = opts.Catalog
+	}
+	if opts.Schema != "" {
+		schema = opts.Schema
+	}
+
+	query := url.Values{}
+	query.Set("catalog", catalog)
+	query.Set("schema", schema)
+	if len(opts.SessionProperties) > 0 {
+		query.Set("session_properties", sessionHeader(opts.SessionProperties))
+	}
+
+	dsn := url.URL{
+		Scheme:   "http",
+		User:     url.User(cfg.User),
+		Host:     cfg.Host + ":" + cfg.Port,
+		RawQuery: query.Encode(),
+	}
+	return dsn.String()
+}
+
+// session returns the connection pool for opts, and a function to call once
+// the query is done with it. An evicted pool is only closed once released
+// by every query it was handed to.
+func (s *PrestoService) session(opts ExecOptions) (*sql.DB, func(), error) {
+	if opts.IsZero() {
+		return s.db, func() {}, nil
+	}
+
+	dsn := prestoDSN(s.cfg, opts)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var session *prestoSession
+	if elem, ok := s.sessions[dsn]; ok {
+		s.lru.MoveToFront(elem)
+		session = elem.Value.(*prestoSession)
+	} else {
+		db, err := sql.Open("presto", dsn)
+		if err != nil {
+			return nil, nil, err
+		}
+		session = &prestoSession{dsn: dsn, db: db}
+		s.sessions[dsn] = s.lru.PushFront(session)
+
+		if s.lru.Len() > maxPrestoSessions {
+			oldest := s.lru.Remove(s.lru.Back()).(*prestoSession)
+			delete(s.sessions, oldest.dsn)
+			s.evict(oldest)
+		}
+	}
+	session.users++
+
+	var once sync.Once
+	return session.db, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			session.users--
+			if session.evicted && session.users == 0 {
+				session.db.Close()
+			}
+		})
 	}, nil
 }
 
-// ExecuteQuery executes a query on Presto
-func (s *PrestoService) ExecuteQuery(ctx context.Context, query string) (*sql.Rows, error) {
+// evict closes a pool that was dropped from the sessions, or leaves that
+// to the last query still using it. s.mu must be held.
+func (s *PrestoService) evict(session *prestoSession) {
+	session.evicted = true
+	if session.users == 0 {
+		session.db.Close()
+	}
+}
+
+// Name returns the name the engine is registered under
+func (s *PrestoService) Name() string {
+	return s.cfg.Name
+}
+
+// Type returns the engine type
+func (s *PrestoService) Type() string {
+	return "presto"
+}
+
+// Execute executes a query on Presto. The query is tagged with a unique
+// client info value so its ID can be looked up on the coordinator while it
+// runs, which is also how the query is killed if ctx ends before the cursor
+// is closed.
+func (s *PrestoService) Execute(ctx context.Context, query string, opts ExecOptions) (*Cursor, error) {
 	s.logger.WithField("query", query).Info("Executing Presto query")
-	return s.db.QueryContext(ctx, query)
+
+	db, release, err := s.session(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := generateID()
+	stop := context.AfterFunc(ctx, func() {
+		if err := s.coordinator.cancelTagged(tag); err != nil {
+			s.logger.WithError(err).Warn("Failed to kill Presto query")
+		}
+	})
+	rows, err := db.QueryContext(ctx, query, sql.Named("X-Presto-Client-Info", tag))
+	if err != nil {
+		stop()
+		release()
+		return nil, err
+	}
+
+	return &Cursor{
+		Rows:      rows,
+		resolveID: s.coordinator.lookupRunningQueryID(ctx, tag),
+		release: func() {
+			stop()
+			release()
+		},
+	}, nil
+}
+
+// Explain returns the JSON plan of a query, or the EXPLAIN ANALYZE output
+// if analyze is set
+func (s *PrestoService) Explain(ctx context.Context, query string, analyze bool, opts ExecOptions) (string, error) {
+	db, release, err := s.session(opts)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	rows, err := db.QueryContext(ctx, explainStatement(query, analyze))
+	if err != nil {
+		return "", err
+	}
+	return readText(rows)
+}
+
+// Health checks that the coordinator answers. The driver connects lazily,
+// so pinging the connection pool doesn't reach it.
+func (s *PrestoService) Health(ctx context.Context) error {
+	_, err := s.coordinator.info(ctx)
+	return err
+}
+
+// Version returns the coordinator's version
+func (s *PrestoService) Version(ctx context.Context) (string, error) {
+	info, err := s.coordinator.info(ctx)
+	if err != nil {
+		return "", err
+	}
+	return info.NodeVersion.Version, nil
+}
+
+// Status reports the cluster's version, uptime and active workers
+func (s *PrestoService) Status(ctx context.Context) (*EngineStatus, error) {
+	return coordinatorStatus(ctx, s.coordinator, s.db)
 }
 
-// GetStatus checks the status of the Presto service
-func (s *PrestoService) GetStatus() error {
-	return s.db.Ping()
+// Cancel kills a running query through the coordinator
+func (s *PrestoService) Cancel(ctx context.Context, queryID string) error {
+	return s.coordinator.cancel(ctx, queryID)
+}
+
+// QueryStats returns the runtime statistics of a finished query from the
+// coordinator
+func (s *PrestoService) QueryStats(ctx context.Context, queryID string) (*QueryStats, error) {
+	return s.coordinator.queryStats(ctx, queryID)
+}
+
+// Capabilities describes what Presto supports
+func (s *PrestoService) Capabilities() Capabilities {
+	return coordinatorCapabilities
+}
+
+// Close closes the connection pools. Pools still used by queries are closed
+// once they're released.
+func (s *PrestoService) Close() error {
+	s.mu.Lock()
+	for elem := s.lru.Front(); elem != nil; elem = elem.Next() {
+		s.evict(elem.Value.(*prestoSession))
+	}
+	s.sessions = make(map[string]*list.Element)
+	s.lru.Init()
+	s.mu.Unlock()
+	return s.db.Close()
 }
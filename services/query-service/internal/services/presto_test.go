@@ -0,0 +1,96 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"query-service/internal/config"
+)
+
+func TestPrestoSessionEviction(t *testing.T) {
+	cfg := config.EngineConfig{Host: "presto", Port: "8080", User: "admin", Catalog: "hive", Schema: "default"}
+	db, err := sql.Open("presto", prestoDSN(cfg, ExecOptions{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &PrestoService{
+		cfg:      cfg,
+		db:       db,
+		sessions: make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+	session := func(schema string) (*sql.DB, func()) {
+		db, release, err := s.session(ExecOptions{Schema: schema})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db, release
+	}
+
+	held, releaseHeld := session("held")
+	if again, release := session("held"); again != held {
+		t.Fatal("the same options got another pool")
+	} else {
+		release()
+	}
+
+	// Fill the cache until the held pool is evicted, releasing the others
+	pools := make([]*sql.DB, maxPrestoSessions)
+	for i := range pools {
+		db, release := session(fmt.Sprintf("schema_%d", i))
+		release()
+		release() // releasing twice is harmless
+		pools[i] = db
+	}
+	if _, ok := s.sessions[prestoDSN(s.cfg, ExecOptions{Schema: "held"})]; ok {
+		t.Fatal("the least recently used pool wasn't evicted")
+	}
+	if isClosed(held) {
+		t.Fatal("an evicted pool was closed while in use")
+	}
+
+	// Pools nobody uses are closed as soon as they're evicted
+	session("one_more")
+	if !isClosed(pools[0]) {
+		t.Error("an evicted idle pool wasn't closed")
+	}
+	if isClosed(pools[1]) {
+		t.Error("a cached pool was closed")
+	}
+
+	releaseHeld()
+	if !isClosed(held) {
+		t.Error("an evicted pool wasn't closed by its last user")
+	}
+	if db, release := session("held"); db == held || isClosed(db) {
+		t.Error("options of an evicted pool didn't get a new one")
+	} else {
+		release()
+	}
+
+	inUse, release := session("schema_5")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if isClosed(inUse) {
+		t.Error("closing the service closed a pool in use")
+	}
+	release()
+	if !isClosed(inUse) {
+		t.Error("a pool in use wasn't closed once released after the service closed")
+	}
+}
+
+// isClosed reports whether db was closed. The driver connects lazily, so
+// taking a connection from an open pool doesn't reach the server.
+func isClosed(db *sql.DB) bool {
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return true
+	}
+	conn.Close()
+	return false
+}
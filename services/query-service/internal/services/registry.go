@@ -0,0 +1,352 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"query-service/internal/config"
+	"query-service/pkg/logger"
+)
+
+var (
+	ErrEngineNotFound    = errors.New("engine not found")
+	ErrEngineUnavailable = errors.New("engine unavailable")
+)
+
+// reconnectMinBackoff and reconnectMaxBackoff bound the wait between
+// attempts to connect to an unavailable engine, which doubles after every
+// failed attempt
+const (
+	reconnectMinBackoff = time.Second
+	reconnectMaxBackoff = time.Minute
+	// connectTimeout bounds the connection check of each attempt
+	connectTimeout = 10 * time.Second
+	// healthCheckInterval is how often a connected engine is checked, and
+	// maxHealthFailures how many checks in a row it may fail before it's
+	// reported unavailable again and reconnected
+	healthCheckInterval = 15 * time.Second
+	maxHealthFailures   = 3
+)
+
+// EngineFactory opens an engine from its configuration, giving up when ctx
+// ends
+type EngineFactory func(ctx context.Context, cfg config.EngineConfig, logger *logger.Logger) (Engine, error)
+
+// engineFactories maps an engine type to the constructor of its implementation
+var engineFactories = map[string]EngineFactory{
+	"trino": func(ctx context.Context, cfg config.EngineConfig, logger *logger.Logger) (Engine, error) {
+		return NewTrinoService(ctx, cfg, logger)
+	},
+	"presto": func(ctx context.Context, cfg config.EngineConfig, logger *logger.Logger) (Engine, error) {
+		return NewPrestoService(ctx, cfg, logger)
+	},
+	"starrocks": func(ctx context.Context, cfg config.EngineConfig, logger *logger.Logger) (Engine, error) {
+		return NewStarRocksService(ctx, cfg, logger)
+	},
+}
+
+// EngineConnection is the connection state of a registered engine. Engine
+// is nil while the engine is unavailable, and Error, Attempts and
+// NextAttempt describe the attempts to connect to it so far.
+type EngineConnection struct {
+	Name        string
+	Type        string
+	Engine      Engine
+	Error       string
+	Attempts    int
+	NextAttempt *time.Time
+}
+
+// Registry holds the engines available to the service, keyed by name,
+// along with the configuration of the ones it opened. Engines opened from
+// configuration connect in the background and stay registered while
+// unavailable, retrying with exponential backoff until they connect, so one
+// engine being down doesn't take the others with it. Connected engines are
+// checked periodically, and one that stops answering goes back to being
+// unavailable until it reconnects.
+type Registry struct {
+	logger *logger.Logger
+
+	mu      sync.RWMutex
+	engines map[string]Engine
+	pending map[string]*EngineConnection
+	configs map[string]config.EngineConfig
+	order   []string
+	closed  bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry(logger *logger.Logger) *Registry {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Registry{
+		logger:  logger,
+		engines: make(map[string]Engine),
+		pending: make(map[string]*EngineConnection),
+		configs: make(map[string]config.EngineConfig),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// openEngine constructs the engine described by cfg without registering it
+// and checks that it accepts connections, since some drivers only connect
+// on first use
+func openEngine(ctx context.Context, cfg config.EngineConfig, logger *logger.Logger) (Engine, error) {
+	factory, ok := engineFactories[cfg.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown engine type %q for engine %q", cfg.Type, cfg.Name)
+	}
+	engine, err := factory(ctx, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := engine.Health(ctx); err != nil {
+		engine.Close()
+		return nil, err
+	}
+	return engine, nil
+}
+
+// Open registers the engine described by cfg and connects to it in the
+// background. Until it connects, Get reports it as unavailable. Only a
+// configuration that can never work, such as an unknown engine type, is an
+// error.
+func (r *Registry) Open(cfg config.EngineConfig) error {
+	if _, ok := engineFactories[cfg.Type]; !ok {
+		return fmt.Errorf("unknown engine type %q for engine %q", cfg.Type, cfg.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.registered(cfg.Name) {
+		return fmt.Errorf("engine %q is already registered", cfg.Name)
+	}
+	r.pending[cfg.Name] = &EngineConnection{Name: cfg.Name, Type: cfg.Type}
+	r.configs[cfg.Name] = cfg
+	r.order = append(r.order, cfg.Name)
+
+	go r.connect(cfg)
+	return nil
+}
+
+// connect opens an engine, retrying with exponential backoff until it
+// connects, then watches it, starting over if it stops answering. It
+// returns when the registry is closed.
+func (r *Registry) connect(cfg config.EngineConfig) {
+	log := r.logger.WithFields(logrus.Fields{
+		"engine": cfg.Name,
+		"host":   cfg.Host,
+		"port":   cfg.Port,
+	})
+
+	for {
+		engine := r.dial(cfg, log)
+		if engine == nil || !r.watch(cfg, engine, log) {
+			return
+		}
+	}
+}
+
+// dial opens an engine with exponential backoff between attempts and
+// registers it as connected. It returns nil if the registry is closed first.
+func (r *Registry) dial(cfg config.EngineConfig, log *logrus.Entry) Engine {
+	backoff := reconnectMinBackoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(r.ctx, connectTimeout)
+		engine, err := openEngine(ctx, cfg, r.logger)
+		cancel()
+		if err == nil {
+			r.mu.Lock()
+			if r.closed {
+				r.mu.Unlock()
+				engine.Close()
+				return nil
+			}
+			r.engines[cfg.Name] = engine
+			delete(r.pending, cfg.Name)
+			r.mu.Unlock()
+
+			log.WithField("attempts", attempt).Info("Connected to engine")
+			return engine
+		}
+
+		next := time.Now().Add(backoff)
+		r.mu.Lock()
+		if connection, ok := r.pending[cfg.Name]; ok {
+			connection.Error = err.Error()
+			connection.Attempts = attempt
+			connection.NextAttempt = &next
+		}
+		r.mu.Unlock()
+		log.WithError(err).WithFields(logrus.Fields{
+			"attempts":    attempt,
+			"retry_after": backoff.String(),
+		}).Warn("Engine unavailable")
+
+		select {
+		case <-r.ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, reconnectMaxBackoff)
+	}
+}
+
+// watch checks a connected engine until it fails maxHealthFailures checks
+// in a row, then closes it and reports it unavailable so it's reconnected.
+// It returns false if the registry is closed first.
+func (r *Registry) watch(cfg config.EngineConfig, engine Engine, log *logrus.Entry) bool {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-r.ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(r.ctx, connectTimeout)
+		err := engine.Health(ctx)
+		cancel()
+		if err == nil {
+			failures = 0
+			continue
+		}
+		if failures++; failures < maxHealthFailures {
+			log.WithError(err).WithField("failures", failures).Debug("Engine health check failed")
+			continue
+		}
+
+		r.mu.Lock()
+		if r.closed {
+			r.mu.Unlock()
+			return false
+		}
+		delete(r.engines, cfg.Name)
+		r.pending[cfg.Name] = &EngineConnection{Name: cfg.Name, Type: cfg.Type, Error: err.Error()}
+		r.mu.Unlock()
+		engine.Close()
+
+		log.WithError(err).Warn("Lost connection to engine, reconnecting")
+		return true
+	}
+}
+
+// Register adds a connected engine under its name
+func (r *Registry) Register(engine Engine) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.registered(engine.Name()) {
+		return fmt.Errorf("engine %q is already registered", engine.Name())
+	}
+	r.engines[engine.Name()] = engine
+	r.order = append(r.order, engine.Name())
+	return nil
+}
+
+// registered reports whether an engine is registered, connected or not.
+// The caller must hold mu.
+func (r *Registry) registered(name string) bool {
+	_, connected := r.engines[name]
+	_, pending := r.pending[name]
+	return connected || pending
+}
+
+// Get returns the engine registered under name, or ErrEngineUnavailable if
+// it hasn't connected yet
+func (r *Registry) Get(name string) (Engine, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if engine, ok := r.engines[name]; ok {
+		return engine, nil
+	}
+	if connection, ok := r.pending[name]; ok {
+		if connection.Error == "" {
+			return nil, fmt.Errorf("%w: %s is still connecting", ErrEngineUnavailable, name)
+		}
+		return nil, fmt.Errorf("%w: %s: %s", ErrEngineUnavailable, name, connection.Error)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrEngineNotFound, name)
+}
+
+// Config returns the configuration an engine was opened with. Engines added
+// with Register have none.
+func (r *Registry) Config(name string) (config.EngineConfig, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cfg, ok := r.configs[name]
+	return cfg, ok
+}
+
+// List returns the connected engines in registration order
+func (r *Registry) List() []Engine {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	engines := make([]Engine, 0, len(r.order))
+	for _, name := range r.order {
+		if engine, ok := r.engines[name]; ok {
+			engines = append(engines, engine)
+		}
+	}
+	return engines
+}
+
+// Connections returns the connection state of every registered engine,
+// connected or not, in registration order
+func (r *Registry) Connections() []EngineConnection {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	connections := make([]EngineConnection, 0, len(r.order))
+	for _, name := range r.order {
+		connections = append(connections, r.connection(name))
+	}
+	return connections
+}
+
+// Connection returns the connection state of a registered engine
+func (r *Registry) Connection(name string) (EngineConnection, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.registered(name) {
+		return EngineConnection{}, false
+	}
+	return r.connection(name), true
+}
+
+// connection describes a registered engine. The caller must hold mu.
+func (r *Registry) connection(name string) EngineConnection {
+	if engine, ok := r.engines[name]; ok {
+		return EngineConnection{Name: name, Type: engine.Type(), Engine: engine}
+	}
+	return *r.pending[name]
+}
+
+// Close stops connecting to unavailable engines and closes every connected
+// one
+func (r *Registry) Close() {
+	r.cancel()
+
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+
+	for _, engine := range r.List() {
+		engine.Close()
+	}
+}
@@ -3,32 +3,46 @@ package services
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"net"
 	"query-service/internal/config"
 	"query-service/pkg/logger"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 // StarRocksService handles StarRocks-specific queries
 type StarRocksService struct {
-	cfg    config.StarRocksConfig
+	cfg    config.EngineConfig
 	logger *logger.Logger
 	db     *sql.DB
 }
 
-// NewStarRocksService creates a new StarRocksService
-func NewStarRocksService(cfg config.StarRocksConfig, logger *logger.Logger) (*StarRocksService, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true",
-		cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Database)
-
-	db, err := sql.Open("mysql", dsn)
+// NewStarRocksService creates a new StarRocksService. The configured
+// external catalogs are created first if missing, since the pool's
+// connections start in the configured catalog, which may be one of them.
+// Connecting and creating the catalogs give up when ctx ends.
+func NewStarRocksService(ctx context.Context, cfg config.EngineConfig, logger *logger.Logger) (*StarRocksService, error) {
+	db, err := openStarRocks(ctx, cfg, "")
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to StarRocks: %w", err)
+		return nil, err
+	}
+	if err := createExternalCatalogs(ctx, db, cfg.ExternalCatalogs, logger); err != nil {
+		db.Close()
+		return nil, err
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping StarRocks: %w", err)
+	if database := starRocksDatabase(cfg); database != "" {
+		db.Close()
+		if db, err = openStarRocks(ctx, cfg, database); err != nil {
+			return nil, err
+		}
 	}
 
 	return &StarRocksService{
@@ -38,13 +52,733 @@ func NewStarRocksService(cfg config.StarRocksConfig, logger *logger.Logger) (*St
 	}, nil
 }
 
-// ExecuteQuery executes a query on StarRocks
-func (s *StarRocksService) ExecuteQuery(ctx context.Context, query string) (*sql.Rows, error) {
+// openStarRocks opens a connection pool whose connections start in
+// database
+func openStarRocks(ctx context.Context, cfg config.EngineConfig, database string) (*sql.DB, error) {
+	db, err := sql.Open("mysql", starRocksDSN(cfg, database))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to StarRocks: %w", err)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping StarRocks: %w", err)
+	}
+	return db, nil
+}
+
+// starRocksDSN returns the driver's DSN for connections starting in
+// database. Building it with the driver escapes credentials and names.
+func starRocksDSN(cfg config.EngineConfig, database string) string {
+	dsn := mysql.NewConfig()
+	dsn.User = cfg.User
+	dsn.Passwd = cfg.Password
+	dsn.Net = "tcp"
+	dsn.Addr = net.JoinHostPort(cfg.Host, cfg.Port)
+	dsn.DBName = database
+	dsn.ParseTime = true
+	// Profiles are enabled on every session so QueryStats can read them
+	dsn.Params = map[string]string{"enable_profile": "true"}
+	return dsn.FormatDSN()
+}
+
+// defaultCatalog is StarRocks' catalog of internal tables
+const defaultCatalog = "default_catalog"
+
+// starRocksDatabase returns the database a connection starts in. Databases
+// of external catalogs are named catalog.database, which is the only way to
+// start a connection in another catalog. A catalog without a schema can't
+// be selected this way and is left to ExecOptions.
+func starRocksDatabase(cfg config.EngineConfig) string {
+	if cfg.Schema == "" {
+		return ""
+	}
+	catalog := catalogName(cfg, cfg.Catalog)
+	if catalog == "" || catalog == defaultCatalog {
+		return cfg.Schema
+	}
+	return catalog + "." + cfg.Schema
+}
+
+// catalogName returns StarRocks' name for a catalog a query refers to
+func catalogName(cfg config.EngineConfig, name string) string {
+	for logical, catalog := range cfg.Catalogs {
+		if strings.EqualFold(logical, name) {
+			return catalog
+		}
+	}
+	return name
+}
+
+// createExternalCatalogs creates the catalogs StarRocks doesn't have yet.
+// Existing catalogs are left as they are, even if configured differently.
+func createExternalCatalogs(ctx context.Context, db *sql.DB, catalogs []config.ExternalCatalog, logger *logger.Logger) error {
+	if len(catalogs) == 0 {
+		return nil
+	}
+
+	rows, err := db.QueryContext(ctx, "SHOW CATALOGS")
+	if err != nil {
+		return fmt.Errorf("failed to list StarRocks catalogs: %w", err)
+	}
+	existing, err := scanRowMaps(rows)
+	if err != nil {
+		return fmt.Errorf("failed to list StarRocks catalogs: %w", err)
+	}
+
+	for _, catalog := range catalogs {
+		found := false
+		for _, row := range existing {
+			if strings.EqualFold(row["Catalog"], catalog.Name) {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		statement, err := externalCatalogStatement(catalog)
+		if err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create StarRocks catalog %s: %w", catalog.Name, err)
+		}
+		logger.WithField("catalog", catalog.Name).Info("Created StarRocks external catalog")
+	}
+	return nil
+}
+
+// externalCatalogStatement builds the CREATE EXTERNAL CATALOG statement of
+// a Hive or Iceberg catalog over the Hive metastore, reading from an S3
+// compatible object store such as MinIO with the catalog's keys
+func externalCatalogStatement(catalog config.ExternalCatalog) (string, error) {
+	properties := [][2]string{{"type", catalog.Type}}
+	switch catalog.Type {
+	case "hive":
+		properties = append(properties, [2]string{"hive.metastore.type", "hive"})
+	case "iceberg":
+		properties = append(properties, [2]string{"iceberg.catalog.type", "hive"})
+	default:
+		return "", fmt.Errorf("unsupported type %q for StarRocks catalog %s", catalog.Type, catalog.Name)
+	}
+	properties = append(properties, [2]string{"hive.metastore.uris", catalog.MetastoreURI})
+
+	if catalog.S3Endpoint != "" {
+		properties = append(properties,
+			[2]string{"aws.s3.endpoint", catalog.S3Endpoint},
+			[2]string{"aws.s3.enable_ssl", strconv.FormatBool(strings.HasPrefix(catalog.S3Endpoint, "https://"))},
+			[2]string{"aws.s3.enable_path_style_access", "true"},
+		)
+	}
+	if catalog.S3AccessKey == "" || catalog.S3SecretKey == "" {
+		return "", fmt.Errorf("StarRocks catalog %s has no S3 access and secret key; set <ENGINE>_S3_ACCESS_KEY and <ENGINE>_S3_SECRET_KEY", catalog.Name)
+	}
+	properties = append(properties,
+		[2]string{"aws.s3.access_key", catalog.S3AccessKey},
+		[2]string{"aws.s3.secret_key", catalog.S3SecretKey},
+	)
+
+	pairs := make([]string, 0, len(properties))
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	for _, property := range properties {
+		pairs = append(pairs, fmt.Sprintf(`"%s" = "%s"`, property[0], escape.Replace(property[1])))
+	}
+	return fmt.Sprintf("CREATE EXTERNAL CATALOG %s PROPERTIES (%s)",
+		quoteIdentifier(catalog.Name), strings.Join(pairs, ", ")), nil
+}
+
+// Name returns the name the engine is registered under
+func (s *StarRocksService) Name() string {
+	return s.cfg.Name
+}
+
+// Type returns the engine type
+func (s *StarRocksService) Type() string {
+	return "starrocks"
+}
+
+// Execute executes a query on StarRocks. The query runs on a dedicated
+// connection so last_query_id() can be read from the same session once the
+// rows are drained. Options are applied to that session with SET CATALOG,
+// USE and SET, and a connection whose session was changed is discarded
+// rather than returned to the pool. Table names are translated as
+// described on translateQuery. If ctx ends before the cursor is
+// closed the query is killed by the connection's ID, since the driver only
+// drops the connection.
+func (s *StarRocksService) Execute(ctx context.Context, query string, opts ExecOptions) (*Cursor, error) {
 	s.logger.WithField("query", query).Info("Executing StarRocks query")
-	return s.db.QueryContext(ctx, query)
+
+	query = s.translateQuery(query)
+	statements, err := s.sessionStatements(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var connectionID uint64
+	if err := conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&connectionID); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		killCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.killQuery(killCtx, connectionID); err != nil {
+			s.logger.WithError(err).Warn("Failed to kill StarRocks query")
+		}
+	})
+	release := func() {
+		// A connection that may have been killed, or whose session was
+		// changed, must not be handed to another query
+		if !stop() || len(statements) > 0 {
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+
+	for _, statement := range statements {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	return &Cursor{
+		Rows: rows,
+		resolveID: func() (string, error) {
+			var id string
+			err := conn.QueryRowContext(ctx, "SELECT last_query_id()").Scan(&id)
+			return id, err
+		},
+		release: release,
+	}, nil
+}
+
+var sessionVariablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// sessionStatements returns the statements that apply opts to a StarRocks
+// session. Switching catalogs leaves the session without a database, so
+// the configured schema is used when opts has a catalog but no schema, the
+// way Trino keeps its session schema.
+func (s *StarRocksService) sessionStatements(opts ExecOptions) ([]string, error) {
+	var statements []string
+	schema := opts.Schema
+	if opts.Catalog != "" {
+		statements = append(statements, "SET CATALOG "+quoteIdentifier(catalogName(s.cfg, opts.Catalog)))
+		schema = firstSet(schema, s.cfg.Schema)
+	}
+	if schema != "" {
+		statements = append(statements, "USE "+quoteIdentifier(schema))
+	}
+
+	names := make([]string, 0, len(opts.SessionProperties))
+	for name := range opts.SessionProperties {
+		if !sessionVariablePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid session variable name %q", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		statements = append(statements, fmt.Sprintf("SET %s = %s", name, sessionValue(opts.SessionProperties[name])))
+	}
+	return statements, nil
+}
+
+// sessionValue formats a session variable value, leaving numbers and
+// booleans unquoted so integer variables accept them
+func sessionValue(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	if strings.EqualFold(value, "true") || strings.EqualFold(value, "false") {
+		return value
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", "''").Replace(value) + "'"
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// translateQuery rewrites the identifiers of a query written for Trino so
+// the same query runs on StarRocks. Catalog-qualified table names like
+// hive.default.orders_hive have the catalog renamed as configured in
+// Catalogs and every part quoted with backticks, since names like default
+// are reserved words to StarRocks. Those are only recognized when their
+// first part is a known catalog, so column references like t.c.x keep their
+// parts. Every other double-quoted identifier is quoted with backticks
+// instead, since StarRocks reads double quotes as strings. String literals
+// and comments are left alone.
+func (s *StarRocksService) translateQuery(query string) string {
+	var out strings.Builder
+	for i := 0; i < len(query); {
+		switch c := query[i]; {
+		case c == '\'':
+			end := skipQuoted(query, i)
+			out.WriteString(query[i:end])
+			i = end
+		case strings.HasPrefix(query[i:], "--"):
+			end := len(query)
+			if n := strings.IndexByte(query[i:], '\n'); n >= 0 {
+				end = i + n
+			}
+			out.WriteString(query[i:end])
+			i = end
+		case strings.HasPrefix(query[i:], "/*"):
+			end := len(query)
+			if n := strings.Index(query[i+2:], "*/"); n >= 0 {
+				end = i + 2 + n + 2
+			}
+			out.WriteString(query[i:end])
+			i = end
+		case c == '"' || c == '`' || isIdentifierStart(c):
+			parts, end := scanQualifiedName(query, i)
+			if len(parts) == 3 && s.knownCatalog(parts[0]) {
+				out.WriteString(quoteIdentifier(catalogName(s.cfg, parts[0])) + "." +
+					quoteIdentifier(parts[1]) + "." + quoteIdentifier(parts[2]))
+			} else {
+				out.WriteString(backtickQuoted(query[i:end]))
+			}
+			i = end
+		case isIdentifierPart(c):
+			// The rest of a number, which mustn't be read as a name
+			end := i
+			for end < len(query) && (isIdentifierPart(query[end]) || query[end] == '.') {
+				end++
+			}
+			out.WriteString(query[i:end])
+			i = end
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String()
+}
+
+// knownCatalog reports whether name is a catalog queries may qualify table
+// names with
+func (s *StarRocksService) knownCatalog(name string) bool {
+	if strings.EqualFold(name, defaultCatalog) || strings.EqualFold(name, s.cfg.Catalog) {
+		return true
+	}
+	for logical := range s.cfg.Catalogs {
+		if strings.EqualFold(logical, name) {
+			return true
+		}
+	}
+	for _, catalog := range s.cfg.ExternalCatalogs {
+		if strings.EqualFold(catalog.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanQualifiedName reads the dot separated parts of a name starting at i,
+// each a plain, double-quoted or backtick-quoted identifier, and returns
+// them unquoted along with the index past the name
+func scanQualifiedName(query string, i int) ([]string, int) {
+	var parts []string
+	for {
+		var part string
+		switch {
+		case i < len(query) && (query[i] == '"' || query[i] == '`'):
+			quote := query[i]
+			end := skipQuoted(query, i)
+			part = query[i+1 : end-1]
+			part = strings.ReplaceAll(part, string(quote)+string(quote), string(quote))
+			i = end
+		case i < len(query) && isIdentifierStart(query[i]):
+			end := i
+			for end < len(query) && isIdentifierPart(query[end]) {
+				end++
+			}
+			part = query[i:end]
+			i = end
+		default:
+			return parts, i
+		}
+		parts = append(parts, part)
+
+		// A dot continues the name only if another part follows it
+		if i+1 < len(query) && query[i] == '.' &&
+			(query[i+1] == '"' || query[i+1] == '`' || isIdentifierStart(query[i+1])) {
+			i++
+			continue
+		}
+		return parts, i
+	}
+}
+
+// backtickQuoted rewrites the double-quoted parts of a name read by
+// scanQualifiedName as backtick-quoted ones. An unterminated part is left
+// as it is.
+func backtickQuoted(name string) string {
+	if !strings.Contains(name, `"`) {
+		return name
+	}
+	var out strings.Builder
+	for i := 0; i < len(name); {
+		if name[i] != '"' && name[i] != '`' {
+			out.WriteByte(name[i])
+			i++
+			continue
+		}
+		end := skipQuoted(name, i)
+		if name[i] == '"' && end-i >= 2 && name[end-1] == '"' {
+			out.WriteString(quoteIdentifier(strings.ReplaceAll(name[i+1:end-1], `""`, `"`)))
+		} else {
+			out.WriteString(name[i:end])
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// skipQuoted returns the index past the quoted string or identifier
+// starting at i, where a doubled quote stands for itself. An unterminated
+// one runs to the end of the query.
+func skipQuoted(query string, i int) int {
+	quote := query[i]
+	for j := i + 1; j < len(query); j++ {
+		if query[j] != quote {
+			continue
+		}
+		if j+1 < len(query) && query[j+1] == quote {
+			j++
+			continue
+		}
+		return j + 1
+	}
+	return len(query)
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || (c >= '0' && c <= '9') || c == '$'
+}
+
+// Explain returns the plan of a query, or its EXPLAIN ANALYZE profile if
+// analyze is set. The query is translated and options are applied the same
+// way as for Execute, on a
+// connection that's discarded afterwards if they changed its session.
+func (s *StarRocksService) Explain(ctx context.Context, query string, analyze bool, opts ExecOptions) (string, error) {
+	query = s.translateQuery(query)
+	statement := "EXPLAIN " + query
+	if analyze {
+		statement = "EXPLAIN ANALYZE " + query
+	}
+
+	statements, err := s.sessionStatements(opts)
+	if err != nil {
+		return "", err
+	}
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if len(statements) > 0 {
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}()
+
+	for _, session := range statements {
+		if _, err := conn.ExecContext(ctx, session); err != nil {
+			return "", err
+		}
+	}
+	rows, err := conn.QueryContext(ctx, statement)
+	if err != nil {
+		return "", err
+	}
+	return readText(rows)
+}
+
+// Health checks the status of the StarRocks service
+func (s *StarRocksService) Health(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Version returns the frontend's version
+func (s *StarRocksService) Version(ctx context.Context) (string, error) {
+	var version string
+	err := s.db.QueryRowContext(ctx, "SELECT current_version()").Scan(&version)
+	return version, err
+}
+
+// starRocksTimeLayout is how SHOW FRONTENDS prints start times, in the
+// frontend's time zone
+const starRocksTimeLayout = "2006-01-02 15:04:05"
+
+// Status reports the leader frontend's version and uptime from SHOW
+// FRONTENDS, and the alive backends from SHOW BACKENDS as workers. Uptime is
+// measured against the frontend's own clock since start times are printed
+// without a time zone.
+func (s *StarRocksService) Status(ctx context.Context) (*EngineStatus, error) {
+	rows, err := s.db.QueryContext(ctx, "SHOW FRONTENDS")
+	if err != nil {
+		return nil, err
+	}
+	frontends, err := scanRowMaps(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var leader map[string]string
+	for _, frontend := range frontends {
+		// Versions before 3.0 report IsMaster instead of Role
+		if frontend["Role"] == "LEADER" || frontend["IsMaster"] == "true" {
+			leader = frontend
+			break
+		}
+	}
+	if leader == nil {
+		return nil, fmt.Errorf("no leader among %d frontends", len(frontends))
+	}
+	if leader["Alive"] != "true" {
+		return nil, fmt.Errorf("leader frontend %s is not alive: %s", leader["Name"], leader["ErrMsg"])
+	}
+
+	status := &EngineStatus{Version: leader["Version"]}
+	if status.Version == "" {
+		if status.Version, err = s.Version(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if started, err := time.Parse(starRocksTimeLayout, leader["StartTime"]); err == nil {
+		var now string
+		if err := s.db.QueryRowContext(ctx, "SELECT CAST(now() AS CHAR)").Scan(&now); err == nil {
+			if current, err := time.Parse(starRocksTimeLayout, now); err == nil {
+				seconds := int64(current.Sub(started).Seconds())
+				status.UptimeSeconds = &seconds
+			}
+		}
+	}
+
+	rows, err = s.db.QueryContext(ctx, "SHOW BACKENDS")
+	if err != nil {
+		return nil, err
+	}
+	backends, err := scanRowMaps(rows)
+	if err != nil {
+		return nil, err
+	}
+	workers := 0
+	for _, backend := range backends {
+		if backend["Alive"] == "true" {
+			workers++
+		}
+	}
+	status.Workers = &workers
+	return status, nil
+}
+
+// Cancel kills a running query. KILL QUERY takes a connection ID, so the
+// query ID is first mapped to the connection running it.
+func (s *StarRocksService) Cancel(ctx context.Context, queryID string) error {
+	rows, err := s.db.QueryContext(ctx, "SHOW PROC '/current_queries'")
+	if err != nil {
+		return err
+	}
+	queries, err := scanRowMaps(rows)
+	if err != nil {
+		return err
+	}
+
+	for _, query := range queries {
+		if query["QueryId"] == queryID {
+			connectionID, err := strconv.ParseUint(query["ConnectionId"], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid connection ID %q for query %s", query["ConnectionId"], queryID)
+			}
+			return s.killQuery(ctx, connectionID)
+		}
+	}
+	return fmt.Errorf("query %s is not running", queryID)
+}
+
+// killQuery stops the statement running on a connection without closing it
+func (s *StarRocksService) killQuery(ctx context.Context, connectionID uint64) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("KILL QUERY %d", connectionID))
+	return err
+}
+
+var (
+	profileCPUPattern      = regexp.MustCompile(`QueryCumulativeCpuTime: (\S+)`)
+	profileMemoryPattern   = regexp.MustCompile(`QueryPeakMemoryUsage(?:PerNode)?: ([\d.]+ ?\w+)`)
+	profilePlanningPattern = regexp.MustCompile(`--\s*Total\[\d+\]\s+(\S+)`)
+	profileQueuedPattern   = regexp.MustCompile(`(?i)(?:Query)?\s*Queue\s*Pending\s*Time: (\S+)`)
+	profileScanPattern     = regexp.MustCompile(`^[A-Z_]*SCAN \(plan_node_id=\d+\):$`)
+	profileCounterPattern  = regexp.MustCompile(`^- (\w+): (.+)$`)
+	profileRowsPattern     = regexp.MustCompile(`^([\d.]+[KMB]?)(?: \((\d+)\))?$`)
+)
+
+// QueryStats returns the runtime statistics of a finished query from its
+// profile. Profiles may be collected asynchronously, so a missing one is
+// retried for a while.
+func (s *StarRocksService) QueryStats(ctx context.Context, queryID string) (*QueryStats, error) {
+	var profile string
+	for attempt := 1; ; attempt++ {
+		err := s.db.QueryRowContext(ctx, "SELECT get_query_profile(?)", queryID).Scan(&profile)
+		if err == nil && strings.Contains(profile, "QueryCumulativeCpuTime") {
+			break
+		}
+		if attempt == statsAttempts {
+			if err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("no profile for query %s", queryID)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(statsRetryInterval):
+		}
+	}
+	return parseProfile(profile), nil
+}
+
+// parseProfile reads the statistics out of a query profile. Input is summed
+// over the scan operators.
+func parseProfile(profile string) *QueryStats {
+	stats := &QueryStats{}
+	duration := func(pattern *regexp.Regexp) *int64 {
+		if m := pattern.FindStringSubmatch(profile); m != nil {
+			if ms, ok := parseEngineDuration(m[1]); ok {
+				return &ms
+			}
+		}
+		return nil
+	}
+	stats.CPUTimeMs = duration(profileCPUPattern)
+	stats.PlanningTimeMs = duration(profilePlanningPattern)
+	stats.QueuedTimeMs = duration(profileQueuedPattern)
+
+	if m := profileMemoryPattern.FindStringSubmatch(profile); m != nil {
+		if bytes, ok := parseEngineSize(m[1]); ok {
+			stats.PeakMemoryBytes = &bytes
+		}
+	}
+
+	counters := scanCounters(profile)
+	if values := counters["BytesRead"]; len(values) > 0 {
+		var bytesRead int64
+		for _, value := range values {
+			if bytes, ok := parseEngineSize(value); ok {
+				bytesRead += bytes
+			}
+		}
+		stats.PhysicalInputBytes = &bytesRead
+	}
+	stats.PhysicalInputRows = sumProfileRows(counters["RawRowsRead"])
+	stats.ProcessedRows = sumProfileRows(counters["RowsRead"])
+	return stats
+}
+
+// scanCounters returns the values of the counters printed directly under
+// the UniqueMetrics of each scan operator, by name. Profiles merge an
+// operator's counters across its instances, and the merged total is the
+// only one at that level: the __MAX_OF_ and __MIN_OF_ counters and the
+// reader's own counters are nested below it, and would count the same
+// input again.
+func scanCounters(profile string) map[string][]string {
+	counters := make(map[string][]string)
+	scanIndent, metricsIndent, counterIndent := -1, -1, -1
+	for _, line := range strings.Split(profile, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent <= scanIndent {
+			scanIndent, metricsIndent = -1, -1
+		}
+		if indent <= metricsIndent {
+			metricsIndent = -1
+		}
+
+		switch {
+		case profileScanPattern.MatchString(trimmed):
+			scanIndent = indent
+		case scanIndent >= 0 && trimmed == "UniqueMetrics:":
+			metricsIndent, counterIndent = indent, -1
+		case metricsIndent >= 0:
+			// The first counter is a top-level one, and sets their indent
+			if counterIndent < 0 {
+				counterIndent = indent
+			}
+			if m := profileCounterPattern.FindStringSubmatch(trimmed); m != nil && indent == counterIndent {
+				counters[m[1]] = append(counters[m[1]], m[2])
+			}
+		}
+	}
+	return counters
+}
+
+// sumProfileRows sums the values of a row counter, which profiles print
+// abbreviated with the exact count in parentheses, like "1.234M (1234000)"
+func sumProfileRows(values []string) *int64 {
+	if len(values) == 0 {
+		return nil
+	}
+	var total int64
+	for _, v := range values {
+		m := profileRowsPattern.FindStringSubmatch(v)
+		if m == nil {
+			continue
+		}
+		if m[2] != "" {
+			if rows, err := strconv.ParseInt(m[2], 10, 64); err == nil {
+				total += rows
+				continue
+			}
+		}
+		value := m[1]
+		multiplier := 1.0
+		switch value[len(value)-1] {
+		case 'K':
+			multiplier, value = 1e3, value[:len(value)-1]
+		case 'M':
+			multiplier, value = 1e6, value[:len(value)-1]
+		case 'B':
+			multiplier, value = 1e9, value[:len(value)-1]
+		}
+		if rows, err := strconv.ParseFloat(value, 64); err == nil {
+			total += int64(rows * multiplier)
+		}
+	}
+	return &total
+}
+
+// Capabilities describes what StarRocks supports
+func (s *StarRocksService) Capabilities() Capabilities {
+	return Capabilities{
+		ExplainAnalyze: true,
+		Cancel:         true,
+		QueryInfo:      true,
+	}
 }
 
-// GetStatus checks the status of the StarRocks service
-func (s *StarRocksService) GetStatus() error {
-	return s.db.Ping()
+// Close closes the connection pool
+func (s *StarRocksService) Close() error {
+	return s.db.Close()
 }
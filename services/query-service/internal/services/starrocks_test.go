@@ -0,0 +1,222 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"query-service/internal/config"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestTranslateQuery(t *testing.T) {
+	s := &StarRocksService{cfg: config.EngineConfig{
+		Catalog:          "hive",
+		Catalogs:         map[string]string{"iceberg": "iceberg_catalog"},
+		ExternalCatalogs: []config.ExternalCatalog{{Name: "delta", Type: "hive"}},
+	}}
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "catalog-qualified table",
+			query: "SELECT * FROM hive.default.orders_hive",
+			want:  "SELECT * FROM `hive`.`default`.`orders_hive`",
+		},
+		{
+			name:  "renamed catalog",
+			query: "SELECT * FROM ICEBERG.tpch.orders o JOIN delta.tpch.customer c ON o.o_custkey = c.c_custkey",
+			want:  "SELECT * FROM `iceberg_catalog`.`tpch`.`orders` o JOIN `delta`.`tpch`.`customer` c ON o.o_custkey = c.c_custkey",
+		},
+		{
+			name:  "quoted catalog-qualified table",
+			query: `SELECT * FROM "hive"."default"."orders_hive", hive."default".customer_hive, default_catalog.db.t`,
+			want:  "SELECT * FROM `hive`.`default`.`orders_hive`, `hive`.`default`.`customer_hive`, `default_catalog`.`db`.`t`",
+		},
+		{
+			name:  "three-part column reference",
+			query: `SELECT t.c.x, "t"."c"."x" FROM t`,
+			want:  "SELECT t.c.x, `t`.`c`.`x` FROM t",
+		},
+		{
+			name:  "two-part names",
+			query: `SELECT "o"."o_orderkey", o.o_totalprice FROM "default"."orders" o, tpch.customer`,
+			want:  "SELECT `o`.`o_orderkey`, o.o_totalprice FROM `default`.`orders` o, tpch.customer",
+		},
+		{
+			name:  "single identifiers",
+			query: `SELECT "o_orderkey" AS "key", count(*) AS "count" FROM "orders" GROUP BY "o_orderkey"`,
+			want:  "SELECT `o_orderkey` AS `key`, count(*) AS `count` FROM `orders` GROUP BY `o_orderkey`",
+		},
+		{
+			name:  "quotes inside identifiers",
+			query: `SELECT "a""b", "c` + "`" + `d" FROM t`,
+			want:  "SELECT `a\"b`, `c``d` FROM t",
+		},
+		{
+			name:  "backtick-quoted identifiers",
+			query: "SELECT `a`.`b` FROM `t`",
+			want:  "SELECT `a`.`b` FROM `t`",
+		},
+		{
+			name:  "string literals",
+			query: `SELECT 'hive.default.orders_hive', 'say "hi"', 'it''s "x"' FROM "t" WHERE c = '"'`,
+			want:  "SELECT 'hive.default.orders_hive', 'say \"hi\"', 'it''s \"x\"' FROM `t` WHERE c = '\"'",
+		},
+		{
+			name:  "line comment",
+			query: "-- \"x\" from hive.default.t\nSELECT \"y\" FROM t -- \"z\"",
+			want:  "-- \"x\" from hive.default.t\nSELECT `y` FROM t -- \"z\"",
+		},
+		{
+			name:  "block comment",
+			query: `SELECT /* "x", hive.default.t */ "y" FROM t`,
+			want:  "SELECT /* \"x\", hive.default.t */ `y` FROM t",
+		},
+		{
+			name:  "numbers",
+			query: `SELECT 1.5e3, 2.5, 1e10, 3.e2, .5, x.y1 FROM t WHERE "a" > 1.5E-3`,
+			want:  "SELECT 1.5e3, 2.5, 1e10, 3.e2, .5, x.y1 FROM t WHERE `a` > 1.5E-3",
+		},
+		{
+			name:  "unterminated identifier",
+			query: `SELECT "abc FROM t`,
+			want:  `SELECT "abc FROM t`,
+		},
+		{
+			name:  "unterminated string",
+			query: `SELECT 'abc "d" FROM t`,
+			want:  `SELECT 'abc "d" FROM t`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.translateQuery(tt.query); got != tt.want {
+				t.Errorf("translateQuery(%q)\n got %s\nwant %s", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStarRocksDSN(t *testing.T) {
+	cfg := config.EngineConfig{Host: "starrocks-fe", Port: "9030", User: "bench@mark", Password: "p@ss:w/rd?"}
+	dsn := starRocksDSN(cfg, "hive.default")
+	parsed, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("ParseDSN(%q): %v", dsn, err)
+	}
+	if parsed.User != cfg.User || parsed.Passwd != cfg.Password || parsed.Addr != "starrocks-fe:9030" || parsed.DBName != "hive.default" {
+		t.Errorf("DSN %q parses as %s:%s@%s/%s", dsn, parsed.User, parsed.Passwd, parsed.Addr, parsed.DBName)
+	}
+	if !parsed.ParseTime || parsed.Params["enable_profile"] != "true" {
+		t.Errorf("DSN %q doesn't parse times and enable profiles", dsn)
+	}
+}
+
+func TestExternalCatalogStatement(t *testing.T) {
+	catalog := config.ExternalCatalog{
+		Name:         "hive",
+		Type:         "hive",
+		MetastoreURI: "thrift://hive-metastore:9083",
+		S3Endpoint:   "http://minio:9000",
+		S3AccessKey:  "admin",
+		S3SecretKey:  `pass"word`,
+	}
+	statement, err := externalCatalogStatement(catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "CREATE EXTERNAL CATALOG `hive` PROPERTIES (" +
+		`"type" = "hive", "hive.metastore.type" = "hive", "hive.metastore.uris" = "thrift://hive-metastore:9083", ` +
+		`"aws.s3.endpoint" = "http://minio:9000", "aws.s3.enable_ssl" = "false", "aws.s3.enable_path_style_access" = "true", ` +
+		`"aws.s3.access_key" = "admin", "aws.s3.secret_key" = "pass\"word")`
+	if statement != want {
+		t.Errorf("statement = %s\nwant %s", statement, want)
+	}
+
+	for _, keys := range [][2]string{{"", ""}, {"admin", ""}, {"", "password"}} {
+		catalog.S3AccessKey, catalog.S3SecretKey = keys[0], keys[1]
+		if _, err := externalCatalogStatement(catalog); err == nil {
+			t.Errorf("keys %q: no error for missing S3 keys", keys)
+		}
+	}
+}
+
+func TestParseProfile(t *testing.T) {
+	profile, err := os.ReadFile(filepath.Join("testdata", "starrocks_profile.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &QueryStats{
+		CPUTimeMs:          int64Ptr(1250),
+		PeakMemoryBytes:    int64Ptr(50593792),           // 48.250 MB
+		PhysicalInputBytes: int64Ptr(2369781 + 42467328), // 2.260 MB + 40.500 MB
+		PhysicalInputRows:  int64Ptr(150000 + 1500000),
+		ProcessedRows:      int64Ptr(150000 + 1200000),
+		QueuedTimeMs:       int64Ptr(3),
+		PlanningTimeMs:     int64Ptr(25),
+	}
+	if got := parseProfile(string(profile)); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseProfile = %s, want %s", describeStats(got), describeStats(want))
+	}
+}
+
+func TestScanCounters(t *testing.T) {
+	profile := `
+    Fragment 1:
+      Pipeline (id=0):
+        EXCHANGE_SINK (plan_node_id=1):
+          UniqueMetrics:
+             - BytesRead: 1.000 KB
+        OLAP_SCAN (plan_node_id=0):
+          CommonMetrics:
+             - RowsRead: 5
+          UniqueMetrics:
+             - Table: lineitem
+             - RowsRead: 1.500K (1500)
+               - __MAX_OF_RowsRead: 1.000K (1000)
+             - SegmentRead:
+               - RowsRead: 1500
+        LOCAL_EXCHANGE_SINK (plan_node_id=0):
+          UniqueMetrics:
+             - RowsRead: 7
+      Pipeline (id=1):
+        META_SCAN (plan_node_id=2):
+          UniqueMetrics:
+             - RowsRead: 12
+`
+	want := map[string][]string{
+		"Table":    {"lineitem"},
+		"RowsRead": {"1.500K (1500)", "12"},
+	}
+	if got := scanCounters(profile); !reflect.DeepEqual(got, want) {
+		t.Errorf("scanCounters = %v, want %v", got, want)
+	}
+	if got := sumProfileRows(want["RowsRead"]); !reflect.DeepEqual(got, int64Ptr(1512)) {
+		t.Errorf("sumProfileRows = %v, want 1512", deref(got))
+	}
+	if got := sumProfileRows(nil); got != nil {
+		t.Errorf("sumProfileRows(nil) = %d, want nil", *got)
+	}
+}
+
+func describeStats(stats *QueryStats) string {
+	return fmt.Sprintf("{cpu: %v, memory: %v, input bytes: %v, input rows: %v, processed rows: %v, queued: %v, planning: %v}",
+		deref(stats.CPUTimeMs), deref(stats.PeakMemoryBytes), deref(stats.PhysicalInputBytes), deref(stats.PhysicalInputRows),
+		deref(stats.ProcessedRows), deref(stats.QueuedTimeMs), deref(stats.PlanningTimeMs))
+}
+
+func deref(p *int64) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+func int64Ptr(v int64) *int64 { return &v }
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryStats are an engine's own runtime statistics for a finished query.
+// Fields the engine doesn't report are nil.
+type QueryStats struct {
+	CPUTimeMs           *int64 `json:"cpu_time_ms,omitempty"`
+	PeakMemoryBytes     *int64 `json:"peak_memory_bytes,omitempty"` // peak user memory
+	PhysicalInputBytes  *int64 `json:"physical_input_bytes,omitempty"`
+	PhysicalInputRows   *int64 `json:"physical_input_rows,omitempty"`
+	ProcessedInputBytes *int64 `json:"processed_input_bytes,omitempty"`
+	ProcessedRows       *int64 `json:"processed_rows,omitempty"`
+	QueuedTimeMs        *int64 `json:"queued_time_ms,omitempty"`
+	PlanningTimeMs      *int64 `json:"planning_time_ms,omitempty"`
+}
+
+// statsRetryInterval and statsAttempts bound how long to wait for an engine
+// to finalize a query's statistics after its last row was read, and
+// statsTimeout how long getting them may take altogether
+const (
+	statsRetryInterval = 200 * time.Millisecond
+	statsAttempts      = 10
+	statsTimeout       = 10 * time.Second
+)
+
+// engineDuration is a duration as Trino and Presto serialize them, like
+// "1.23ms", or as StarRocks profiles print them, like "1s12ms"
+type engineDuration struct {
+	ms    int64
+	known bool
+}
+
+func (d *engineDuration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return nil
+	}
+	d.ms, d.known = parseEngineDuration(text)
+	return nil
+}
+
+func (d engineDuration) ptr() *int64 {
+	if !d.known {
+		return nil
+	}
+	ms := d.ms
+	return &ms
+}
+
+// engineSize is a data size as Trino and Presto serialize them, either a
+// string like "1.23MB" or a number of bytes
+type engineSize struct {
+	bytes int64
+	known bool
+}
+
+func (s *engineSize) UnmarshalJSON(data []byte) error {
+	var bytes float64
+	if err := json.Unmarshal(data, &bytes); err == nil {
+		s.bytes, s.known = int64(bytes), true
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return nil
+	}
+	s.bytes, s.known = parseEngineSize(text)
+	return nil
+}
+
+func (s engineSize) ptr() *int64 {
+	if !s.known {
+		return nil
+	}
+	bytes := s.bytes
+	return &bytes
+}
+
+var (
+	durationPartPattern = regexp.MustCompile(`([\d.]+)\s*(ns|us|µs|ms|s|m|h|d)`)
+	sizePattern         = regexp.MustCompile(`(?i)^([\d.]+)\s*([KMGTP]?i?B)$`)
+)
+
+var durationUnits = map[string]float64{
+	"ns": 1e-6,
+	"us": 1e-3,
+	"µs": 1e-3,
+	"ms": 1,
+	"s":  1e3,
+	"m":  60e3,
+	"h":  3600e3,
+	"d":  86400e3,
+}
+
+// parseEngineDuration parses a duration in milliseconds, summing its parts
+// so both "1.50s" and "1s500ms" work
+func parseEngineDuration(text string) (int64, bool) {
+	parts := durationPartPattern.FindAllStringSubmatch(text, -1)
+	if len(parts) == 0 {
+		return 0, false
+	}
+	var ms float64
+	for _, part := range parts {
+		value, err := strconv.ParseFloat(part[1], 64)
+		if err != nil {
+			return 0, false
+		}
+		ms += value * durationUnits[part[2]]
+	}
+	return int64(ms), true
+}
+
+// parseEngineSize parses a data size in bytes, like "1.23MB", "1.23 MB" or
+// "12B", with units of 1024
+func parseEngineSize(text string) (int64, bool) {
+	m := sizePattern.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	for exponent := strings.IndexByte("BKMGTP", strings.ToUpper(m[2])[0]); exponent > 0; exponent-- {
+		value *= 1024
+	}
+	return int64(value), true
+}
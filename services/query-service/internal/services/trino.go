@@ -12,13 +12,15 @@ import (
 
 // TrinoService handles Trino-specific queries
 type TrinoService struct {
-	cfg    config.TrinoConfig
-	logger *logger.Logger
-	db     *sql.DB
+	cfg         config.EngineConfig
+	logger      *logger.Logger
+	db          *sql.DB
+	coordinator *coordinatorClient
 }
 
-// NewTrinoService creates a new TrinoService
-func NewTrinoService(cfg config.TrinoConfig, logger *logger.Logger) (*TrinoService, error) {
+// NewTrinoService creates a new TrinoService, giving up on connecting
+// when ctx ends
+func NewTrinoService(ctx context.Context, cfg config.EngineConfig, logger *logger.Logger) (*TrinoService, error) {
 	dsn := fmt.Sprintf("http://%s@%s:%s?catalog=%s&schema=%s",
 		cfg.User, cfg.Host, cfg.Port, cfg.Catalog, cfg.Schema)
 
@@ -27,24 +29,124 @@ func NewTrinoService(cfg config.TrinoConfig, logger *logger.Logger) (*TrinoServi
 		return nil, fmt.Errorf("failed to connect to Trino: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping Trino: %w", err)
 	}
 
 	return &TrinoService{
-		cfg:    cfg,
-		logger: logger,
-		db:     db,
+		cfg:         cfg,
+		logger:      logger,
+		db:          db,
+		coordinator: newCoordinatorClient(cfg.Host, cfg.Port, cfg.User, "X-Trino-"),
 	}, nil
 }
 
-// ExecuteQuery executes a query on Trino
-func (s *TrinoService) ExecuteQuery(ctx context.Context, query string) (*sql.Rows, error) {
+// Name returns the name the engine is registered under
+func (s *TrinoService) Name() string {
+	return s.cfg.Name
+}
+
+// Type returns the engine type
+func (s *TrinoService) Type() string {
+	return "trino"
+}
+
+// Execute executes a query on Trino. The query is tagged with a unique
+// client info value so its ID can be looked up on the coordinator while it
+// runs, which is also how the query is killed if ctx ends before the cursor
+// is closed. Options are sent as request headers, which take precedence over
+// the DSN.
+func (s *TrinoService) Execute(ctx context.Context, query string, opts ExecOptions) (*Cursor, error) {
 	s.logger.WithField("query", query).Info("Executing Trino query")
-	return s.db.QueryContext(ctx, query)
+
+	tag := generateID()
+	stop := context.AfterFunc(ctx, func() {
+		if err := s.coordinator.cancelTagged(tag); err != nil {
+			s.logger.WithError(err).Warn("Failed to kill Trino query")
+		}
+	})
+	args := append([]interface{}{sql.Named("X-Trino-Client-Info", tag)}, trinoHeaders(opts)...)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+
+	return &Cursor{
+		Rows:      rows,
+		resolveID: s.coordinator.lookupRunningQueryID(ctx, tag),
+		release: func() {
+			stop()
+		},
+	}, nil
+}
+
+// trinoHeaders passes opts as the request headers the driver takes from
+// named arguments
+func trinoHeaders(opts ExecOptions) []interface{} {
+	var args []interface{}
+	if opts.Catalog != "" {
+		args = append(args, sql.Named("X-Trino-Catalog", opts.Catalog))
+	}
+	if opts.Schema != "" {
+		args = append(args, sql.Named("X-Trino-Schema", opts.Schema))
+	}
+	if len(opts.SessionProperties) > 0 {
+		args = append(args, sql.Named("X-Trino-Session", sessionHeader(opts.SessionProperties)))
+	}
+	return args
+}
+
+// Explain returns the JSON plan of a query, or the EXPLAIN ANALYZE output
+// if analyze is set
+func (s *TrinoService) Explain(ctx context.Context, query string, analyze bool, opts ExecOptions) (string, error) {
+	rows, err := s.db.QueryContext(ctx, explainStatement(query, analyze), trinoHeaders(opts)...)
+	if err != nil {
+		return "", err
+	}
+	return readText(rows)
+}
+
+// Health checks that the coordinator answers. The driver connects lazily,
+// so pinging the connection pool doesn't reach it.
+func (s *TrinoService) Health(ctx context.Context) error {
+	_, err := s.coordinator.info(ctx)
+	return err
+}
+
+// Version returns the coordinator's version
+func (s *TrinoService) Version(ctx context.Context) (string, error) {
+	info, err := s.coordinator.info(ctx)
+	if err != nil {
+		return "", err
+	}
+	return info.NodeVersion.Version, nil
+}
+
+// Status reports the cluster's version, uptime and active workers
+func (s *TrinoService) Status(ctx context.Context) (*EngineStatus, error) {
+	return coordinatorStatus(ctx, s.coordinator, s.db)
+}
+
+// Cancel kills a running query through the coordinator
+func (s *TrinoService) Cancel(ctx context.Context, queryID string) error {
+	return s.coordinator.cancel(ctx, queryID)
+}
+
+// QueryStats returns the runtime statistics of a finished query from the
+// coordinator
+func (s *TrinoService) QueryStats(ctx context.Context, queryID string) (*QueryStats, error) {
+	return s.coordinator.queryStats(ctx, queryID)
+}
+
+// Capabilities describes what Trino supports
+func (s *TrinoService) Capabilities() Capabilities {
+	return coordinatorCapabilities
 }
 
-// GetStatus checks the status of the Trino service
-func (s *TrinoService) GetStatus() error {
-	return s.db.Ping()
+// Close closes the connection pool
+func (s *TrinoService) Close() error {
+	return s.db.Close()
 }
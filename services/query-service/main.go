@@ -33,24 +33,24 @@ func main() {
 	// Initialize metrics
 	metrics.Init()
 
-	// Initialize services
-	trinoService, err := services.NewTrinoService(cfg.Trino, logger)
-	if err != nil {
-		log.Fatal("Failed to initialize Trino service:", err)
-	}
-	prestoService, err := services.NewPrestoService(cfg.Presto, logger)
-	if err != nil {
-		log.Fatal("Failed to initialize Presto service:", err)
+	// Initialize engines
+	// Engines connect in the background so the service starts, degraded,
+	// while some of them are down
+	registry := services.NewRegistry(logger)
+	defer registry.Close()
+	for _, engineCfg := range cfg.Engines {
+		if err := registry.Open(engineCfg); err != nil {
+			log.Fatalf("Failed to initialize %s engine: %v", engineCfg.Name, err)
+		}
 	}
-	// starrocksService, err := services.NewStarRocksService(cfg.StarRocks, logger)
-	// if err != nil {
-	// 	log.Fatal("Failed to initialize StarRocks service:", err)
-	// }
-	queryExecutor := services.NewQueryExecutor(trinoService, prestoService, nil, logger)
+	queryExecutor := services.NewQueryExecutor(registry, cfg.Query, logger)
+	healthProber := services.NewHealthProber(registry, cfg.Health, logger)
+	healthProber.Start()
+	defer healthProber.Stop()
 
 	// Initialize handlers
-	queryHandler := handlers.NewQueryHandler(queryExecutor, logger)
-	healthHandler := handlers.NewHealthHandler(logger)
+	queryHandler := handlers.NewQueryHandler(queryExecutor, healthProber, logger)
+	healthHandler := handlers.NewHealthHandler(registry, logger)
 
 	// Setup Gin router
 	router := setupRouter(cfg, queryHandler, healthHandler)
@@ -116,6 +116,9 @@ func setupRouter(cfg *config.Config, queryHandler *handlers.QueryHandler, health
 	api := router.Group("/api/v1")
 	{
 		api.POST("/execute", queryHandler.ExecuteQuery)
+		api.POST("/explain", queryHandler.ExplainQuery)
+		api.GET("/queries", queryHandler.ListRunningQueries)
+		api.DELETE("/queries/:query_id", queryHandler.CancelQuery)
 		api.GET("/engines", queryHandler.ListEngines)
 		api.GET("/engines/:engine/status", queryHandler.GetEngineStatus)
 		api.POST("/engines/:engine/test", queryHandler.TestEngine)
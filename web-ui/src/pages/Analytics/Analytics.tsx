@@ -1,13 +1,219 @@
-import React from 'react';
-import { Container, Typography } from '@mui/material';
+import React, { useEffect, useMemo, useState } from 'react';
+import {
+  Alert,
+  Box,
+  CircularProgress,
+  Container,
+  FormControl,
+  InputLabel,
+  MenuItem,
+  Paper,
+  Select,
+  Typography,
+} from '@mui/material';
+import {
+  CartesianGrid,
+  Legend,
+  Line,
+  LineChart,
+  ResponsiveContainer,
+  Tooltip,
+  XAxis,
+  YAxis,
+} from 'recharts';
+import api from '../../services/api';
+import {
+  Analytics as AnalyticsData,
+  AnalyticsBucket,
+  AnalyticsDimension,
+  AnalyticsPoint,
+} from '../../types';
+
+type Metric = 'median_latency_ms' | 'p95_latency_ms' | 'throughput' | 'failure_rate';
+
+const metrics: { value: Metric; label: string }[] = [
+  { value: 'median_latency_ms', label: 'Median latency (ms)' },
+  { value: 'p95_latency_ms', label: 'P95 latency (ms)' },
+  { value: 'throughput', label: 'Throughput (queries/s)' },
+  { value: 'failure_rate', label: 'Failure rate (%)' },
+];
+
+const dimensions: { value: AnalyticsDimension; label: string }[] = [
+  { value: 'engine', label: 'Engine' },
+  { value: 'table_format', label: 'Table format' },
+  { value: 'dataset_size', label: 'Dataset size' },
+  { value: 'query_type', label: 'Query type' },
+  { value: 'complexity', label: 'Complexity' },
+  { value: 'concurrency', label: 'Concurrency' },
+  { value: 'arrival_rate', label: 'Arrival rate' },
+];
+
+const ranges: { days: number; label: string }[] = [
+  { days: 7, label: 'Last 7 days' },
+  { days: 30, label: 'Last 30 days' },
+  { days: 90, label: 'Last 90 days' },
+  { days: 365, label: 'Last year' },
+];
+
+const colors = ['#1976d2', '#dc004e', '#2e7d32', '#ed6c02', '#9c27b0', '#0288d1'];
+
+const pointValue = (point: AnalyticsPoint, metric: Metric): number | null => {
+  if (metric === 'failure_rate') {
+    return point.failure_rate * 100;
+  }
+  return point[metric];
+};
 
 const Analytics: React.FC = () => {
+  const [metric, setMetric] = useState<Metric>('median_latency_ms');
+  const [groupBy, setGroupBy] = useState<AnalyticsDimension>('engine');
+  const [bucket, setBucket] = useState<AnalyticsBucket>('day');
+  const [days, setDays] = useState(30);
+  const [data, setData] = useState<AnalyticsData | null>(null);
+  const [loading, setLoading] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    const from = new Date(Date.now() - days * 24 * 60 * 60 * 1000);
+    setLoading(true);
+    setError(null);
+    api
+      .get<AnalyticsData>('/results/analytics', {
+        params: { bucket, group_by: groupBy, from: from.toISOString() },
+      })
+      .then((response) => setData(response.data))
+      .catch(() => setError('Failed to load analytics'))
+      .finally(() => setLoading(false));
+  }, [bucket, groupBy, days]);
+
+  // Recharts wants one row per time bucket with a key per series
+  const { rows, labels } = useMemo(() => {
+    const byTime: { [time: string]: { [key: string]: number | string | null } } = {};
+    const seriesLabels: string[] = [];
+    (data?.series ?? []).forEach((series) => {
+      const label = series.group[groupBy] || 'unknown';
+      seriesLabels.push(label);
+      series.points.forEach((point) => {
+        if (!byTime[point.time]) {
+          byTime[point.time] = { time: point.time };
+        }
+        byTime[point.time][label] = pointValue(point, metric);
+      });
+    });
+    const sorted = Object.keys(byTime)
+      .sort()
+      .map((time) => byTime[time]);
+    return { rows: sorted, labels: seriesLabels };
+  }, [data, groupBy, metric]);
+
+  const formatTime = (time: string) => {
+    const date = new Date(time);
+    return bucket === 'hour' ? date.toLocaleString() : date.toLocaleDateString();
+  };
+
   return (
     <Container maxWidth="lg" sx={{ mt: 4, mb: 4 }}>
       <Typography variant="h4" component="h1" gutterBottom>
         Analytics
       </Typography>
-      {/* Placeholder for analytics and visualizations */}
+
+      <Box sx={{ display: 'flex', gap: 2, flexWrap: 'wrap', mb: 3 }}>
+        <FormControl size="small" sx={{ minWidth: 200 }}>
+          <InputLabel id="metric-label">Metric</InputLabel>
+          <Select
+            labelId="metric-label"
+            label="Metric"
+            value={metric}
+            onChange={(e) => setMetric(e.target.value as Metric)}
+          >
+            {metrics.map((m) => (
+              <MenuItem key={m.value} value={m.value}>
+                {m.label}
+              </MenuItem>
+            ))}
+          </Select>
+        </FormControl>
+        <FormControl size="small" sx={{ minWidth: 160 }}>
+          <InputLabel id="group-by-label">Group by</InputLabel>
+          <Select
+            labelId="group-by-label"
+            label="Group by"
+            value={groupBy}
+            onChange={(e) => setGroupBy(e.target.value as AnalyticsDimension)}
+          >
+            {dimensions.map((d) => (
+              <MenuItem key={d.value} value={d.value}>
+                {d.label}
+              </MenuItem>
+            ))}
+          </Select>
+        </FormControl>
+        <FormControl size="small" sx={{ minWidth: 120 }}>
+          <InputLabel id="bucket-label">Bucket</InputLabel>
+          <Select
+            labelId="bucket-label"
+            label="Bucket"
+            value={bucket}
+            onChange={(e) => setBucket(e.target.value as AnalyticsBucket)}
+          >
+            <MenuItem value="hour">Hour</MenuItem>
+            <MenuItem value="day">Day</MenuItem>
+            <MenuItem value="week">Week</MenuItem>
+            <MenuItem value="month">Month</MenuItem>
+          </Select>
+        </FormControl>
+        <FormControl size="small" sx={{ minWidth: 160 }}>
+          <InputLabel id="range-label">Range</InputLabel>
+          <Select
+            labelId="range-label"
+            label="Range"
+            value={days}
+            onChange={(e) => setDays(Number(e.target.value))}
+          >
+            {ranges.map((r) => (
+              <MenuItem key={r.days} value={r.days}>
+                {r.label}
+              </MenuItem>
+            ))}
+          </Select>
+        </FormControl>
+      </Box>
+
+      {error && (
+        <Alert severity="error" sx={{ mb: 2 }}>
+          {error}
+        </Alert>
+      )}
+
+      <Paper sx={{ p: 2, height: 420, display: 'flex', flexDirection: 'column' }}>
+        {loading ? (
+          <Box sx={{ display: 'flex', justifyContent: 'center', alignItems: 'center', flexGrow: 1 }}>
+            <CircularProgress />
+          </Box>
+        ) : rows.length === 0 ? (
+          <Typography color="text.secondary">No executions in this range yet.</Typography>
+        ) : (
+          <ResponsiveContainer width="100%" height="100%">
+            <LineChart data={rows}>
+              <CartesianGrid strokeDasharray="3 3" />
+              <XAxis dataKey="time" tickFormatter={formatTime} />
+              <YAxis />
+              <Tooltip labelFormatter={(time) => formatTime(String(time))} />
+              <Legend />
+              {labels.map((label, i) => (
+                <Line
+                  key={label}
+                  type="monotone"
+                  dataKey={label}
+                  stroke={colors[i % colors.length]}
+                  connectNulls
+                  dot={false}
+                />
+              ))}
+            </LineChart>
+          </ResponsiveContainer>
+        )}
+      </Paper>
     </Container>
   );
 };
@@ -6,6 +6,10 @@ export interface Benchmark {
   dataset_name: string;
   dataset_size: 'small' | 'medium' | 'large';
   engines: string[];
+  warmup_iterations: number;
+  measured_iterations: number;
+  baseline_run_id: number | null;
+  workload?: WorkloadConfig;
   status: 'created' | 'running' | 'completed' | 'failed';
   created_at: string;
   updated_at: string;
@@ -13,6 +17,18 @@ export interface Benchmark {
   results?: Result[];
 }
 
+export interface WorkloadConfig {
+  mode: 'sequential' | 'concurrent' | 'open_loop';
+  concurrency_levels?: number[];
+  arrival_rates?: number[];
+  arrival?: 'poisson' | 'constant';
+  max_in_flight?: number;
+  duration_seconds?: number;
+  iterations?: number;
+  think_time_ms?: number;
+  seed?: number;
+}
+
 export interface Query {
   id: number;
   benchmark_id: number;
@@ -28,46 +44,196 @@ export interface Query {
 export interface QueryExecution {
   id: number;
   query_id: number;
+  run_id?: number;
   engine: string;
-  status: 'pending' | 'running' | 'completed' | 'failed';
+  iteration: number;
+  concurrency: number;
+  virtual_user: number;
+  arrival_rate: number;
+  scheduled_time?: string;
+  service_time_ms?: number;
+  status: 'pending' | 'running' | 'completed' | 'failed' | 'cancelled' | 'timed_out';
   start_time?: string;
   end_time?: string;
   execution_time_ms?: number;
+  rows_returned?: number;
   rows_processed?: number;
   bytes_processed?: number;
   cpu_usage?: number;
+  cpu_time_ms?: number;
   memory_usage?: number;
   io_read_bytes?: number;
+  io_read_rows?: number;
   io_write_bytes?: number;
+  queued_time_ms?: number;
+  planning_time_ms?: number;
   error_message?: string;
   query_plan?: string;
+  plan_type?: 'explain' | 'analyze';
+  plan_analysis?: PlanAnalysis | null;
+  result_checksum?: string;
   created_at: string;
   updated_at: string;
 }
 
+export interface QueryExecutionResult {
+  execution: QueryExecution;
+  columns: ResultColumn[] | null;
+  rows: unknown[][] | null;
+  truncated: boolean;
+}
+
+export interface ResultColumn {
+  name: string;
+  type: string;
+}
+
+export interface ExecutionPlan {
+  execution_id: number;
+  query_id: number;
+  run_id: number | null;
+  engine: string;
+  plan_type: 'explain' | 'analyze';
+  format: 'json' | 'text';
+  plan: unknown;
+  analysis: PlanAnalysis | null;
+}
+
+export interface PlanNode {
+  id?: string;
+  name: string;
+  descriptor?: Record<string, string>;
+  estimated_rows?: number;
+  actual_rows?: number;
+  children?: PlanNode[];
+}
+
+export interface ScannedTable {
+  table: string;
+  catalog: string;
+  constraint?: string;
+  filter_predicate?: string;
+  predicate_pushdown?: 'full' | 'partial' | 'none';
+  partitions_read?: number;
+  partitions_total?: number;
+  files_read?: number;
+  splits?: number;
+  estimated_rows?: number;
+  actual_rows?: number;
+  physical_input_bytes?: number;
+}
+
+export interface PlanJoin {
+  type: string;
+  distribution: string;
+  criteria?: string;
+  estimated_rows?: number;
+  actual_rows?: number;
+}
+
+export interface PlanAnalysis {
+  root: PlanNode | null;
+  tables: ScannedTable[];
+  joins: PlanJoin[];
+  estimated_rows: number | null;
+  actual_rows: number | null;
+}
+
+export interface PlanDiffSide {
+  execution_id: number;
+  run_id: number | null;
+  query_id: number;
+  engine: string;
+  table_format: string;
+  plan_type: 'explain' | 'analyze';
+  analysis: PlanAnalysis;
+}
+
+export interface PlanDiff {
+  base: PlanDiffSide;
+  candidate: PlanDiffSide;
+  changes: string[];
+  operators: { operator: string; base: number; candidate: number }[];
+  tables: { table: string; base: ScannedTable | null; candidate: ScannedTable | null; changes: string[] }[];
+  joins: { index: number; base: PlanJoin | null; candidate: PlanJoin | null; changes: string[] }[];
+}
+
 export interface Result {
   id: number;
   benchmark_id: number;
   engine: string;
   table_format: string;
+  concurrency: number;
+  arrival_rate: number;
   total_queries: number;
   successful_queries: number;
   failed_queries: number;
   avg_execution_time_ms: number;
   min_execution_time_ms: number;
   max_execution_time_ms: number;
+  median_execution_time_ms: number;
+  p90_execution_time_ms: number;
+  p95_execution_time_ms: number;
+  p99_execution_time_ms: number;
+  stddev_execution_time_ms: number;
+  coefficient_of_variation: number;
+  ci_lower_ms: number;
+  ci_upper_ms: number;
   total_rows_processed: number;
   total_bytes_processed: number;
   avg_cpu_usage: number;
   avg_memory_usage: number;
   total_io_read_bytes: number;
   total_io_write_bytes: number;
+  error_rate: number;
   throughput: number;
+  saturated: boolean;
   efficiency_score: number;
   created_at: string;
   updated_at: string;
 }
 
+export interface Regression {
+  id: number;
+  benchmark_id: number;
+  run_id: number;
+  baseline_run_id: number;
+  query_id: number;
+  engine: string;
+  concurrency: number;
+  arrival_rate: number;
+  test: 'welch' | 'mann_whitney';
+  baseline_median_ms: number;
+  median_ms: number;
+  magnitude: number;
+  p_value: number;
+  created_at: string;
+  query?: Query;
+}
+
+export interface CorrectnessReport {
+  run_id: number;
+  against_run_id: number | null;
+  consistent: boolean;
+  mismatches: number;
+  nondeterministic: number;
+  queries: QueryCorrectness[];
+}
+
+export interface QueryCorrectness {
+  query_id: number;
+  query_name: string;
+  consistent: boolean;
+  nondeterministic: boolean;
+  checksums: {
+    run_id: number;
+    engine: string;
+    table_format: string;
+    checksum: string;
+    variants?: string[];
+  }[];
+}
+
 export interface Engine {
   name: string;
   type: 'trino' | 'presto' | 'starrocks';
@@ -80,6 +246,18 @@ export interface Engine {
   updated_at: string;
 }
 
+export interface EngineHealth {
+  engine: string;
+  type: string;
+  status: 'healthy' | 'unhealthy' | 'unavailable' | 'unknown';
+  version: string;
+  uptime_seconds?: number;
+  workers?: number;
+  latency_ms?: number;
+  error?: string;
+  checked_at?: string;
+}
+
 export interface Dataset {
   id: number;
   name: string;
@@ -124,19 +302,77 @@ export interface BenchmarkStatus {
 }
 
 export interface ComparisonResult {
-  engines: string[];
-  table_formats: string[];
-  metrics: {
-    execution_time: ComparisonMetric;
-    throughput: ComparisonMetric;
-    resource_usage: ComparisonMetric;
-    efficiency: ComparisonMetric;
-  };
+  baseline: ComparisonSide;
+  comparisons: SideComparison[];
 }
 
-export interface ComparisonMetric {
-  name: string;
-  values: { [engine: string]: { [format: string]: number } };
-  unit: string;
-  description: string;
+export interface ComparisonSide {
+  label: string;
+  run_id: number;
+  result_id?: number;
+  engine?: string;
+  table_format: string;
+}
+
+export interface SideComparison {
+  candidate: ComparisonSide;
+  geometric_mean_speedup: number | null;
+  wins: number;
+  losses: number;
+  ties: number;
+  significant_wins: number;
+  significant_losses: number;
+  mismatches: number;
+  queries: QueryComparison[];
+}
+
+export interface QueryComparison {
+  query_id: number;
+  query_name: string;
+  engine?: string;
+  concurrency: number;
+  arrival_rate: number;
+  outcome: 'both_succeeded' | 'baseline_failed' | 'candidate_failed' | 'both_failed';
+  baseline_median_ms: number | null;
+  candidate_median_ms: number | null;
+  ratio: number | null;
+  significant: boolean;
+  baseline_error?: string;
+  candidate_error?: string;
+}
+
+export type AnalyticsBucket = 'hour' | 'day' | 'week' | 'month';
+
+export type AnalyticsDimension =
+  | 'engine'
+  | 'table_format'
+  | 'dataset_size'
+  | 'query_type'
+  | 'complexity'
+  | 'concurrency'
+  | 'arrival_rate';
+
+export interface Analytics {
+  bucket: AnalyticsBucket;
+  from: string;
+  to: string;
+  group_by: AnalyticsDimension[];
+  series: AnalyticsSeries[];
+}
+
+export interface AnalyticsSeries {
+  group: { [dimension: string]: string };
+  points: AnalyticsPoint[];
+}
+
+export interface AnalyticsPoint {
+  time: string;
+  executions: number;
+  completed: number;
+  failed: number;
+  failure_rate: number;
+  avg_latency_ms: number | null;
+  median_latency_ms: number | null;
+  p95_latency_ms: number | null;
+  throughput: number;
 }